@@ -0,0 +1,54 @@
+package stalkerlib
+
+import (
+	"context"
+	"strconv"
+)
+
+/* AccountInfo is the subscriber account as reported by the portal. */
+type AccountInfo struct {
+	MAC           string // Account MAC address
+	Phone         string // Contact phone or login
+	Tariff        string // Tariff plan name
+	ExpiresAt     string // Subscription end date as formatted by the portal
+	PlaybackLimit int    // Simultaneous streams allowed, 0 when not reported
+}
+
+/* accountInfoResponse represents the JSON response from the account_info get_main_info action. */
+type accountInfoResponse struct {
+	Js struct {
+		MAC           string     `json:"mac"`
+		Phone         string     `json:"phone"`
+		Tariff        string     `json:"tariff_plan"`
+		EndDate       string     `json:"end_date"`
+		PlaybackLimit flexString `json:"playback_limit"`
+		MaxOnline     flexString `json:"max_online"`
+	} `json:"js"`
+}
+
+/* GetAccountInfo fetches the subscriber account, including its simultaneous stream limit when the portal reports one. */
+func (c *StalkerClient) GetAccountInfo() (AccountInfo, error) {
+	return c.getAccountInfo(context.Background())
+}
+
+/* GetAccountInfoContext is GetAccountInfo under ctx, which may carry a Priority or request ID. */
+func (c *StalkerClient) GetAccountInfoContext(ctx context.Context) (AccountInfo, error) {
+	return c.getAccountInfo(ctx)
+}
+
+/* getAccountInfo implements GetAccountInfo under the given context. */
+func (c *StalkerClient) getAccountInfo(ctx context.Context) (AccountInfo, error) {
+	var response accountInfoResponse
+	if err := c.doAction(ctx, "account_info", "get_main_info", nil, &response); err != nil {
+		return AccountInfo{}, err
+	}
+	js := response.Js
+	limit, _ := strconv.Atoi(firstNonEmpty(string(js.PlaybackLimit), string(js.MaxOnline)))
+	return AccountInfo{
+		MAC:           js.MAC,
+		Phone:         js.Phone,
+		Tariff:        js.Tariff,
+		ExpiresAt:     js.EndDate,
+		PlaybackLimit: limit,
+	}, nil
+}
@@ -0,0 +1,94 @@
+package stalkerlib
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+)
+
+/* WithActionCache caches the responses of Do calls for the TTL given per "type|action", e.g. {"itv|get_genres": time.Hour}, keyed by action type, action, and parameters; cache hits never reach the portal or its rate limits, concurrent misses share one call, and portal error responses are never cached. */
+func WithActionCache(ttls map[string]time.Duration) Option {
+	return func(c *StalkerClient) {
+		c.actions.ttls = ttls
+	}
+}
+
+/* actionCache holds raw Do responses per request until they expire. */
+type actionCache struct {
+	ttls map[string]time.Duration // Read-only after construction
+
+	mu    sync.Mutex
+	slots map[string]*actionSlot
+}
+
+/* actionSlot is the cached response of one request. */
+type actionSlot struct {
+	cacheSlot[json.RawMessage]
+	ttl time.Duration
+}
+
+/* slot returns the slot of key, creating it on first use and dropping expired ones. */
+func (a *actionCache) slot(key string, ttl time.Duration) *actionSlot {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if s, ok := a.slots[key]; ok {
+		return s
+	}
+	if a.slots == nil {
+		a.slots = make(map[string]*actionSlot)
+	}
+	for k, s := range a.slots {
+		if info, ok := s.info(s.ttl); ok && info.Stale {
+			delete(a.slots, k)
+		}
+	}
+	s := &actionSlot{ttl: ttl}
+	a.slots[key] = s
+	return s
+}
+
+/* flush drops every cached response. */
+func (a *actionCache) flush() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.slots = nil
+}
+
+/* FlushActionCache drops the responses cached for Do calls, so the next calls reach the portal. */
+func (c *StalkerClient) FlushActionCache() {
+	c.actions.flush()
+}
+
+/* doCached performs a Do call through the action cache when a TTL is configured for its type and action. */
+func (c *StalkerClient) doCached(ctx context.Context, actionType, action string, params url.Values, out interface{}) error {
+	ttl := c.actions.ttls[actionType+"|"+action]
+	if ttl <= 0 {
+		return c.doAction(ctx, actionType, action, params, out)
+	}
+
+	slot := c.actions.slot(actionType+"|"+action+"|"+params.Encode(), ttl)
+	body, err := slot.readFresh(ctx, ttl, func(ctx context.Context) (json.RawMessage, error) {
+		// doAction has already renewed a rejected token; other portal failures reach the caller uncached
+		var body json.RawMessage
+		if err := c.doAction(ctx, actionType, action, params, &body); err != nil {
+			return nil, err
+		}
+		if portalFailureFromBody(body) == nil {
+			slot.store(body)
+		}
+		return body, nil
+	})
+	if err != nil {
+		return err
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("failed to parse %s response: %w", action, err)
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+package stalkerlib
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+/* ErrUnrecognizedResponse is returned when a portal response matches none of the known JSON shapes. */
+var ErrUnrecognizedResponse = errors.New("stalkerlib: unrecognized response shape")
+
+/* portalEnvelope is the common {"js": ...} wrapper of load.php responses. */
+type portalEnvelope struct {
+	Js   json.RawMessage `json:"js"`
+	Text string          `json:"text"` // Optional portal message, e.g. "Authorization failed."
+}
+
+/* channelListKeys are the members of "js" known to hold the channel list, in the order they are tried. */
+var channelListKeys = []string{"channels", "data"}
+
+/* programListKeys are the members of "js" known to hold EPG programs, in the order they are tried. */
+var programListKeys = []string{"programs", "data", "epg"}
+
+/* decodeChannelList extracts the channel list from a get_all_channels response. */
+func decodeChannelList(env portalEnvelope) ([]Channel, error) {
+	return decodeEnvelopeList[Channel](env, channelListKeys)
+}
+
+/* decodeProgramList extracts the programs from a get_epg response. */
+func decodeProgramList(env portalEnvelope) ([]EPGProgram, error) {
+	return decodeEnvelopeList[EPGProgram](env, programListKeys)
+}
+
+/* decodeEnvelopeList decodes the list in env like decodeList, first turning error payloads into a PortalError. */
+func decodeEnvelopeList[T any](env portalEnvelope, keys []string) ([]T, error) {
+	if err := env.failure(); err != nil {
+		return nil, err
+	}
+	return decodeList[T](env.Js, keys)
+}
+
+/* decodeList tries the known shapes of a list response in order: a bare array, an object member holding an array, or an object member holding arrays keyed by ID (flattened in key order). */
+func decodeList[T any](js json.RawMessage, keys []string) ([]T, error) {
+	js = bytes.TrimSpace(js)
+	if len(js) == 0 || bytes.Equal(js, []byte("null")) {
+		return nil, fmt.Errorf("%w: missing \"js\"", ErrUnrecognizedResponse)
+	}
+	if js[0] == '[' {
+		var list []T
+		err := json.Unmarshal(js, &list)
+		return list, err
+	}
+
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(js, &obj); err != nil {
+		return nil, err
+	}
+	for _, key := range keys {
+		raw, ok := obj[key]
+		if !ok {
+			continue
+		}
+		raw = bytes.TrimSpace(raw)
+		if len(raw) > 0 && raw[0] == '{' {
+			var byID map[string][]T
+			if err := json.Unmarshal(raw, &byID); err != nil {
+				return nil, err
+			}
+			ids := make([]string, 0, len(byID))
+			for id := range byID {
+				ids = append(ids, id)
+			}
+			sort.Strings(ids)
+			var list []T
+			for _, id := range ids {
+				list = append(list, byID[id]...)
+			}
+			return list, nil
+		}
+		var list []T
+		if err := json.Unmarshal(raw, &list); err != nil {
+			return nil, err
+		}
+		return list, nil
+	}
+	return nil, fmt.Errorf("%w: none of %v in \"js\"", ErrUnrecognizedResponse, keys)
+}
+
+/* UnmarshalJSON decodes a program, accepting the key names and value types used by different portal versions. */
+func (p *EPGProgram) UnmarshalJSON(data []byte) error {
+	type plain EPGProgram
+	aux := struct {
+		ChannelID   flexString      `json:"ch_id"`
+		AltChannel  flexString      `json:"channel_id"`
+		Title       string          `json:"title"`
+		Start       flexString      `json:"start_timestamp"`
+		AltStart    flexString      `json:"start"`
+		Stop        flexString      `json:"stop_timestamp"`
+		AltStop     flexString      `json:"stop"`
+		End         flexString      `json:"end"`
+		Description string          `json:"description"`
+		Genre       string          `json:"genre"`
+		Icon        string          `json:"icon"`
+		Image       string          `json:"image"`
+		Picture     string          `json:"picture"`
+		Season      flexString      `json:"season"`
+		Episode     flexString      `json:"episode"`
+		Year        flexString      `json:"year"`
+		Director    string          `json:"director"`
+		Actor       string          `json:"actor"`
+		Actors      json.RawMessage `json:"actors"`
+		*plain
+	}{plain: (*plain)(p)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	p.ChannelID = firstNonEmpty(string(aux.ChannelID), string(aux.AltChannel))
+	p.Name = firstNonEmpty(p.Name, aux.Title)
+	p.Start = programTime(aux.Start, aux.AltStart)
+	p.Stop = programTime(aux.Stop, aux.AltStop, aux.End)
+	p.Desc = firstNonEmpty(p.Desc, aux.Description)
+	p.Category = firstNonEmpty(p.Category, aux.Genre)
+	p.Icon = firstNonEmpty(aux.Icon, aux.Image, aux.Picture)
+	p.Season, _ = strconv.Atoi(string(aux.Season))
+	p.Episode, _ = strconv.Atoi(string(aux.Episode))
+	p.Year = string(aux.Year)
+	if names := splitNames(aux.Director); len(names) > 0 {
+		p.Directors = names
+	}
+
+	// Portals list actors as one string; stored programs keep them as an array
+	var actors string
+	if json.Unmarshal(aux.Actors, &actors) != nil {
+		json.Unmarshal(aux.Actors, &p.Actors)
+	}
+	if names := splitNames(firstNonEmpty(aux.Actor, actors)); len(names) > 0 {
+		p.Actors = names
+	}
+	if p.Season == 0 && p.Episode == 0 {
+		p.Season, p.Episode = episodeMarker(p.Name)
+	}
+	return nil
+}
+
+/* episodeMarkerPattern matches "S02E05"-style markers in program titles. */
+var episodeMarkerPattern = regexp.MustCompile(`(?i)\bS(\d{1,3})\s?E(\d{1,4})\b`)
+
+/* episodeMarker extracts the season and episode from a title marker, or returns zeros. */
+func episodeMarker(title string) (int, int) {
+	m := episodeMarkerPattern.FindStringSubmatch(title)
+	if m == nil {
+		return 0, 0
+	}
+	season, _ := strconv.Atoi(m[1])
+	episode, _ := strconv.Atoi(m[2])
+	return season, episode
+}
+
+/* firstNonEmpty returns the first non-empty value. */
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+/* programTime returns the first value that parses as a Unix timestamp or a "2006-01-02 15:04:05" UTC time. */
+func programTime(values ...flexString) int64 {
+	for _, v := range values {
+		if v == "" {
+			continue
+		}
+		if n, err := strconv.ParseInt(string(v), 10, 64); err == nil {
+			return n
+		}
+		if t, err := time.Parse("2006-01-02 15:04:05", string(v)); err == nil {
+			return t.Unix()
+		}
+	}
+	return 0
+}
@@ -0,0 +1,123 @@
+package stalkerlib
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/* ErrNoRecording is returned by GetArchiveURL when no recorded program covers the requested time. */
+var ErrNoRecording = errors.New("stalkerlib: no recording available")
+
+/* ArchiveProgram is a past program the portal has recorded and can replay. */
+type ArchiveProgram struct {
+	EPGProgram
+	ID string // Program ID used to request the recording
+}
+
+/* UnmarshalJSON decodes a get_simple_data_table item, keeping the program ID and archive mark next to the EPG fields. */
+func (p *ArchiveProgram) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &p.EPGProgram); err != nil {
+		return err
+	}
+	var aux struct {
+		ID flexString `json:"id"`
+	}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	p.ID = string(aux.ID)
+	return nil
+}
+
+/* archiveItem is one get_simple_data_table entry with its archive availability. */
+type archiveItem struct {
+	ArchiveProgram
+	MarkArchive flexString
+}
+
+/* UnmarshalJSON decodes the program and its mark_archive flag. */
+func (it *archiveItem) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &it.ArchiveProgram); err != nil {
+		return err
+	}
+	var aux struct {
+		MarkArchive flexString `json:"mark_archive"`
+	}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	it.MarkArchive = aux.MarkArchive
+	return nil
+}
+
+/* GetArchivePrograms returns the programs of a channel on date (in the client timezone) that have ended and are marked as recorded, so replay UIs only offer playable items. */
+func (c *StalkerClient) GetArchivePrograms(channelID string, date time.Time) ([]ArchiveProgram, error) {
+	loc, err := time.LoadLocation(c.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone %s: %w", c.Timezone, err)
+	}
+	params := url.Values{
+		"ch_id": {channelID},
+		"date":  {date.In(loc).Format("2006-01-02")},
+	}
+	items, err := fetchPagedList[archiveItem](context.Background(), c, "epg", "get_simple_data_table", params, 0, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list archive of channel %s: %w", channelID, err)
+	}
+	now := time.Now().Unix()
+	var programs []ArchiveProgram
+	for _, it := range items {
+		if it.MarkArchive != "1" || it.Stop > now {
+			continue
+		}
+		p := it.ArchiveProgram
+		if p.ChannelID == "" {
+			p.ChannelID = channelID
+		}
+		if c.sanitizer != nil {
+			p.Name = c.sanitizer.Clean(p.Name)
+			p.Desc = c.sanitizer.Clean(p.Desc)
+		}
+		programs = append(programs, p)
+	}
+	return programs, nil
+}
+
+/* GetArchiveURL resolves a playable URL of the recording covering at on a channel, with the position inside the program when the portal supports seeking. */
+func (c *StalkerClient) GetArchiveURL(channelID string, at time.Time) (string, error) {
+	programs, err := c.GetArchivePrograms(channelID, at)
+	if err != nil {
+		return "", err
+	}
+	for _, p := range programs {
+		if at.Unix() < p.Start || at.Unix() >= p.Stop {
+			continue
+		}
+		var response CreateLinkResponse
+		params := url.Values{"cmd": {"auto /media/" + p.ID + ".mpg"}}
+		if err := c.doAction(context.Background(), "tv_archive", "create_link", params, &response); err != nil {
+			return "", fmt.Errorf("failed to resolve archive of channel %s: %w", channelID, err)
+		}
+		archiveURL := streamLocation(response.Js.Cmd)
+		if offset := at.Unix() - p.Start; offset > 0 {
+			archiveURL = addQueryParam(archiveURL, "position", strconv.FormatInt(offset, 10))
+		}
+		return archiveURL, nil
+	}
+	return "", fmt.Errorf("%w: channel %s at %s", ErrNoRecording, channelID, at.Format(time.RFC3339))
+}
+
+/* addQueryParam appends key=value to the query of rawURL. */
+func addQueryParam(rawURL, key, value string) string {
+	sep := "?"
+	if strings.Contains(rawURL, "?") {
+		sep = "&"
+	}
+	return rawURL + sep + url.QueryEscape(key) + "=" + url.QueryEscape(value)
+}
@@ -0,0 +1,99 @@
+package stalkerlib
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+/* batchSummaryLimit is how many failures BatchResult.Error lists before summarizing the rest. */
+const batchSummaryLimit = 3
+
+/* BatchResult records the per-item outcome of a bulk operation; as an error it summarizes the failures. */
+type BatchResult struct {
+	Succeeded []string         // Keys (channel IDs) of items that succeeded
+	Failed    map[string]error // Error of each failed item by key
+}
+
+/* add records the outcome of one item. */
+func (b *BatchResult) add(key string, err error) {
+	if err == nil {
+		b.Succeeded = append(b.Succeeded, key)
+		return
+	}
+	if b.Failed == nil {
+		b.Failed = make(map[string]error)
+	}
+	b.Failed[key] = err
+}
+
+/* Err returns b when any item failed and nil otherwise, avoiding a non-nil error interface holding an empty result. */
+func (b *BatchResult) Err() error {
+	if len(b.Failed) == 0 {
+		return nil
+	}
+	return b
+}
+
+/* failedKeys returns the failed keys in sorted order. */
+func (b *BatchResult) failedKeys() []string {
+	keys := make([]string, 0, len(b.Failed))
+	for k := range b.Failed {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+/* Error summarizes the failures, e.g. "2 of 40 items failed: 7: timeout; 9: not found". */
+func (b *BatchResult) Error() string {
+	keys := b.failedKeys()
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%d of %d items failed: ", len(keys), len(keys)+len(b.Succeeded))
+	for i, k := range keys {
+		if i == batchSummaryLimit {
+			fmt.Fprintf(&sb, "; and %d more", len(keys)-i)
+			break
+		}
+		if i > 0 {
+			sb.WriteString("; ")
+		}
+		fmt.Fprintf(&sb, "%s: %v", k, b.Failed[k])
+	}
+	return sb.String()
+}
+
+/* Unwrap returns the item errors so errors.Is and errors.As see through the batch. */
+func (b *BatchResult) Unwrap() []error {
+	keys := b.failedKeys()
+	errs := make([]error, len(keys))
+	for i, k := range keys {
+		errs[i] = b.Failed[k]
+	}
+	return errs
+}
+
+/* DownloadChannelLogos downloads the logo of every channel, continuing past failures; the returned BatchResult is keyed by channel ID. */
+func (c *StalkerClient) DownloadChannelLogos(channels []Channel, outputDir, filenameFormat string) *BatchResult {
+	result := &BatchResult{}
+	for _, ch := range channels {
+		result.add(ch.ID, c.DownloadChannelLogo(ch.Logo, outputDir, filenameFormat, ch))
+	}
+	return result
+}
+
+/* GetAllEPG fetches the EPG of every channel, continuing past failures; programs holds the channels that succeeded. */
+func (c *StalkerClient) GetAllEPG(channels []Channel) (map[string][]EPGProgram, *BatchResult) {
+	ctx := context.Background()
+	programs := make(map[string][]EPGProgram, len(channels))
+	result := &BatchResult{}
+	for _, ch := range channels {
+		p, err := c.getEPG(ctx, ch.ID)
+		if err == nil {
+			programs[ch.ID] = p
+		}
+		result.add(ch.ID, err)
+	}
+	return programs, result
+}
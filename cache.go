@@ -0,0 +1,229 @@
+package stalkerlib
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+/* CacheInfo describes the freshness of cached portal data. */
+type CacheInfo struct {
+	FetchedAt time.Time     // When the data was last fetched successfully
+	Age       time.Duration // Time elapsed since FetchedAt
+	Stale     bool          // Whether the data is older than the offline cache TTL
+	LastError error         // Error from the most recent failed refresh, nil after a success
+}
+
+/* WithOfflineCache serves GetChannels and GetEPG stale-while-revalidate: cached data is returned even when older than ttl or the portal is down, refreshing in the background. */
+func WithOfflineCache(ttl time.Duration) Option {
+	return func(c *StalkerClient) {
+		c.offlineTTL = ttl
+	}
+}
+
+/* cacheSlot holds one cached value together with its freshness metadata. */
+type cacheSlot[T any] struct {
+	mu         sync.Mutex
+	value      T
+	valid      bool
+	fetchedAt  time.Time
+	lastErr    error
+	refreshing bool
+	inflight   *slotFetch[T] // Fetch shared by callers missing the cache, nil when none runs
+}
+
+/* slotFetch is a running fetch of a cacheSlot miss. */
+type slotFetch[T any] struct {
+	done  chan struct{} // Closed when the fetch finishes
+	value T
+	err   error
+}
+
+/* store records a freshly fetched value. */
+func (s *cacheSlot[T]) store(v T) {
+	s.storeAt(v, time.Now())
+}
+
+/* storeAt records a value fetched at the given time. */
+func (s *cacheSlot[T]) storeAt(v T, fetchedAt time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.value, s.valid, s.fetchedAt, s.lastErr = v, true, fetchedAt, nil
+}
+
+/* load returns the cached value, if any. */
+func (s *cacheSlot[T]) load() (T, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.value, s.valid
+}
+
+/* info reports the slot's freshness relative to ttl. */
+func (s *cacheSlot[T]) info(ttl time.Duration) (CacheInfo, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.valid {
+		return CacheInfo{LastError: s.lastErr}, false
+	}
+	age := time.Since(s.fetchedAt)
+	return CacheInfo{FetchedAt: s.fetchedAt, Age: age, Stale: ttl > 0 && age >= ttl, LastError: s.lastErr}, true
+}
+
+/* readThrough returns the cached value, fetching synchronously only when nothing is cached and refreshing stale values in the background under base. */
+func (s *cacheSlot[T]) readThrough(ctx, base context.Context, ttl time.Duration, fetch func(context.Context) (T, error)) (T, error) {
+	s.mu.Lock()
+	if !s.valid {
+		s.mu.Unlock()
+		return s.fetchShared(ctx, fetch)
+	}
+	v := s.value
+	if time.Since(s.fetchedAt) >= ttl && !s.refreshing {
+		s.refreshing = true
+		go func() {
+			_, err := fetch(base)
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			s.refreshing = false
+			if err != nil {
+				s.lastErr = err
+			}
+		}()
+	}
+	s.mu.Unlock()
+	return v, nil
+}
+
+/* readFresh returns the cached value while it is younger than ttl, otherwise fetching it synchronously; fetch is expected to store what it fetched. */
+func (s *cacheSlot[T]) readFresh(ctx context.Context, ttl time.Duration, fetch func(context.Context) (T, error)) (T, error) {
+	s.mu.Lock()
+	if s.valid && time.Since(s.fetchedAt) < ttl {
+		v := s.value
+		s.mu.Unlock()
+		return v, nil
+	}
+	s.mu.Unlock()
+	return s.fetchShared(ctx, fetch)
+}
+
+/* fetchShared runs fetch for a cache miss, letting concurrent callers wait for the running fetch instead of starting their own. */
+func (s *cacheSlot[T]) fetchShared(ctx context.Context, fetch func(context.Context) (T, error)) (T, error) {
+	for {
+		s.mu.Lock()
+		f := s.inflight
+		if f == nil {
+			break
+		}
+		s.mu.Unlock()
+		select {
+		case <-f.done:
+		case <-ctx.Done():
+			var zero T
+			return zero, ctx.Err()
+		}
+
+		// A fetch abandoned by its own caller says nothing about ours
+		if errors.Is(f.err, context.Canceled) || errors.Is(f.err, context.DeadlineExceeded) {
+			continue
+		}
+		return f.value, f.err
+	}
+	f := &slotFetch[T]{done: make(chan struct{})}
+	s.inflight = f
+	s.mu.Unlock()
+
+	f.value, f.err = fetch(ctx)
+	s.mu.Lock()
+	s.inflight = nil
+	if f.err != nil {
+		s.lastErr = f.err
+	}
+	s.mu.Unlock()
+	close(f.done)
+	return f.value, f.err
+}
+
+/* flush drops the cached value. */
+func (s *cacheSlot[T]) flush() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var zero T
+	s.value, s.valid, s.lastErr = zero, false, nil
+}
+
+/* epgCache holds the most recently fetched EPG programs per channel. */
+type epgCache struct {
+	mu    sync.Mutex
+	slots map[string]*cacheSlot[[]EPGProgram]
+}
+
+/* slot returns the cache slot for a channel, creating it on first use. */
+func (e *epgCache) slot(channelID string) *cacheSlot[[]EPGProgram] {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.slots == nil {
+		e.slots = make(map[string]*cacheSlot[[]EPGProgram])
+	}
+	s, ok := e.slots[channelID]
+	if !ok {
+		s = &cacheSlot[[]EPGProgram]{}
+		e.slots[channelID] = s
+	}
+	return s
+}
+
+/* store replaces the cached programs for a channel. */
+func (e *epgCache) store(channelID string, programs []EPGProgram) {
+	e.slot(channelID).store(programs)
+}
+
+/* load returns the cached programs for a channel. */
+func (e *epgCache) load(channelID string) ([]EPGProgram, bool) {
+	e.mu.Lock()
+	s, ok := e.slots[channelID]
+	e.mu.Unlock()
+	if !ok {
+		return nil, false
+	}
+	return s.load()
+}
+
+/* channelIDs returns the IDs of all channels with cached programs. */
+func (e *epgCache) channelIDs() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	ids := make([]string, 0, len(e.slots))
+	for id, s := range e.slots {
+		if _, ok := s.load(); ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+/* flush drops all cached programs. */
+func (e *epgCache) flush() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.slots = nil
+}
+
+/* CachedEPG returns the programs last fetched by GetEPG for a channel, without contacting the portal. */
+func (c *StalkerClient) CachedEPG(channelID string) ([]EPGProgram, bool) {
+	return c.epg.load(channelID)
+}
+
+/* CachedChannels returns the channel list last fetched by GetChannels, without contacting the portal. */
+func (c *StalkerClient) CachedChannels() ([]Channel, bool) {
+	return c.channels.load()
+}
+
+/* ChannelsCacheInfo reports the age and refresh state of the cached channel list. */
+func (c *StalkerClient) ChannelsCacheInfo() (CacheInfo, bool) {
+	return c.channels.info(c.offlineTTL)
+}
+
+/* EPGCacheInfo reports the age and refresh state of a channel's cached EPG. */
+func (c *StalkerClient) EPGCacheInfo(channelID string) (CacheInfo, bool) {
+	return c.epg.slot(channelID).info(c.offlineTTL)
+}
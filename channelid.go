@@ -0,0 +1,36 @@
+package stalkerlib
+
+import (
+	"strings"
+
+	"github.com/ericcmi/stalkerlib/matching"
+)
+
+/* ChannelIDStrategy selects how exported channel IDs (M3U tvg-id, XMLTV channel id) are derived. */
+type ChannelIDStrategy int
+
+const (
+	ChannelIDXMLTV  ChannelIDStrategy = iota // The portal's xmltv_id, falling back to the portal ID
+	ChannelIDPortal                          // The portal channel ID
+	ChannelIDSlug                            // The slugified channel name, e.g. "bbc-one-hd", falling back to the portal ID
+)
+
+/* ChannelIDScheme derives exported channel IDs; M3U and XMLTV exports sharing a scheme stay aligned, and a per-portal prefix keeps aggregated lineups from colliding. */
+type ChannelIDScheme struct {
+	Strategy ChannelIDStrategy // How the ID is derived
+	Prefix   string            // Prepended to every ID, e.g. "portal1."
+}
+
+/* ID returns the exported ID of ch. */
+func (s ChannelIDScheme) ID(ch Channel) string {
+	var id string
+	switch s.Strategy {
+	case ChannelIDXMLTV:
+		id = firstNonEmpty(ch.XMLTVID, ch.ID)
+	case ChannelIDSlug:
+		id = firstNonEmpty(strings.Join(matching.Tokens(ch.Name), "-"), ch.ID)
+	default:
+		id = ch.ID
+	}
+	return s.Prefix + id
+}
@@ -0,0 +1,139 @@
+package stalkerlib
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"strings"
+	"unicode/utf8"
+)
+
+/* windows1251 maps bytes 0x80-0xFF of the windows-1251 (Cyrillic) code page to Unicode. */
+var windows1251 = [128]rune{
+	0x0402, 0x0403, 0x201A, 0x0453, 0x201E, 0x2026, 0x2020, 0x2021,
+	0x20AC, 0x2030, 0x0409, 0x2039, 0x040A, 0x040C, 0x040B, 0x040F,
+	0x0452, 0x2018, 0x2019, 0x201C, 0x201D, 0x2022, 0x2013, 0x2014,
+	0xFFFD, 0x2122, 0x0459, 0x203A, 0x045A, 0x045C, 0x045B, 0x045F,
+	0x00A0, 0x040E, 0x045E, 0x0408, 0x00A4, 0x0490, 0x00A6, 0x00A7,
+	0x0401, 0x00A9, 0x0404, 0x00AB, 0x00AC, 0x00AD, 0x00AE, 0x0407,
+	0x00B0, 0x00B1, 0x0406, 0x0456, 0x0491, 0x00B5, 0x00B6, 0x00B7,
+	0x0451, 0x2116, 0x0454, 0x00BB, 0x0458, 0x0405, 0x0455, 0x0457,
+	0x0410, 0x0411, 0x0412, 0x0413, 0x0414, 0x0415, 0x0416, 0x0417,
+	0x0418, 0x0419, 0x041A, 0x041B, 0x041C, 0x041D, 0x041E, 0x041F,
+	0x0420, 0x0421, 0x0422, 0x0423, 0x0424, 0x0425, 0x0426, 0x0427,
+	0x0428, 0x0429, 0x042A, 0x042B, 0x042C, 0x042D, 0x042E, 0x042F,
+	0x0430, 0x0431, 0x0432, 0x0433, 0x0434, 0x0435, 0x0436, 0x0437,
+	0x0438, 0x0439, 0x043A, 0x043B, 0x043C, 0x043D, 0x043E, 0x043F,
+	0x0440, 0x0441, 0x0442, 0x0443, 0x0444, 0x0445, 0x0446, 0x0447,
+	0x0448, 0x0449, 0x044A, 0x044B, 0x044C, 0x044D, 0x044E, 0x044F,
+}
+
+/* windows1252 maps bytes 0x80-0x9F of windows-1252; the remaining high bytes match ISO-8859-1. */
+var windows1252 = [32]rune{
+	0x20AC, 0xFFFD, 0x201A, 0x0192, 0x201E, 0x2026, 0x2020, 0x2021,
+	0x02C6, 0x2030, 0x0160, 0x2039, 0x0152, 0xFFFD, 0x017D, 0xFFFD,
+	0xFFFD, 0x2018, 0x2019, 0x201C, 0x201D, 0x2022, 0x2013, 0x2014,
+	0x02DC, 0x2122, 0x0161, 0x203A, 0x0153, 0xFFFD, 0x017E, 0x0178,
+}
+
+/* WithCharset forces portal responses to be decoded from the given charset (e.g. "windows-1251") instead of detecting it. */
+func WithCharset(charset string) Option {
+	return func(c *StalkerClient) {
+		c.charset = charset
+	}
+}
+
+/* decodeJSON reads a portal response body, transcodes it to UTF-8 if needed, and unmarshals it into out. */
+func (c *StalkerClient) decodeJSON(r io.Reader, contentType string, out interface{}) error {
+	body, err := c.readJSON(r, contentType)
+	if err != nil {
+		return err
+	}
+	return unmarshalPortal(body, out)
+}
+
+/* readJSON reads a portal response body and transcodes it to UTF-8 if needed. */
+func (c *StalkerClient) readJSON(r io.Reader, contentType string) ([]byte, error) {
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	charset := c.charset
+	if charset == "" {
+		charset = detectCharset(body, contentType)
+	}
+	return toUTF8(body, charset)
+}
+
+/* unmarshalPortal unmarshals a UTF-8 portal response into out, reporting a "js" error form that does not fit out as its PortalError. */
+func unmarshalPortal(body []byte, out interface{}) error {
+	err := json.Unmarshal(body, out)
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		if failure := portalFailureFromBody(body); failure != nil {
+			return failure
+		}
+	}
+	return err
+}
+
+/* detectCharset determines the body charset from the Content-Type header, falling back to heuristics for invalid UTF-8. */
+func detectCharset(body []byte, contentType string) string {
+	if _, params, err := mime.ParseMediaType(contentType); err == nil && params["charset"] != "" {
+		return params["charset"]
+	}
+	if utf8.Valid(body) {
+		return "utf-8"
+	}
+
+	// Cyrillic text encodes whole words as runs of high bytes, while
+	// Western European text has isolated accented letters between ASCII ones
+	high, adjacent := 0, 0
+	for i, b := range body {
+		if b < 0x80 {
+			continue
+		}
+		high++
+		if (i > 0 && body[i-1] >= 0x80) || (i+1 < len(body) && body[i+1] >= 0x80) {
+			adjacent++
+		}
+	}
+	if adjacent*2 > high {
+		return "windows-1251"
+	}
+	return "windows-1252"
+}
+
+/* toUTF8 transcodes body from the named single-byte charset to UTF-8. */
+func toUTF8(body []byte, charset string) ([]byte, error) {
+	var table func(b byte) rune
+	switch strings.ToLower(charset) {
+	case "", "utf-8", "utf8":
+		return body, nil
+	case "windows-1251", "cp1251", "x-cp1251":
+		table = func(b byte) rune { return windows1251[b-0x80] }
+	case "windows-1252", "cp1252":
+		table = func(b byte) rune {
+			if b < 0xA0 {
+				return windows1252[b-0x80]
+			}
+			return rune(b)
+		}
+	case "iso-8859-1", "latin1", "latin-1", "iso8859-1":
+		table = func(b byte) rune { return rune(b) }
+	default:
+		return nil, fmt.Errorf("unsupported charset %q", charset)
+	}
+
+	out := make([]byte, 0, len(body)*2)
+	for _, b := range body {
+		if b < 0x80 {
+			out = append(out, b)
+			continue
+		}
+		out = utf8.AppendRune(out, table(b))
+	}
+	return out, nil
+}
@@ -0,0 +1,76 @@
+package stalkerlib
+
+import (
+	"slices"
+
+	"github.com/ericcmi/stalkerlib/matching"
+)
+
+/* defaultQualityPreference ranks variants from the best picture down. */
+var defaultQualityPreference = []matching.Quality{
+	matching.QualityUHD, matching.QualityFHD, matching.QualityHD, matching.QualitySD, matching.QualityUnknown,
+}
+
+/* QualityCollapse makes exporters keep a single variant of channels the lineup carries in several qualities, e.g. "ESPN SD", "ESPN HD" and "ESPN FHD". */
+type QualityCollapse struct {
+	Enabled    bool               // Collapse variants whose names match apart from quality markers
+	Preference []matching.Quality // Qualities from most to least preferred, e.g. {QualityFHD, QualityHD} to avoid 4K; nil for UHD, FHD, HD, SD, unmarked
+}
+
+/* CollapseQualities returns channels with each set of same-region quality variants reduced to the most preferred one, placed where the set's first variant was; unlisted qualities rank after listed ones, and ties keep the earlier channel. */
+func CollapseQualities(channels []Channel, preference []matching.Quality) []Channel {
+	if preference == nil {
+		preference = defaultQualityPreference
+	}
+	rank := func(ch Channel) int {
+		if i := slices.Index(preference, channelQuality(ch)); i >= 0 {
+			return i
+		}
+		return len(preference)
+	}
+
+	index := make(map[string]int)
+	collapsed := make([]Channel, 0, len(channels))
+	for _, ch := range channels {
+		name := matching.Normalize(ch.Name)
+		if name == "" {
+			collapsed = append(collapsed, ch)
+			continue
+		}
+
+		// Same-named channels of different regions are different feeds
+		key := ch.Region + "|" + name
+		i, ok := index[key]
+		if !ok {
+			index[key] = len(collapsed)
+			collapsed = append(collapsed, ch)
+			continue
+		}
+		if rank(ch) < rank(collapsed[i]) {
+			collapsed[i] = ch
+		}
+	}
+	return collapsed
+}
+
+/* channelQuality returns the quality advertised by the channel name, falling back to HD for channels the portal flags as such. */
+func channelQuality(ch Channel) matching.Quality {
+	q := matching.QualityOf(ch.Name)
+	if q == matching.QualityUnknown && ch.HD {
+		return matching.QualityHD
+	}
+	return q
+}
+
+/* exportChannels returns the channels an exporter writes: the live ones of the selected regions, collapsed to one quality variant and sorted when asked, leaving the caller's slice untouched. */
+func (c *StalkerClient) exportChannels(channels []Channel, regions []string, collapse QualityCollapse, order ChannelSort) []Channel {
+	channels = FilterRegions(c.availableChannels(channels), regions)
+	if collapse.Enabled {
+		channels = CollapseQualities(channels, collapse.Preference)
+	}
+	if order.Enabled {
+		channels = slices.Clone(channels)
+		SortChannels(channels, order.Strategy)
+	}
+	return channels
+}
@@ -0,0 +1,99 @@
+package stalkerlib
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+/* ContentDecoder wraps a compressed response body in a reader of the decoded content. */
+type ContentDecoder func(r io.Reader) (io.ReadCloser, error)
+
+/* WithContentDecoder adds a Content-Encoding the client advertises and decodes, such as "br" backed by a Brotli package. */
+func WithContentDecoder(encoding string, decoder ContentDecoder) Option {
+	return func(c *StalkerClient) {
+		if c.decoders == nil {
+			c.decoders = make(map[string]ContentDecoder)
+		}
+		c.decoders[strings.ToLower(encoding)] = decoder
+	}
+}
+
+/* builtinDecoders are the encodings supported by the standard library. */
+var builtinDecoders = map[string]ContentDecoder{
+	"gzip":   func(r io.Reader) (io.ReadCloser, error) { return gzip.NewReader(r) },
+	"x-gzip": func(r io.Reader) (io.ReadCloser, error) { return gzip.NewReader(r) },
+	"deflate": func(r io.Reader) (io.ReadCloser, error) {
+		// Many servers send raw DEFLATE instead of the zlib stream the RFC asks for
+		br := bufio.NewReader(r)
+		header, err := br.Peek(2)
+		if err == nil && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 && header[0]&0x0f == 8 {
+			return zlib.NewReader(br)
+		}
+		return flate.NewReader(br), nil
+	},
+}
+
+/* contentDecoder returns the decoder of an encoding, preferring one added with WithContentDecoder. */
+func (c *StalkerClient) contentDecoder(encoding string) (ContentDecoder, bool) {
+	encoding = strings.ToLower(strings.TrimSpace(encoding))
+	if d, ok := c.decoders[encoding]; ok {
+		return d, true
+	}
+	d, ok := builtinDecoders[encoding]
+	return d, ok
+}
+
+/* acceptEncoding lists the advertised encodings: gzip and deflate, then any added decoders. */
+func (c *StalkerClient) acceptEncoding() string {
+	encodings := []string{"gzip", "deflate"}
+	var extra []string
+	for encoding := range c.decoders {
+		if encoding != "gzip" && encoding != "deflate" {
+			extra = append(extra, encoding)
+		}
+	}
+	sort.Strings(extra)
+	return strings.Join(append(encodings, extra...), ", ")
+}
+
+/* decodeResponse replaces a compressed body with its decoded content and marks the response Uncompressed, as the standard transport does. */
+func (c *StalkerClient) decodeResponse(resp *http.Response) error {
+	encoding := resp.Header.Get("Content-Encoding")
+	if encoding == "" || strings.EqualFold(encoding, "identity") {
+		return nil
+	}
+	decoder, ok := c.contentDecoder(encoding)
+	if !ok {
+		return nil
+	}
+	body, err := decoder(resp.Body)
+	if err != nil {
+		resp.Body.Close()
+		return fmt.Errorf("failed to decode %s response: %w", encoding, err)
+	}
+	resp.Body = &decodedBody{ReadCloser: body, raw: resp.Body}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+	return nil
+}
+
+/* decodedBody closes both the decoder and the underlying response body. */
+type decodedBody struct {
+	io.ReadCloser
+	raw io.ReadCloser
+}
+
+/* Close closes the decoder, then the raw body. */
+func (b *decodedBody) Close() error {
+	b.ReadCloser.Close()
+	return b.raw.Close()
+}
@@ -0,0 +1,67 @@
+package stalkerlib
+
+import "time"
+
+/* CoverageOptions sets the thresholds used by ReportEPGCoverage. */
+type CoverageOptions struct {
+	MinWindow time.Duration // Guide data should extend at least this far past now; 24h when zero
+	MaxAge    time.Duration // Cached EPG older than this is stale; 24h when zero
+	Now       time.Time     // Reference time; time.Now() when zero
+}
+
+/* ChannelCoverage summarizes the cached guide data of one channel. */
+type ChannelCoverage struct {
+	Channel      Channel       // Channel the summary describes
+	Programs     int           // Number of cached programs
+	CoveredUntil time.Time     // End of the last cached program, zero without data
+	Window       time.Duration // How far past now the guide reaches
+	FetchedAt    time.Time     // When the EPG was last fetched
+}
+
+/* EPGCoverageReport groups channels by guide-data problems. */
+type EPGCoverageReport struct {
+	Missing []ChannelCoverage // Channels with no guide data at all
+	Short   []ChannelCoverage // Channels whose guide ends before the minimum window
+	Stale   []ChannelCoverage // Channels whose EPG was fetched too long ago
+	OK      []ChannelCoverage // Channels without problems
+}
+
+/* ReportEPGCoverage checks the cached EPG of every channel in the lineup, identifying candidates for an external XMLTV overlay. */
+func (c *StalkerClient) ReportEPGCoverage(channels []Channel, opts CoverageOptions) EPGCoverageReport {
+	now := opts.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	minWindow := orDefault(opts.MinWindow, 24*time.Hour)
+	maxAge := orDefault(opts.MaxAge, 24*time.Hour)
+
+	var report EPGCoverageReport
+	for _, ch := range channels {
+		cov := ChannelCoverage{Channel: ch}
+		programs, _ := c.epg.load(ch.ID)
+		if info, ok := c.epg.slot(ch.ID).info(0); ok {
+			cov.FetchedAt = info.FetchedAt
+		}
+		cov.Programs = len(programs)
+		for _, p := range programs {
+			if stop := time.Unix(p.Stop, 0); stop.After(cov.CoveredUntil) {
+				cov.CoveredUntil = stop
+			}
+		}
+		if cov.CoveredUntil.After(now) {
+			cov.Window = cov.CoveredUntil.Sub(now)
+		}
+
+		switch {
+		case cov.Programs == 0:
+			report.Missing = append(report.Missing, cov)
+		case now.Sub(cov.FetchedAt) > maxAge:
+			report.Stale = append(report.Stale, cov)
+		case cov.Window < minWindow:
+			report.Short = append(report.Short, cov)
+		default:
+			report.OK = append(report.OK, cov)
+		}
+	}
+	return report
+}
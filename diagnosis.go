@@ -0,0 +1,329 @@
+package stalkerlib
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"regexp"
+	"strings"
+	"syscall"
+	"time"
+)
+
+/* DiagnosisCause classifies why the portal cannot be used from this network. */
+type DiagnosisCause int
+
+const (
+	CauseNone            DiagnosisCause = iota // Every stage passed
+	CauseDNSFailure                            // The portal host does not resolve
+	CauseDNSBlocked                            // The host resolves only to sinkhole addresses such as 0.0.0.0 or loopback
+	CauseTCPReset                              // Connections are reset or refused, including resets during the TLS handshake
+	CauseTCPTimeout                            // Connections time out
+	CauseTLSInterception                       // The TLS certificate is untrusted or issued for another name
+	CauseBlockPage                             // The portal URL answers with an HTML page, status 451, or a redirect to another host
+	CauseAccountBanned                         // The portal rejects the MAC or reports the account as blocked
+	CauseEmptyData                             // The account works but the portal returns no channels
+)
+
+/* String returns the cause name. */
+func (c DiagnosisCause) String() string {
+	switch c {
+	case CauseNone:
+		return "None"
+	case CauseDNSFailure:
+		return "DNSFailure"
+	case CauseDNSBlocked:
+		return "DNSBlocked"
+	case CauseTCPReset:
+		return "TCPReset"
+	case CauseTCPTimeout:
+		return "TCPTimeout"
+	case CauseTLSInterception:
+		return "TLSInterception"
+	case CauseBlockPage:
+		return "BlockPage"
+	case CauseAccountBanned:
+		return "AccountBanned"
+	case CauseEmptyData:
+		return "EmptyData"
+	}
+	return "Unknown"
+}
+
+/* diagnosisAdvice suggests what the user can do about each cause. */
+var diagnosisAdvice = map[DiagnosisCause]string{
+	CauseDNSFailure:      "Check the portal URL, or try another resolver with WithResolver or NewDoHResolver.",
+	CauseDNSBlocked:      "Your DNS provider is blocking the portal; use NewDoHResolver or a different DNS server.",
+	CauseTCPReset:        "The connection is being cut, often by ISP filtering; try a VPN or ask the provider for an alternative host.",
+	CauseTCPTimeout:      "The portal is unreachable; it may be down or filtered, so retry later or through a VPN.",
+	CauseTLSInterception: "Something between you and the portal presents its own certificate; check for a filtering proxy or use a VPN.",
+	CauseBlockPage:       "The network serves a block page in place of the portal; use a VPN or an alternative portal host.",
+	CauseAccountBanned:   "The portal rejects this MAC; contact the provider about the subscription.",
+	CauseEmptyData:       "The account has no channels; check the subscription's tariff plan with the provider.",
+}
+
+/* DiagnosisStage is the outcome of one step of Diagnose. */
+type DiagnosisStage struct {
+	Name     string        // "dns", "tcp", "tls", "http", "account", or "data"
+	OK       bool          // Whether the step passed
+	Detail   string        // What was observed
+	Duration time.Duration // Time the step took
+}
+
+/* DiagnosisReport is the result of Diagnose. */
+type DiagnosisReport struct {
+	Host      string           // Portal host that was examined
+	Addresses []string         // Addresses the host resolved to
+	Cause     DiagnosisCause   // First problem found, CauseNone when everything passed
+	Stages    []DiagnosisStage // Steps in the order they ran, ending at the first failure
+	Advice    string           // Suggested next step for Cause, empty for CauseNone
+}
+
+/* Err returns nil for a healthy portal, or an error naming the cause and the failing stage. */
+func (r DiagnosisReport) Err() error {
+	if r.Cause == CauseNone || len(r.Stages) == 0 {
+		return nil
+	}
+	last := r.Stages[len(r.Stages)-1]
+	return fmt.Errorf("portal %s: %s at %s stage: %s", r.Host, r.Cause, last.Name, last.Detail)
+}
+
+/* htmlTitle extracts the title of a block page. */
+var htmlTitle = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+
+/* Diagnose examines each layer between the client and the portal in turn, DNS, TCP, TLS, HTTP, the account, and the channel data, stopping at the first failure, to tell network blocks apart from portal and account problems. */
+func (c *StalkerClient) Diagnose(ctx context.Context) DiagnosisReport {
+	var report DiagnosisReport
+	u, err := url.Parse(c.apiURL())
+	if err != nil {
+		report.Cause = CauseDNSFailure
+		report.Stages = append(report.Stages, DiagnosisStage{Name: "dns", Detail: "invalid portal URL: " + err.Error()})
+		report.Advice = diagnosisAdvice[report.Cause]
+		return report
+	}
+	report.Host = u.Hostname()
+	stage := func(name string, run func() (DiagnosisCause, string)) bool {
+		start := time.Now()
+		cause, detail := run()
+		report.Stages = append(report.Stages, DiagnosisStage{Name: name, OK: cause == CauseNone, Detail: detail, Duration: time.Since(start)})
+		if cause != CauseNone {
+			report.Cause, report.Advice = cause, diagnosisAdvice[cause]
+			return false
+		}
+		return true
+	}
+
+	port := u.Port()
+	if port == "" {
+		port = "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+	}
+	addr := net.JoinHostPort(report.Host, port)
+	if !stage("dns", func() (DiagnosisCause, string) {
+		cause, detail, addrs := c.diagnoseDNS(ctx, report.Host)
+		report.Addresses = addrs
+		return cause, detail
+	}) {
+		return report
+	}
+	var conn net.Conn
+	if !stage("tcp", func() (DiagnosisCause, string) {
+		dialer := &net.Dialer{Timeout: orDefault(c.timeouts.Dial, 10*time.Second), Resolver: c.resolver}
+		if c.failover != nil {
+			dialer.FallbackDelay = c.failover.opts.FallbackDelay
+		}
+		conn, err = c.dial(ctx, dialer, "tcp", addr)
+		if err != nil {
+			return dialCause(err), err.Error()
+		}
+		return CauseNone, "connected to " + conn.RemoteAddr().String()
+	}) {
+		return report
+	}
+	defer conn.Close()
+	if u.Scheme == "https" && !stage("tls", func() (DiagnosisCause, string) {
+		return c.diagnoseTLS(ctx, conn, report.Host)
+	}) {
+		return report
+	}
+	if !stage("http", func() (DiagnosisCause, string) {
+		return c.diagnoseHTTP(ctx, u.Host)
+	}) || !stage("account", func() (DiagnosisCause, string) {
+		return c.diagnoseAccount(ctx)
+	}) {
+		return report
+	}
+	stage("data", func() (DiagnosisCause, string) {
+		channels, err := c.fetchChannels(ctx)
+		if err != nil {
+			return CauseEmptyData, err.Error()
+		}
+		if len(channels) == 0 {
+			return CauseEmptyData, "the portal returned an empty channel list"
+		}
+		return CauseNone, fmt.Sprintf("%d channels", len(channels))
+	})
+	return report
+}
+
+/* diagnoseDNS resolves host as the client's dials do, through configured portal addresses, the DNS cache and its pins, or the client's resolver, flagging answers that only point at sinkholes. */
+func (c *StalkerClient) diagnoseDNS(ctx context.Context, host string) (DiagnosisCause, string, []string) {
+	if ip := net.ParseIP(host); ip != nil {
+		return CauseNone, "portal host is an IP address", []string{ip.String()}
+	}
+	if c.failover != nil && len(c.failover.opts.PortalAddrs) > 0 {
+		addrs := c.failover.opts.PortalAddrs
+		return CauseNone, "using configured portal addresses " + strings.Join(addrs, ", "), addrs
+	}
+	var ips []netip.Addr
+	var err error
+	if c.dnsCache != nil {
+		ips, err = c.dnsCache.lookup(ctx, host, c.resolver)
+	} else {
+		resolver := c.resolver
+		if resolver == nil {
+			resolver = net.DefaultResolver
+		}
+		ips, err = resolver.LookupNetIP(ctx, "ip", host)
+	}
+	if err != nil {
+		return CauseDNSFailure, err.Error(), nil
+	}
+	addrs := make([]string, len(ips))
+	sinkholed := true
+	for i, ip := range ips {
+		addrs[i] = ip.String()
+		if !ip.IsUnspecified() && !ip.IsLoopback() {
+			sinkholed = false
+		}
+	}
+	if len(ips) == 0 {
+		return CauseDNSFailure, "no addresses", nil
+	}
+	if sinkholed {
+		return CauseDNSBlocked, "resolved only to " + strings.Join(addrs, ", "), addrs
+	}
+	return CauseNone, "resolved to " + strings.Join(addrs, ", "), addrs
+}
+
+/* dialCause classifies a failed connection attempt. */
+func dialCause(err error) DiagnosisCause {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return CauseTCPTimeout
+	}
+	return CauseTCPReset
+}
+
+/* diagnoseTLS handshakes over conn with standard verification, reporting the issuer of a certificate that fails it. */
+func (c *StalkerClient) diagnoseTLS(ctx context.Context, conn net.Conn, host string) (DiagnosisCause, string) {
+	serverName := host
+	if c.hostOverride != "" {
+		serverName = c.hostOverride
+		if h, _, err := net.SplitHostPort(c.hostOverride); err == nil {
+			serverName = h
+		}
+	}
+	tlsConn := tls.Client(conn, &tls.Config{ServerName: serverName})
+	hsCtx, cancel := context.WithTimeout(ctx, orDefault(c.timeouts.TLSHandshake, 10*time.Second))
+	defer cancel()
+	err := tlsConn.HandshakeContext(hsCtx)
+	if err == nil {
+		state := tlsConn.ConnectionState()
+		return CauseNone, "certificate issued by " + state.PeerCertificates[0].Issuer.String()
+	}
+
+	var unknown x509.UnknownAuthorityError
+	var hostname x509.HostnameError
+	var invalid x509.CertificateInvalidError
+	switch {
+	case errors.As(err, &unknown) && unknown.Cert != nil:
+		return CauseTLSInterception, "untrusted certificate issued by " + unknown.Cert.Issuer.String()
+	case errors.As(err, &hostname) && hostname.Certificate != nil:
+		return CauseTLSInterception, fmt.Sprintf("certificate for %v issued by %s", hostname.Certificate.DNSNames, hostname.Certificate.Issuer)
+	case errors.As(err, &invalid):
+		return CauseTLSInterception, err.Error()
+	case errors.Is(err, syscall.ECONNRESET), errors.Is(err, io.EOF):
+		// Resets right after the ClientHello are typical of SNI filtering
+		return CauseTCPReset, "connection cut during the TLS handshake: " + err.Error()
+	}
+	return dialCause(err), err.Error()
+}
+
+/* diagnoseHTTP sends a handshake and checks that the answer is portal JSON from the portal host rather than a block page. */
+func (c *StalkerClient) diagnoseHTTP(ctx context.Context, host string) (DiagnosisCause, string) {
+	resp, err := c.sendAction(ctx, "stb", "handshake", nil)
+	if err != nil {
+		if errors.Is(err, ErrCrossHostRedirect) {
+			return CauseBlockPage, err.Error()
+		}
+		return dialCause(err), err.Error()
+	}
+	defer resp.Body.Close()
+	if final := resp.Request.URL.Host; final != host {
+		return CauseBlockPage, "redirected to " + resp.Request.URL.String()
+	}
+	if resp.StatusCode == http.StatusUnavailableForLegalReasons {
+		return CauseBlockPage, "status 451 Unavailable For Legal Reasons"
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	body = bytes.TrimSpace(body)
+	if strings.Contains(resp.Header.Get("Content-Type"), "text/html") || bytes.HasPrefix(body, []byte("<")) {
+		detail := fmt.Sprintf("status %d with an HTML page", resp.StatusCode)
+		if m := htmlTitle.FindSubmatch(body); m != nil {
+			detail += fmt.Sprintf(" titled %q", strings.TrimSpace(string(m[1])))
+		}
+		return CauseBlockPage, detail
+	}
+	if resp.StatusCode != http.StatusOK {
+		return CauseBlockPage, fmt.Sprintf("status %d", resp.StatusCode)
+	}
+	if !json.Valid(body) {
+		return CauseBlockPage, "the answer is not JSON"
+	}
+	return CauseNone, "portal answered with JSON"
+}
+
+/* profileStatus is the blocking state within the stb get_profile response. */
+type profileStatus struct {
+	Js struct {
+		Blocked  flexString `json:"blocked"`
+		BlockMsg string     `json:"block_msg"`
+	} `json:"js"`
+}
+
+/* diagnoseAccount handshakes and reads the profile, reporting rejected MACs and blocked accounts. */
+func (c *StalkerClient) diagnoseAccount(ctx context.Context) (DiagnosisCause, string) {
+	if err := c.authenticate(ctx); err != nil {
+		var portalErr *PortalError
+		if errors.As(err, &portalErr) {
+			return CauseAccountBanned, err.Error()
+		}
+		return dialCause(err), err.Error()
+	}
+	if c.CurrentToken() == "" {
+		return CauseAccountBanned, "the portal issued no token"
+	}
+	var profile profileStatus
+	if err := c.doAction(ctx, "stb", "get_profile", nil, &profile); err != nil {
+		if errors.Is(err, ErrAuthorizationFailed) {
+			return CauseAccountBanned, err.Error()
+		}
+		return CauseNone, "authenticated; profile unavailable: " + err.Error()
+	}
+	if js := profile.Js; js.Blocked == "1" || js.BlockMsg != "" {
+		return CauseAccountBanned, firstNonEmpty(js.BlockMsg, "the portal reports the account as blocked")
+	}
+	return CauseNone, "authenticated"
+}
@@ -0,0 +1,100 @@
+package stalkerlib
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+)
+
+/* ShapeDiagnostic describes the raw structure of a response that decoded to an empty or unrecognized list. */
+type ShapeDiagnostic struct {
+	Action    string   `json:"action"`    // Portal action, e.g. "get_all_channels"
+	Keys      []string `json:"keys"`      // Members of "js", or "[]" for an array and "null" when absent
+	Text      string   `json:"text"`      // Portal message from the envelope's "text" member
+	Resembles string   `json:"resembles"` // Closest known schema, empty when nothing matches
+}
+
+/* knownShape is a response schema recognized by its characteristic "js" members. */
+type knownShape struct {
+	name string
+	keys []string
+}
+
+/* knownShapes lists the schemas ShapeDiagnostic can recognize. */
+var knownShapes = []knownShape{
+	{"stalker channel list", []string{"channels"}},
+	{"ministra paginated list", []string{"total_items", "max_page_items", "data"}},
+	{"stalker epg", []string{"programs"}},
+	{"ministra epg info", []string{"data"}},
+	{"portal error", []string{"error"}},
+	{"account status", []string{"status", "msg"}},
+	{"handshake", []string{"token"}},
+	{"xtream codes", []string{"user_info", "server_info"}},
+}
+
+/* WithShapeDiagnostics calls fn whenever a channel or EPG response decodes to an empty or unrecognized list, describing which known schema the raw JSON resembles. */
+func WithShapeDiagnostics(fn func(ShapeDiagnostic)) Option {
+	return func(c *StalkerClient) {
+		c.diagnostics = fn
+	}
+}
+
+/* DiagnoseShape inspects a raw load.php response body and reports its structure. */
+func DiagnoseShape(action string, body []byte) ShapeDiagnostic {
+	var env portalEnvelope
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(body, &obj); err == nil {
+		json.Unmarshal(body, &env)
+		if len(env.Js) == 0 && len(obj) > 0 {
+			// Not wrapped in "js"; inspect the top level instead
+			env.Js = body
+		}
+	}
+	return diagnoseEnvelope(action, env)
+}
+
+/* diagnoseEnvelope describes a decoded response envelope. */
+func diagnoseEnvelope(action string, env portalEnvelope) ShapeDiagnostic {
+	d := ShapeDiagnostic{Action: action, Text: env.Text}
+	js := bytes.TrimSpace(env.Js)
+	switch {
+	case len(js) == 0 || bytes.Equal(js, []byte("null")):
+		d.Keys = []string{"null"}
+		return d
+	case js[0] == '[':
+		d.Keys = []string{"[]"}
+		return d
+	}
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(js, &obj); err != nil {
+		return d
+	}
+	for k := range obj {
+		d.Keys = append(d.Keys, k)
+	}
+	sort.Strings(d.Keys)
+
+	// Pick the schema with the most keys present, then the largest share of its keys
+	bestFound, bestShare := 0, 0.0
+	for _, shape := range knownShapes {
+		found := 0
+		for _, k := range shape.keys {
+			if _, ok := obj[k]; ok {
+				found++
+			}
+		}
+		share := float64(found) / float64(len(shape.keys))
+		if found > bestFound || (found == bestFound && found > 0 && share > bestShare) {
+			bestFound, bestShare, d.Resembles = found, share, shape.name
+		}
+	}
+	return d
+}
+
+/* diagnose reports a decoded list of n items to the diagnostics callback when it is empty or failed to decode. */
+func (c *StalkerClient) diagnose(action string, env portalEnvelope, n int, err error) {
+	if c.diagnostics == nil || (n > 0 && err == nil) {
+		return
+	}
+	c.diagnostics(diagnoseEnvelope(action, env))
+}
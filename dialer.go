@@ -0,0 +1,261 @@
+package stalkerlib
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+/* DialOptions tunes how connections are established, for portal names that resolve to several servers of varying health. */
+type DialOptions struct {
+	FallbackDelay time.Duration // Head start of the first address family before the other is raced (Happy Eyeballs); 0 for 300ms, negative to dial strictly in order
+	Family        AddressFamily // Forced address family, as with WithAddressFamily; AddressFamilyAny keeps the client's setting
+	PortalAddrs   []string      // Addresses of the portal host, "ip" or "ip:port", used instead of its DNS answer
+	Cooldown      time.Duration // How long a portal address that failed to connect is tried last; 0 for one minute
+}
+
+/* WithDialOptions applies o to every connection; dials to the portal host fail over between its addresses, trying recently failed ones last. */
+func WithDialOptions(o DialOptions) Option {
+	return func(c *StalkerClient) {
+		if o.Family != AddressFamilyAny {
+			c.family = o.Family
+		}
+		if o.Cooldown <= 0 {
+			o.Cooldown = time.Minute
+		}
+		c.failover = &portalFailover{opts: o, failed: make(map[string]time.Time)}
+	}
+}
+
+/* portalFailover orders the portal's addresses by recent connect failures. */
+type portalFailover struct {
+	opts DialOptions
+
+	mu     sync.Mutex
+	failed map[string]time.Time // Address to when it last failed to connect
+}
+
+/* dial connects to addr, failing over between the portal's addresses when addr names the portal host. */
+func (c *StalkerClient) dial(ctx context.Context, dialer *net.Dialer, network, addr string) (net.Conn, error) {
+	network = c.family.network(network)
+	f := c.failover
+	host, port, err := net.SplitHostPort(addr)
+	if f == nil || err != nil || !strings.EqualFold(host, c.portalHost()) {
+		if c.dnsCache != nil {
+			return c.dnsCache.DialContext(ctx, dialer, network, addr)
+		}
+		return dialer.DialContext(ctx, network, addr)
+	}
+
+	targets, err := c.portalTargets(ctx, host, port, network)
+	if err != nil {
+		return nil, err
+	}
+	return dialTargets(ctx, dialer, network, f.order(targets), f.opts.FallbackDelay, f.record)
+}
+
+/* portalHost returns the host name of PortalURL. */
+func (c *StalkerClient) portalHost() string {
+	u, err := url.Parse(c.PortalURL)
+	if err != nil {
+		return ""
+	}
+	return u.Hostname()
+}
+
+/* portalTargets lists the "ip:port" addresses to try for the portal: the configured ones, or the host's DNS answer in the dial network's family. */
+func (c *StalkerClient) portalTargets(ctx context.Context, host, port, network string) ([]string, error) {
+	var targets []string
+	for _, a := range c.failover.opts.PortalAddrs {
+		if _, _, err := net.SplitHostPort(a); err != nil {
+			a = net.JoinHostPort(a, port)
+		}
+		targets = append(targets, a)
+	}
+	if len(targets) > 0 {
+		return targets, nil
+	}
+
+	var addrs []netip.Addr
+	var err error
+	if c.dnsCache != nil {
+		addrs, err = c.dnsCache.lookup(ctx, host, c.resolver)
+	} else {
+		resolver := c.resolver
+		if resolver == nil {
+			resolver = net.DefaultResolver
+		}
+		addrs, err = resolver.LookupNetIP(ctx, "ip", host)
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, ip := range addrs {
+		ip = ip.Unmap()
+		if (strings.HasSuffix(network, "4") && !ip.Is4()) || (strings.HasSuffix(network, "6") && !ip.Is6()) {
+			continue
+		}
+		targets = append(targets, net.JoinHostPort(ip.String(), port))
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("no %s addresses for %s", network, host)
+	}
+	return targets, nil
+}
+
+/* isIPv6Target reports whether an "ip:port" address is IPv6. */
+func isIPv6Target(target string) bool {
+	host, _, _ := net.SplitHostPort(target)
+	return strings.Contains(host, ":")
+}
+
+/* order moves addresses that failed within the cooldown behind the others, keeping the relative order of each group. */
+func (f *portalFailover) order(targets []string) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	now := time.Now()
+	healthy := make([]string, 0, len(targets))
+	var failing []string
+	for _, target := range targets {
+		if at, ok := f.failed[target]; ok && now.Sub(at) < f.opts.Cooldown {
+			failing = append(failing, target)
+		} else {
+			healthy = append(healthy, target)
+		}
+	}
+	return append(healthy, failing...)
+}
+
+/* record remembers whether a connection to target succeeded. */
+func (f *portalFailover) record(target string, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err != nil {
+		f.failed[target] = time.Now()
+	} else {
+		delete(f.failed, target)
+	}
+}
+
+/* dialResult is the outcome of one racer of dialParallel. */
+type dialResult struct {
+	conn    net.Conn
+	err     error
+	primary bool
+}
+
+/* dialTargets connects to the first reachable of targets within dialer.Timeout, racing the first target's address family against the other after fallbackDelay (300ms when 0, dialing strictly in order when negative); record, when set, sees the outcome of every finished attempt. */
+func dialTargets(ctx context.Context, dialer *net.Dialer, network string, targets []string, fallbackDelay time.Duration, record func(target string, err error)) (net.Conn, error) {
+	// The timeout bounds the whole dial, as with net.Dialer, and is shared out between the attempts
+	if dialer.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, dialer.Timeout)
+		defer cancel()
+		d := *dialer
+		d.Timeout = 0
+		dialer = &d
+	}
+	var primaries, fallbacks []string
+	for _, target := range targets {
+		if isIPv6Target(target) == isIPv6Target(targets[0]) {
+			primaries = append(primaries, target)
+		} else {
+			fallbacks = append(fallbacks, target)
+		}
+	}
+	if fallbackDelay < 0 {
+		primaries, fallbacks = targets, nil
+	}
+	if fallbackDelay <= 0 {
+		fallbackDelay = 300 * time.Millisecond
+	}
+	return dialParallel(ctx, dialer, network, primaries, fallbacks, fallbackDelay, record)
+}
+
+/* dialParallel dials primaries in order, racing fallbacks in order once delay passes or the primaries fail, and returns the first connection. */
+func dialParallel(ctx context.Context, dialer *net.Dialer, network string, primaries, fallbacks []string, delay time.Duration, record func(string, error)) (net.Conn, error) {
+	if len(fallbacks) == 0 {
+		return dialSerial(ctx, dialer, network, primaries, record)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	results := make(chan dialResult)
+	race := func(targets []string, primary bool) {
+		conn, err := dialSerial(ctx, dialer, network, targets, record)
+		select {
+		case results <- dialResult{conn: conn, err: err, primary: primary}:
+		case <-ctx.Done():
+			if conn != nil {
+				conn.Close()
+			}
+		}
+	}
+	go race(primaries, true)
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	var errs []error
+	fallbackStarted := false
+	for {
+		select {
+		case <-timer.C:
+			if !fallbackStarted {
+				fallbackStarted = true
+				go race(fallbacks, false)
+			}
+		case res := <-results:
+			if res.err == nil {
+				return res.conn, nil
+			}
+			errs = append(errs, res.err)
+			if len(errs) == 2 {
+				return nil, errors.Join(errs...)
+			}
+			if res.primary && !fallbackStarted {
+				fallbackStarted = true
+				go race(fallbacks, false)
+			}
+		}
+	}
+}
+
+/* dialSerial tries targets in order, recording each outcome, until one connects; each attempt gets an equal share of the time left. */
+func dialSerial(ctx context.Context, dialer *net.Dialer, network string, targets []string, record func(string, error)) (net.Conn, error) {
+	var errs []error
+	for i, target := range targets {
+		attemptCtx, cancel := partialDeadline(ctx, len(targets)-i)
+		conn, err := dialer.DialContext(attemptCtx, network, target)
+		cancel()
+		if ctx.Err() != nil {
+			// A canceled race says nothing about the address
+			if conn != nil {
+				conn.Close()
+			}
+			return nil, ctx.Err()
+		}
+		if record != nil {
+			record(target, err)
+		}
+		if err == nil {
+			return conn, nil
+		}
+		errs = append(errs, err)
+	}
+	return nil, errors.Join(errs...)
+}
+
+/* partialDeadline limits one of remaining dial attempts to its share of the time left in ctx, but no less than two seconds, as net.Dialer does. */
+func partialDeadline(ctx context.Context, remaining int) (context.Context, context.CancelFunc) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return context.WithCancel(ctx)
+	}
+	share := time.Until(deadline) / time.Duration(remaining)
+	return context.WithTimeout(ctx, max(share, 2*time.Second))
+}
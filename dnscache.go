@@ -0,0 +1,178 @@
+package stalkerlib
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+	"sync"
+	"time"
+)
+
+/* DNSCache keeps host lookups for a fixed TTL, serving the last answer when a refresh fails, and lets addresses be pinned per host; one cache can be shared by the client and the relay server. */
+type DNSCache struct {
+	ttl      time.Duration
+	resolver *net.Resolver
+
+	mu      sync.Mutex
+	entries map[string]*dnsEntry
+	pins    map[string][]netip.Addr
+}
+
+/* dnsEntry is the cached answer for one host. */
+type dnsEntry struct {
+	addrs   []netip.Addr
+	expires time.Time
+	lookup  chan struct{} // Closed when a running lookup finishes, nil when none runs
+	err     error         // Failure of the last lookup, for callers waiting on it without an answer
+}
+
+/* dnsStaleRetry bounds how long a stale answer is served after a failed refresh before the host is looked up again. */
+const dnsStaleRetry = 30 * time.Second
+
+/* NewDNSCache creates a cache keeping answers for ttl, looked up with resolver; when nil, lookups use the resolver of the dialing client or relay (WithResolver), or net.DefaultResolver. */
+func NewDNSCache(ttl time.Duration, resolver *net.Resolver) *DNSCache {
+	return &DNSCache{ttl: ttl, resolver: resolver, entries: make(map[string]*dnsEntry), pins: make(map[string][]netip.Addr)}
+}
+
+/* WithDNSCache resolves portal and stream hosts through cache when dialing. */
+func WithDNSCache(cache *DNSCache) Option {
+	return func(c *StalkerClient) {
+		c.dnsCache = cache
+	}
+}
+
+/* Pin makes host resolve to addrs without lookups until Unpin is called. */
+func (d *DNSCache) Pin(host string, addrs ...netip.Addr) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.pins[strings.ToLower(host)] = addrs
+}
+
+/* Unpin returns host to normal lookups. */
+func (d *DNSCache) Unpin(host string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.pins, strings.ToLower(host))
+}
+
+/* Flush drops every cached answer, keeping the pins. */
+func (d *DNSCache) Flush() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.entries = make(map[string]*dnsEntry)
+}
+
+/* LookupNetIP returns the addresses of host: pinned ones, a fresh cached answer, or a new lookup shared by concurrent callers. */
+func (d *DNSCache) LookupNetIP(ctx context.Context, host string) ([]netip.Addr, error) {
+	return d.lookup(ctx, host, nil)
+}
+
+/* lookup implements LookupNetIP, resolving with fallback when the cache has no resolver of its own. */
+func (d *DNSCache) lookup(ctx context.Context, host string, fallback *net.Resolver) ([]netip.Addr, error) {
+	host = strings.ToLower(host)
+	for {
+		d.mu.Lock()
+		if addrs, ok := d.pins[host]; ok {
+			d.mu.Unlock()
+			return addrs, nil
+		}
+		e := d.entries[host]
+		if e == nil {
+			e = &dnsEntry{}
+			d.entries[host] = e
+		}
+		if e.addrs != nil && time.Now().Before(e.expires) {
+			addrs := e.addrs
+			d.mu.Unlock()
+			return addrs, nil
+		}
+		if wait := e.lookup; wait != nil {
+			d.mu.Unlock()
+			select {
+			case <-wait:
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+			d.mu.Lock()
+			addrs, err := e.addrs, e.err
+			d.mu.Unlock()
+			if addrs != nil {
+				return addrs, nil
+			}
+
+			// A lookup abandoned by its own caller says nothing about the host
+			if err != nil && !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) {
+				return nil, err
+			}
+			continue
+		}
+		e.lookup = make(chan struct{})
+		d.mu.Unlock()
+		return d.refresh(ctx, host, e, fallback)
+	}
+}
+
+/* refresh looks host up for e, keeping the previous answer for a short retry window when the lookup fails. */
+func (d *DNSCache) refresh(ctx context.Context, host string, e *dnsEntry, fallback *net.Resolver) ([]netip.Addr, error) {
+	resolver := d.resolver
+	if resolver == nil {
+		resolver = fallback
+	}
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	addrs, err := resolver.LookupNetIP(ctx, "ip", host)
+	for i := range addrs {
+		addrs[i] = addrs[i].Unmap()
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	close(e.lookup)
+	e.lookup, e.err = nil, err
+	if err == nil && len(addrs) > 0 {
+		e.addrs, e.expires = addrs, time.Now().Add(d.ttl)
+		return addrs, nil
+	}
+	if e.addrs != nil {
+		// Flaky DNS should not take down a host that resolved before, nor stall every dial on retries
+		e.expires = time.Now().Add(min(d.ttl, dnsStaleRetry))
+		return e.addrs, nil
+	}
+	if err == nil {
+		err = fmt.Errorf("no addresses for %s", host)
+		e.err = err
+	}
+	return nil, err
+}
+
+/* DialContext dials addr with dialer (a zero Dialer when nil), resolving its host through the cache, or the dialer's Resolver when the cache has none, and racing the answer's IPv4 and IPv6 addresses as dialer.FallbackDelay directs. */
+func (d *DNSCache) DialContext(ctx context.Context, dialer *net.Dialer, network, addr string) (net.Conn, error) {
+	if dialer == nil {
+		dialer = &net.Dialer{}
+	}
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := netip.ParseAddr(host); err == nil {
+		return dialer.DialContext(ctx, network, addr)
+	}
+	addrs, err := d.lookup(ctx, host, dialer.Resolver)
+	if err != nil {
+		return nil, err
+	}
+	var targets []string
+	for _, ip := range addrs {
+		if (strings.HasSuffix(network, "4") && !ip.Is4()) || (strings.HasSuffix(network, "6") && !ip.Is6()) {
+			continue
+		}
+		targets = append(targets, net.JoinHostPort(ip.String(), port))
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("no %s addresses for %s", network, host)
+	}
+	return dialTargets(ctx, dialer, network, targets, dialer.FallbackDelay, nil)
+}
@@ -0,0 +1,101 @@
+package stalkerlib
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+)
+
+/* NewDoHResolver returns a net.Resolver that sends DNS queries to a DNS-over-HTTPS endpoint (e.g. https://cloudflare-dns.com/dns-query). */
+func NewDoHResolver(endpoint string) *net.Resolver {
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			return &dohConn{ctx: ctx, endpoint: endpoint}, nil
+		},
+	}
+}
+
+/* dohConn adapts the Go resolver's length-prefixed DNS exchange to RFC 8484 POST requests. */
+type dohConn struct {
+	ctx      context.Context
+	endpoint string
+	deadline time.Time
+	wbuf     bytes.Buffer
+	rbuf     bytes.Buffer
+}
+
+func (d *dohConn) Write(b []byte) (int, error) {
+	d.wbuf.Write(b)
+	for d.wbuf.Len() >= 2 {
+		n := int(binary.BigEndian.Uint16(d.wbuf.Bytes()[:2]))
+		if d.wbuf.Len() < 2+n {
+			break
+		}
+		query := make([]byte, n)
+		d.wbuf.Next(2)
+		d.wbuf.Read(query)
+		answer, err := d.exchange(query)
+		if err != nil {
+			return 0, err
+		}
+		binary.Write(&d.rbuf, binary.BigEndian, uint16(len(answer)))
+		d.rbuf.Write(answer)
+	}
+	return len(b), nil
+}
+
+func (d *dohConn) Read(b []byte) (int, error) {
+	if d.rbuf.Len() == 0 {
+		return 0, io.EOF
+	}
+	return d.rbuf.Read(b)
+}
+
+/* exchange posts a single DNS wire-format query to the DoH endpoint and returns the wire-format answer. */
+func (d *dohConn) exchange(query []byte) ([]byte, error) {
+	ctx := d.ctx
+	if !d.deadline.IsZero() {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, d.deadline)
+		defer cancel()
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", d.endpoint, bytes.NewReader(query))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create DoH request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/dns-message")
+	req.Header.Set("Accept", "application/dns-message")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("DoH request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("DoH request failed with status %d", resp.StatusCode)
+	}
+	answer, err := io.ReadAll(io.LimitReader(resp.Body, 65535))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read DoH response: %w", err)
+	}
+	return answer, nil
+}
+
+func (d *dohConn) Close() error                       { return nil }
+func (d *dohConn) LocalAddr() net.Addr                { return dohAddr(d.endpoint) }
+func (d *dohConn) RemoteAddr() net.Addr               { return dohAddr(d.endpoint) }
+func (d *dohConn) SetDeadline(t time.Time) error      { d.deadline = t; return nil }
+func (d *dohConn) SetReadDeadline(t time.Time) error  { return nil }
+func (d *dohConn) SetWriteDeadline(t time.Time) error { d.deadline = t; return nil }
+
+/* dohAddr is the net.Addr reported by dohConn. */
+type dohAddr string
+
+func (a dohAddr) Network() string { return "https" }
+func (a dohAddr) String() string  { return string(a) }
@@ -0,0 +1,130 @@
+package stalkerlib
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+/* errResumeMismatch reports that the server cannot continue a partial download where it stopped. */
+var errResumeMismatch = errors.New("server did not resume at the requested offset")
+
+/* DownloadFile downloads fileURL to filename, resuming a previous partial download via HTTP Range requests when the server supports it and starting over when its answer does not continue the partial file. */
+func (c *StalkerClient) DownloadFile(fileURL, filename string) error {
+	// Partial data is kept next to the target until the download completes
+	partial := filename + ".part"
+	var offset int64
+	if info, err := os.Stat(partial); err == nil {
+		offset = info.Size()
+	}
+
+	ctx, cancel := c.requestContext(context.Background(), "download")
+	defer cancel()
+	err := c.downloadFrom(ctx, fileURL, partial, offset)
+	if errors.Is(err, errResumeMismatch) && offset > 0 {
+		err = c.downloadFrom(ctx, fileURL, partial, 0)
+	}
+	if errors.Is(err, errResumeMismatch) {
+		return fmt.Errorf("failed to download %s: %w", fileURL, err)
+	}
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(partial, filename); err != nil {
+		return fmt.Errorf("failed to finalize %s: %w", filename, err)
+	}
+	return nil
+}
+
+/* downloadFrom fetches fileURL into partial from offset on, returning errResumeMismatch when the server's answer does not continue the partial file. */
+func (c *StalkerClient) downloadFrom(ctx context.Context, fileURL, partial string, offset int64) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", fileURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create download request for %s: %w", fileURL, err)
+	}
+	req.Header.Set("User-Agent", STBUserAgent)
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return fmt.Errorf("failed to download %s: %w", fileURL, err)
+	}
+	defer resp.Body.Close()
+
+	// Choose between appending and starting over based on the server's answer
+	flags := os.O_CREATE | os.O_WRONLY
+	switch {
+	case resp.StatusCode == http.StatusPartialContent:
+		if start, _, ok := parseContentRange(resp.Header.Get("Content-Range")); !ok || start != offset {
+			return errResumeMismatch
+		}
+		if offset > 0 {
+			flags |= os.O_APPEND
+		} else {
+			flags |= os.O_TRUNC
+		}
+	case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable && offset > 0:
+		// Only a partial file as long as the whole content is complete
+		if _, total, ok := parseContentRange(resp.Header.Get("Content-Range")); ok && total == offset {
+			return nil
+		}
+		return errResumeMismatch
+	case resp.StatusCode == http.StatusOK:
+		flags |= os.O_TRUNC
+	default:
+		return fmt.Errorf("failed to download %s: unexpected status %d", fileURL, resp.StatusCode)
+	}
+
+	out, err := os.OpenFile(partial, flags, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to create file %s: %w", partial, err)
+	}
+	if _, err := io.Copy(out, resp.Body); err != nil {
+		out.Close()
+		if resp.StatusCode == http.StatusOK && !strings.EqualFold(resp.Header.Get("Accept-Ranges"), "bytes") {
+			// A server without range support could never continue this file
+			os.Remove(partial)
+		}
+		return fmt.Errorf("failed to save %s: %w", partial, err)
+	}
+	if err := out.Close(); err != nil {
+		return fmt.Errorf("failed to save %s: %w", partial, err)
+	}
+	return nil
+}
+
+/* parseContentRange parses a Content-Range header such as "bytes 100-199/1000", returning -1 for a start or total given as an asterisk. */
+func parseContentRange(header string) (start, total int64, ok bool) {
+	spec, found := strings.CutPrefix(header, "bytes ")
+	if !found {
+		return 0, 0, false
+	}
+	span, size, found := strings.Cut(spec, "/")
+	if !found {
+		return 0, 0, false
+	}
+	start, total = -1, -1
+	if span != "*" {
+		first, _, found := strings.Cut(span, "-")
+		n, err := strconv.ParseInt(first, 10, 64)
+		if !found || err != nil {
+			return 0, 0, false
+		}
+		start = n
+	}
+	if size != "*" {
+		n, err := strconv.ParseInt(size, 10, 64)
+		if err != nil {
+			return 0, 0, false
+		}
+		total = n
+	}
+	return start, total, true
+}
@@ -0,0 +1,109 @@
+package stalkerlib
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDownloadFileResume(t *testing.T) {
+	content := []byte("0123456789abcdefghijklmnopqrstuvwxyz")
+	serveContent := func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "file.bin", time.Time{}, bytes.NewReader(content))
+	}
+	tests := []struct {
+		name     string
+		partial  string // Content of the .part file left by an earlier attempt, "" for none
+		ranged   http.HandlerFunc
+		requests int
+	}{
+		{"fresh download", "", serveContent, 1},
+		{"resumed", "0123456789", serveContent, 1},
+		{"already complete", string(content), serveContent, 1},
+		{"range ignored", "0123456789", func(w http.ResponseWriter, r *http.Request) {
+			w.Write(content)
+		}, 1},
+		{"resumed at another offset", "0123456789", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Range", "bytes 5-35/36")
+			w.WriteHeader(http.StatusPartialContent)
+			w.Write(content[5:])
+		}, 2},
+		{"partial content without range", "0123456789", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusPartialContent)
+			w.Write(content[10:])
+		}, 2},
+		{"unsatisfiable without range", "0123456789", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
+		}, 2},
+		{"partial file of another length", "0123456789", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Range", "bytes */8")
+			w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
+		}, 2},
+		{"stale partial file longer than the content", string(content) + "junk", serveContent, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := 0
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				if r.Header.Get("Range") == "" {
+					serveContent(w, r)
+					return
+				}
+				tt.ranged(w, r)
+			}))
+			defer srv.Close()
+
+			filename := filepath.Join(t.TempDir(), "file.bin")
+			if tt.partial != "" {
+				if err := os.WriteFile(filename+".part", []byte(tt.partial), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			c := NewStalkerClient(srv.URL, "00:1A:79:00:00:01", "UTC")
+			if err := c.DownloadFile(srv.URL+"/file.bin", filename); err != nil {
+				t.Fatalf("DownloadFile = %v", err)
+			}
+			got, err := os.ReadFile(filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, content) {
+				t.Errorf("downloaded %q, want %q", got, content)
+			}
+			if requests != tt.requests {
+				t.Errorf("requests = %d, want %d", requests, tt.requests)
+			}
+			if _, err := os.Stat(filename + ".part"); !os.IsNotExist(err) {
+				t.Errorf("partial file left behind: %v", err)
+			}
+		})
+	}
+}
+
+func TestParseContentRange(t *testing.T) {
+	tests := []struct {
+		header       string
+		start, total int64
+		ok           bool
+	}{
+		{"bytes 100-199/1000", 100, 1000, true},
+		{"bytes 100-199/*", 100, -1, true},
+		{"bytes */1000", -1, 1000, true},
+		{"bytes 100/1000", 0, 0, false},
+		{"items 1-2/3", 0, 0, false},
+		{"", 0, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			start, total, ok := parseContentRange(tt.header)
+			if start != tt.start || total != tt.total || ok != tt.ok {
+				t.Errorf("parseContentRange(%q) = %d, %d, %v, want %d, %d, %v", tt.header, start, total, ok, tt.start, tt.total, tt.ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package stalkerlib
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+/* defaultAPIPath is the load.php location used until another variant is detected. */
+const defaultAPIPath = "/stalker_portal/server/load.php"
+
+/* apiPathVariants are the known API endpoint locations, in order of preference. */
+var apiPathVariants = []string{
+	defaultAPIPath,
+	"/portal.php",
+	"/server/load.php",
+	"/stalker_portal/portal.php",
+	"/c/server/load.php",
+}
+
+/* WithProbeAttemptTimeout bounds each endpoint-variant attempt made by DiscoverEndpoint (5s by default). */
+func WithProbeAttemptTimeout(d time.Duration) Option {
+	return func(c *StalkerClient) {
+		c.attemptTimeout = d
+	}
+}
+
+/* apiURL returns the portal API endpoint, honoring a detected path variant. */
+func (c *StalkerClient) apiURL() string {
+	path := c.config().APIPath
+	if path == "" {
+		path = defaultAPIPath
+	}
+	return c.PortalURL + path
+}
+
+/* DiscoverEndpoint tries the known API path variants one at a time, so no handshake invalidates the token of another, and stores the first that answers a handshake in Config.APIPath, keeping its token; it then finds a token transport the portal accepts, unless one is pinned with a ProbeOverride. */
+func (c *StalkerClient) DiscoverEndpoint() (string, error) {
+	return c.discoverEndpoint(context.Background())
+}
+
+/* discoverEndpoint implements DiscoverEndpoint under the given context. */
+func (c *StalkerClient) discoverEndpoint(ctx context.Context) (string, error) {
+	var errs []error
+	for _, path := range apiPathVariants {
+		err := c.tryEndpoint(ctx, path)
+		if err == nil {
+			c.updateConfig(func(cfg *ServerConfig) { cfg.APIPath = path })
+			if c.override == nil || c.override.TokenTransport == nil {
+				c.discoverTokenTransport(ctx)
+			}
+			return path, nil
+		}
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", path, err))
+	}
+	return "", errors.Join(errs...)
+}
+
+/* tryEndpoint handshakes through path under the per-attempt time limit, with the template, signing, and protocol parameters of every action, installing the token when it answers. */
+func (c *StalkerClient) tryEndpoint(ctx context.Context, path string) error {
+	ctx, cancel := context.WithTimeout(ctx, orDefault(c.attemptTimeout, 5*time.Second))
+	defer cancel()
+	c.auth.mu.Lock()
+	defer c.auth.mu.Unlock()
+	return c.handshakeLocked(context.WithValue(ctx, apiPathKey{}, path))
+}
+
+/* tokenTransports are the token transports discovery tries, in order of preference. */
+var tokenTransports = []TokenTransport{TokenInHeader, TokenInCookie, TokenInQuery}
+
+/* discoverTokenTransport stores in Config.TokenTransport the first transport with which the portal accepts the token for an authenticated call, keeping the current one when none is accepted. */
+func (c *StalkerClient) discoverTokenTransport(ctx context.Context) {
+	for _, transport := range tokenTransports {
+		attemptCtx, cancel := context.WithTimeout(ctx, orDefault(c.attemptTimeout, 5*time.Second))
+		err := c.callAction(context.WithValue(attemptCtx, tokenTransportKey{}, transport), "stb", "get_profile", nil, nil)
+		cancel()
+		if err == nil {
+			c.updateConfig(func(cfg *ServerConfig) { cfg.TokenTransport = transport })
+			return
+		}
+	}
+}
+
+/* apiPathKey carries the API path a discovery attempt sends its request to, in place of Config.APIPath. */
+type apiPathKey struct{}
+
+/* tokenTransportKey carries the token transport a discovery attempt uses, in place of Config.TokenTransport. */
+type tokenTransportKey struct{}
+
+/* actionURL returns the API endpoint a request under ctx goes to: the one of a discovery attempt, or apiURL. */
+func (c *StalkerClient) actionURL(ctx context.Context) string {
+	if path, ok := ctx.Value(apiPathKey{}).(string); ok {
+		return c.PortalURL + path
+	}
+	return c.apiURL()
+}
@@ -0,0 +1,117 @@
+package stalkerlib
+
+import (
+	"context"
+	"strings"
+	"sync"
+)
+
+/* Metadata is catalog data for a title from an external source such as TMDB or IMDb. */
+type Metadata struct {
+	Title         string   `json:"title,omitempty"`          // Canonical title
+	OriginalTitle string   `json:"original_title,omitempty"` // Title in the original language
+	Plot          string   `json:"plot,omitempty"`           // Plot summary
+	Year          string   `json:"year,omitempty"`           // Release year
+	Rating        float64  `json:"rating,omitempty"`         // Rating on a 0-10 scale
+	Poster        string   `json:"poster,omitempty"`         // Poster image URL
+	Fanart        string   `json:"fanart,omitempty"`         // Backdrop image URL
+	Genres        []string `json:"genres,omitempty"`         // Genre names
+	IMDbID        string   `json:"imdb_id,omitempty"`        // IMDb ID, e.g. "tt0111161"
+	TMDbID        string   `json:"tmdb_id,omitempty"`        // TMDB ID
+}
+
+/* MetadataQuery describes the title an enricher should look up. */
+type MetadataQuery struct {
+	Title         string // Portal title
+	OriginalTitle string // Portal original-language title, when known
+	Year          string // Release year, when known
+	Series        bool   // Whether the title is a series rather than a film
+}
+
+/* MetadataEnricher looks up external metadata for VOD items and, when enabled, EPG movies, reporting false when it knows no match. */
+type MetadataEnricher interface {
+	Enrich(ctx context.Context, q MetadataQuery) (Metadata, bool, error)
+}
+
+/* MetadataEnricherFunc adapts a function to the MetadataEnricher interface. */
+type MetadataEnricherFunc func(ctx context.Context, q MetadataQuery) (Metadata, bool, error)
+
+/* Enrich calls f(ctx, q). */
+func (f MetadataEnricherFunc) Enrich(ctx context.Context, q MetadataQuery) (Metadata, bool, error) {
+	return f(ctx, q)
+}
+
+/* WithMetadataEnricher sets the enricher attached to VOD listings; lookups are cached per title, and failed ones leave the item without metadata. */
+func WithMetadataEnricher(e MetadataEnricher) Option {
+	return func(c *StalkerClient) {
+		c.enricher.source = e
+	}
+}
+
+/* WithEPGEnrichment also enriches fetched EPG programs whose category contains one of the given keywords (case-insensitive), e.g. "movie" or "film". */
+func WithEPGEnrichment(categories ...string) Option {
+	return func(c *StalkerClient) {
+		for _, cat := range categories {
+			c.enricher.epgCategories = append(c.enricher.epgCategories, strings.ToLower(cat))
+		}
+	}
+}
+
+/* metadataEnrichment holds the configured enricher and its lookup cache. */
+type metadataEnrichment struct {
+	source        MetadataEnricher
+	epgCategories []string
+
+	mu    sync.Mutex
+	cache map[MetadataQuery]*Metadata // nil entries record titles without a match
+}
+
+/* lookup returns the metadata of q, consulting the cache first; errors are not cached so the title is retried later. */
+func (m *metadataEnrichment) lookup(ctx context.Context, q MetadataQuery) *Metadata {
+	m.mu.Lock()
+	md, cached := m.cache[q]
+	m.mu.Unlock()
+	if cached {
+		return md
+	}
+	found, ok, err := m.source.Enrich(ctx, q)
+	if err != nil {
+		return nil
+	}
+	if ok {
+		md = &found
+	}
+	m.mu.Lock()
+	if m.cache == nil {
+		m.cache = make(map[MetadataQuery]*Metadata)
+	}
+	m.cache[q] = md
+	m.mu.Unlock()
+	return md
+}
+
+/* enrichVOD attaches enricher metadata to items. */
+func (c *StalkerClient) enrichVOD(ctx context.Context, items []VODItem) {
+	if c.enricher.source == nil {
+		return
+	}
+	for i, v := range items {
+		items[i].Metadata = c.enricher.lookup(ctx, MetadataQuery{Title: v.Name, OriginalTitle: v.OriginalName, Year: v.Year, Series: v.Series})
+	}
+}
+
+/* enrichPrograms attaches enricher metadata to programs in the configured EPG categories. */
+func (c *StalkerClient) enrichPrograms(ctx context.Context, programs []EPGProgram) {
+	if c.enricher.source == nil || len(c.enricher.epgCategories) == 0 {
+		return
+	}
+	for i, p := range programs {
+		category := strings.ToLower(p.Category)
+		for _, keyword := range c.enricher.epgCategories {
+			if strings.Contains(category, keyword) {
+				programs[i].Metadata = c.enricher.lookup(ctx, MetadataQuery{Title: p.Name})
+				break
+			}
+		}
+	}
+}
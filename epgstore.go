@@ -0,0 +1,124 @@
+package stalkerlib
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+/* EPGStore persists fetched programs and answers time-range queries without loading whole days, e.g. backed by a database. */
+type EPGStore interface {
+	PutPrograms(channelID string, programs []EPGProgram) error                  // Replaces stored programs within the span of programs
+	ProgramsBetween(channelID string, from, to time.Time) ([]EPGProgram, error) // Programs overlapping [from, to), ordered by start
+}
+
+/* WithEPGStore saves every fetched EPG in store and answers ProgramsBetween and ProgramAt from it; failed writes are reported as EventEPGStoreFailed. */
+func WithEPGStore(store EPGStore) Option {
+	return func(c *StalkerClient) {
+		c.epgStore = store
+	}
+}
+
+/* ProgramsBetween returns a channel's programs overlapping [from, to), ordered by start, fetching the EPG when nothing is stored or cached. */
+func (c *StalkerClient) ProgramsBetween(channelID string, from, to time.Time) ([]EPGProgram, error) {
+	return c.programsBetween(context.Background(), channelID, from, to)
+}
+
+/* ProgramAt returns the program airing on a channel at t, reporting false when there is none. */
+func (c *StalkerClient) ProgramAt(channelID string, t time.Time) (EPGProgram, bool, error) {
+	programs, err := c.programsBetween(context.Background(), channelID, t, t.Add(time.Second))
+	if err != nil {
+		return EPGProgram{}, false, err
+	}
+	ts := t.Unix()
+	for _, p := range programs {
+		if p.Start <= ts && ts < p.Stop {
+			return p, true, nil
+		}
+	}
+	return EPGProgram{}, false, nil
+}
+
+/* programsBetween implements ProgramsBetween under the given context. */
+func (c *StalkerClient) programsBetween(ctx context.Context, channelID string, from, to time.Time) ([]EPGProgram, error) {
+	query := func() ([]EPGProgram, bool, error) {
+		if c.epgStore != nil {
+			programs, err := c.epgStore.ProgramsBetween(channelID, from, to)
+			return programs, len(programs) > 0, err
+		}
+		programs, ok := c.epg.load(channelID)
+		return overlapping(sortedPrograms(programs), from.Unix(), to.Unix()), ok, nil
+	}
+	programs, found, err := query()
+	if err != nil || found {
+		return programs, err
+	}
+	if _, err := c.getEPG(ctx, channelID); err != nil {
+		return nil, err
+	}
+	programs, _, err = query()
+	return programs, err
+}
+
+/* sortedPrograms returns a copy of programs ordered by start time. */
+func sortedPrograms(programs []EPGProgram) []EPGProgram {
+	sorted := append([]EPGProgram(nil), programs...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Start < sorted[j].Start })
+	return sorted
+}
+
+/* overlapping returns the programs of a start-ordered slice that overlap [from, to). */
+func overlapping(sorted []EPGProgram, from, to int64) []EPGProgram {
+	end := sort.Search(len(sorted), func(i int) bool { return sorted[i].Start >= to })
+	var result []EPGProgram
+	for _, p := range sorted[:end] {
+		if p.Stop > from {
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
+/* MemoryEPGStore is an in-memory EPGStore keeping each channel's programs ordered by start. */
+type MemoryEPGStore struct {
+	mu       sync.RWMutex
+	programs map[string][]EPGProgram
+}
+
+/* NewMemoryEPGStore creates an empty in-memory EPG store. */
+func NewMemoryEPGStore() *MemoryEPGStore {
+	return &MemoryEPGStore{programs: make(map[string][]EPGProgram)}
+}
+
+/* PutPrograms merges programs into the channel's schedule, dropping stored programs that overlap their span. */
+func (s *MemoryEPGStore) PutPrograms(channelID string, programs []EPGProgram) error {
+	if len(programs) == 0 {
+		return nil
+	}
+	incoming := sortedPrograms(programs)
+	from, to := incoming[0].Start, incoming[0].Stop
+	for _, p := range incoming {
+		if p.Stop > to {
+			to = p.Stop
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var kept []EPGProgram
+	for _, p := range s.programs[channelID] {
+		if p.Stop <= from || p.Start >= to {
+			kept = append(kept, p)
+		}
+	}
+	s.programs[channelID] = sortedPrograms(append(kept, incoming...))
+	return nil
+}
+
+/* ProgramsBetween returns the stored programs overlapping [from, to). */
+func (s *MemoryEPGStore) ProgramsBetween(channelID string, from, to time.Time) ([]EPGProgram, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return overlapping(s.programs[channelID], from.Unix(), to.Unix()), nil
+}
@@ -0,0 +1,107 @@
+package stalkerlib
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+/* EventType identifies a client lifecycle event. */
+type EventType int
+
+const (
+	EventAuthenticated     EventType = iota // A handshake obtained the first token
+	EventTokenRefreshed                     // A handshake replaced an existing token
+	EventChannelsUpdated                    // A channel list was fetched from the portal
+	EventEPGUpdated                         // A channel's EPG was fetched from the portal
+	EventPortalUnreachable                  // A request to the portal failed at the network level
+	EventChannelDead                        // An AvailabilityMonitor marked a channel dead
+	EventChannelRecovered                   // A channel marked dead passed a stream check again
+	EventEPGStoreFailed                     // Fetched programs could not be written to the EPGStore
+)
+
+/* String returns the event type name. */
+func (t EventType) String() string {
+	switch t {
+	case EventAuthenticated:
+		return "Authenticated"
+	case EventTokenRefreshed:
+		return "TokenRefreshed"
+	case EventChannelsUpdated:
+		return "ChannelsUpdated"
+	case EventEPGUpdated:
+		return "EPGUpdated"
+	case EventPortalUnreachable:
+		return "PortalUnreachable"
+	case EventChannelDead:
+		return "ChannelDead"
+	case EventChannelRecovered:
+		return "ChannelRecovered"
+	case EventEPGStoreFailed:
+		return "EPGStoreFailed"
+	}
+	return "Unknown"
+}
+
+/* Event describes something that happened inside the client. */
+type Event struct {
+	Type      EventType // What happened
+	Time      time.Time // When it happened
+	ChannelID string    // Channel concerned, for EventEPGUpdated, EventEPGStoreFailed, EventChannelDead and EventChannelRecovered
+	Err       error     // Underlying failure, for EventPortalUnreachable, EventEPGStoreFailed and EventChannelDead
+	RequestID string    // Correlation ID of the failed call, for EventPortalUnreachable
+}
+
+/* eventBus fans events out to subscribers. */
+type eventBus struct {
+	mu     sync.Mutex
+	nextID int
+	subs   map[int]func(Event)
+}
+
+/* Subscribe registers handler for all client events and returns a function that removes it; handlers run synchronously and should return quickly. */
+func (c *StalkerClient) Subscribe(handler func(Event)) (unsubscribe func()) {
+	c.events.mu.Lock()
+	defer c.events.mu.Unlock()
+	if c.events.subs == nil {
+		c.events.subs = make(map[int]func(Event))
+	}
+	c.events.nextID++
+	id := c.events.nextID
+	c.events.subs[id] = handler
+	return func() {
+		c.events.mu.Lock()
+		defer c.events.mu.Unlock()
+		delete(c.events.subs, id)
+	}
+}
+
+/* emit delivers an event to every subscriber. */
+func (c *StalkerClient) emit(e Event) {
+	e.Time = time.Now()
+	c.events.mu.Lock()
+	handlers := make([]func(Event), 0, len(c.events.subs))
+	for _, h := range c.events.subs {
+		handlers = append(handlers, h)
+	}
+	c.events.mu.Unlock()
+	for _, h := range handlers {
+		h(e)
+	}
+}
+
+/* reportTransportError emits EventPortalUnreachable for network failures of requests addressed to the portal. */
+func (c *StalkerClient) reportTransportError(req *http.Request, err error) {
+	if errors.Is(err, context.Canceled) || errors.Is(err, ErrClientClosed) {
+		return
+	}
+	portal, perr := url.Parse(c.PortalURL)
+	if perr != nil || portal.Host != req.URL.Host {
+		return
+	}
+	requestID, _ := RequestIDFromContext(req.Context())
+	c.emit(Event{Type: EventPortalUnreachable, Err: err, RequestID: requestID})
+}
@@ -0,0 +1,65 @@
+package stalkerlib
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+/* channelFields holds the JSON names of the modeled Channel fields, which are kept out of Extras. */
+var channelFields = func() map[string]bool {
+	fields := make(map[string]bool)
+	t := reflect.TypeOf(Channel{})
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			fields[name] = true
+		}
+	}
+	return fields
+}()
+
+/* channelExtras returns the fields of a channel object that Channel does not model, nil when there are none. */
+func channelExtras(data []byte) (map[string]json.RawMessage, error) {
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(data, &all); err != nil {
+		return nil, err
+	}
+	for name := range all {
+		if channelFields[name] {
+			delete(all, name)
+		}
+	}
+	if len(all) == 0 {
+		return nil, nil
+	}
+	return all, nil
+}
+
+/* MarshalJSON encodes a channel with its Extras alongside the modeled fields, so snapshots and API responses keep them. */
+func (ch Channel) MarshalJSON() ([]byte, error) {
+	type plain Channel
+	data, err := json.Marshal(plain(ch))
+	if err != nil || len(ch.Extras) == 0 {
+		return data, err
+	}
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(data, &all); err != nil {
+		return nil, err
+	}
+	for name, value := range ch.Extras {
+		if _, ok := all[name]; !ok {
+			all[name] = value
+		}
+	}
+	return json.Marshal(all)
+}
+
+/* Extra decodes the unmodeled portal field key into v, reporting false when the channel has no such field. */
+func (ch Channel) Extra(key string, v interface{}) (bool, error) {
+	raw, ok := ch.Extras[key]
+	if !ok {
+		return false, nil
+	}
+	return true, json.Unmarshal(raw, v)
+}
@@ -0,0 +1,28 @@
+package stalkerlib
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+)
+
+/* flexString decodes a JSON string, number, or boolean into its string form, since portals disagree on field types. */
+type flexString string
+
+func (f *flexString) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		*f = ""
+		return nil
+	}
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		*f = flexString(s)
+		return nil
+	}
+	*f = flexString(strings.Trim(string(data), `"`))
+	return nil
+}
@@ -0,0 +1,153 @@
+package stalkerlib
+
+import (
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+/* DVB/ETSI EN 300 468 content genres as used in XMLTV category elements by DVR software. */
+const (
+	GenreMovie         = "Movie / Drama"
+	GenreNews          = "News / Current affairs"
+	GenreShow          = "Show / Game show"
+	GenreSports        = "Sports"
+	GenreChildren      = "Children's / Youth programs"
+	GenreMusic         = "Music / Ballet / Dance"
+	GenreArts          = "Arts / Culture (without music)"
+	GenreSocial        = "Social / Political issues / Economics"
+	GenreEducation     = "Education / Science / Factual topics"
+	GenreLeisure       = "Leisure hobbies"
+	GenreDocumentary   = "Documentary"
+	GenreComedy        = "Comedy"
+	GenreSoap          = "Soap / Melodrama / Folklore"
+	GenreThriller      = "Detective / Thriller"
+	GenreAdventure     = "Adventure / Western / War"
+	GenreSciFi         = "Science fiction / Fantasy / Horror"
+	GenreRomance       = "Romance"
+	GenreCartoons      = "Cartoons / Puppets"
+	GenreTravel        = "Tourism / Travel"
+	GenreCooking       = "Cooking"
+	GenreNature        = "Nature / Animals / Environment"
+	GenreReligion      = "Religion"
+	GenreWeather       = "News / Weather report"
+	GenreFootball      = "Football / Soccer"
+	GenreTalkShow      = "Talk show"
+	GenreAdultMovie    = "Adult movie / Drama"
+	GenreEntertainment = "Variety show"
+)
+
+/* genreMapping maps a lowercase keyword found in a portal category to a standard genre; a keyword ending in "*" matches every word it begins, any other only the whole word or its plural in "s". */
+type genreMapping struct {
+	keyword string
+	genre   string
+}
+
+/* defaultGenreMappings covers common English and Russian portal categories; more specific keywords come first, and Russian ones are stems, as the words inflect. */
+var defaultGenreMappings = []genreMapping{
+	{"football", GenreFootball}, {"soccer", GenreFootball}, {"футбол*", GenreFootball},
+	{"sport*", GenreSports}, {"спорт*", GenreSports},
+	{"weather", GenreWeather}, {"погод*", GenreWeather},
+	{"news", GenreNews}, {"новост*", GenreNews}, {"информац*", GenreNews},
+	{"documentar*", GenreDocumentary}, {"документ*", GenreDocumentary}, {"познават*", GenreEducation},
+	{"cartoon", GenreCartoons}, {"animation", GenreCartoons}, {"animated", GenreCartoons}, {"мульт*", GenreCartoons},
+	{"kids", GenreChildren}, {"kid", GenreChildren}, {"children", GenreChildren}, {"детск*", GenreChildren}, {"дети", GenreChildren},
+	{"music*", GenreMusic}, {"музык*", GenreMusic},
+	{"comedy", GenreComedy}, {"comedies", GenreComedy}, {"комеди*", GenreComedy}, {"юмор*", GenreComedy},
+	{"soap", GenreSoap}, {"сериал*", GenreSoap},
+	{"thriller", GenreThriller}, {"detective", GenreThriller}, {"crime", GenreThriller}, {"детектив*", GenreThriller}, {"триллер*", GenreThriller},
+	{"western", GenreAdventure}, {"adventure", GenreAdventure}, {"war", GenreAdventure}, {"приключ*", GenreAdventure}, {"боевик*", GenreAdventure},
+	{"sci-fi", GenreSciFi}, {"fantasy", GenreSciFi}, {"horror", GenreSciFi}, {"фантаст*", GenreSciFi}, {"ужас*", GenreSciFi},
+	{"romance", GenreRomance}, {"romantic", GenreRomance}, {"мелодрам*", GenreRomance},
+	{"adult", GenreAdultMovie}, {"xxx", GenreAdultMovie}, {"эрот*", GenreAdultMovie},
+	{"talk", GenreTalkShow}, {"ток-шоу", GenreTalkShow},
+	{"travel", GenreTravel}, {"путешеств*", GenreTravel},
+	{"cooking", GenreCooking}, {"food", GenreCooking}, {"кулинар*", GenreCooking},
+	{"nature", GenreNature}, {"animal", GenreNature}, {"wildlife", GenreNature}, {"природ*", GenreNature}, {"животн*", GenreNature},
+	{"religio*", GenreReligion}, {"религ*", GenreReligion},
+	{"science", GenreEducation}, {"education*", GenreEducation}, {"наук*", GenreEducation}, {"образоват*", GenreEducation},
+	{"art", GenreArts}, {"culture", GenreArts}, {"cultural", GenreArts}, {"культур*", GenreArts},
+	{"politic*", GenreSocial}, {"business", GenreSocial}, {"econom*", GenreSocial}, {"политик*", GenreSocial}, {"бизнес*", GenreSocial},
+	{"hobby", GenreLeisure}, {"hobbies", GenreLeisure}, {"lifestyle", GenreLeisure}, {"хобби", GenreLeisure}, {"досуг*", GenreLeisure},
+	{"entertainment", GenreEntertainment}, {"развлека*", GenreEntertainment},
+	{"show", GenreShow}, {"шоу", GenreShow},
+	{"movie", GenreMovie}, {"film", GenreMovie}, {"cinema", GenreMovie}, {"drama", GenreMovie}, {"кино*", GenreMovie}, {"фильм*", GenreMovie}, {"драм*", GenreMovie},
+}
+
+/* GenreMap normalizes free-form portal categories to standard DVB genres, with user mappings taking precedence over the defaults. */
+type GenreMap struct {
+	mu     sync.RWMutex
+	custom []genreMapping
+}
+
+/* NewGenreMap creates a GenreMap backed by the built-in mapping table. */
+func NewGenreMap() *GenreMap {
+	return &GenreMap{}
+}
+
+/* Add maps categories containing keyword (case-insensitive) as a whole word, or its plural in "s", to genre; a keyword ending in "*", such as "document*", matches every word it begins instead, and later additions win over earlier ones. */
+func (m *GenreMap) Add(keyword, genre string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.custom = append(m.custom, genreMapping{keyword: strings.ToLower(keyword), genre: genre})
+}
+
+/* Normalize returns the standard genre for a portal category, or the category unchanged if nothing matches. */
+func (m *GenreMap) Normalize(category string) string {
+	lower := strings.ToLower(strings.TrimSpace(category))
+	if lower == "" {
+		return category
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for i := len(m.custom) - 1; i >= 0; i-- {
+		if hasKeyword(lower, m.custom[i].keyword) {
+			return m.custom[i].genre
+		}
+	}
+	for _, mapping := range defaultGenreMappings {
+		if hasKeyword(lower, mapping.keyword) {
+			return mapping.genre
+		}
+	}
+	return category
+}
+
+/* hasKeyword reports whether s contains keyword on word boundaries, so "war" matches "war movies" but neither "warner" nor "award", and "doc*" matches "documentary". */
+func hasKeyword(s, keyword string) bool {
+	keyword, stem := strings.CutSuffix(keyword, "*")
+	if keyword == "" {
+		return false
+	}
+	for i := 0; i < len(s); {
+		j := strings.Index(s[i:], keyword)
+		if j < 0 {
+			return false
+		}
+		j += i
+		prev, _ := utf8.DecodeLastRuneInString(s[:j])
+		end := j + len(keyword)
+		if !stem && strings.HasPrefix(s[end:], "s") {
+			end++
+		}
+		next, _ := utf8.DecodeRuneInString(s[end:])
+		if (j == 0 || !isWordRune(prev)) && (stem || end == len(s) || !isWordRune(next)) {
+			return true
+		}
+		i = j + 1
+	}
+	return false
+}
+
+/* isWordRune reports whether r belongs to a word rather than separating words. */
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+/* WithGenreMap normalizes program categories through m when exporting XMLTV and iCalendar, and genre group titles in M3U exports. */
+func WithGenreMap(m *GenreMap) Option {
+	return func(c *StalkerClient) {
+		c.genres = m
+	}
+}
@@ -0,0 +1,43 @@
+package stalkerlib
+
+import "testing"
+
+func TestGenreMapNormalize(t *testing.T) {
+	m := NewGenreMap()
+	m.Add("anime", GenreCartoons)
+	m.Add("doc*", GenreDocumentary)
+
+	tests := []struct {
+		category string
+		want     string
+	}{
+		{"War Movies", GenreAdventure},
+		{"WAR", GenreAdventure},
+		{"Warner TV", "Warner TV"},
+		{"Golden State Warriors", "Golden State Warriors"},
+		{"Award Show", GenreShow},
+		{"Arts", GenreArts},
+		{"Party", "Party"},
+		{"Arthouse", "Arthouse"},
+		{"Sports HD", GenreSports},
+		{"Sportsnet", GenreSports},
+		{"Talk Shows", GenreTalkShow},
+		{"Documentaries", GenreDocumentary},
+		{"Sci-Fi & Fantasy", GenreSciFi},
+		{"Kids' Channels", GenreChildren},
+		{"Детские", GenreChildren},
+		{"Спортивные каналы", GenreSports},
+		{"Фильмы", GenreMovie},
+		{"Anime", GenreCartoons},
+		{"Animeland", "Animeland"},
+		{"Docs", GenreDocumentary},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.category, func(t *testing.T) {
+			if got := m.Normalize(tt.category); got != tt.want {
+				t.Errorf("Normalize(%q) = %q, want %q", tt.category, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package stalkerlib
+
+import "time"
+
+/* DefaultGridSlot is the slot size EPG grid UIs commonly align programs to. */
+const DefaultGridSlot = 5 * time.Minute
+
+/* NoInformationTitle names the synthetic blocks FillGuideGaps inserts. */
+const NoInformationTitle = "No information"
+
+/* AlignPrograms rounds program start and stop times to the nearest multiple of slot, dropping programs that collapse to zero length; the result is ordered by start. */
+func AlignPrograms(programs []EPGProgram, slot time.Duration) []EPGProgram {
+	step := int64(slot / time.Second)
+	if step <= 0 {
+		return sortedPrograms(programs)
+	}
+	round := func(ts int64) int64 {
+		return (ts + step/2) / step * step
+	}
+	aligned := make([]EPGProgram, 0, len(programs))
+	for _, p := range sortedPrograms(programs) {
+		p.Start, p.Stop = round(p.Start), round(p.Stop)
+		if p.Stop > p.Start {
+			aligned = append(aligned, p)
+		}
+	}
+	return aligned
+}
+
+/* ClipPrograms keeps the programs overlapping window and trims their times to its bounds; open sides of window are not clipped. */
+func ClipPrograms(programs []EPGProgram, window TimeRange) []EPGProgram {
+	var clipped []EPGProgram
+	for _, p := range sortedPrograms(programs) {
+		if !window.Overlaps(time.Unix(p.Start, 0), time.Unix(p.Stop, 0)) {
+			continue
+		}
+		if !window.From.IsZero() && p.Start < window.From.Unix() {
+			p.Start = window.From.Unix()
+		}
+		if !window.To.IsZero() && p.Stop > window.To.Unix() {
+			p.Stop = window.To.Unix()
+		}
+		clipped = append(clipped, p)
+	}
+	return clipped
+}
+
+/* FillGuideGaps returns start-ordered programs with NoInformationTitle blocks covering every gap inside window, including before the first and after the last program. */
+func FillGuideGaps(programs []EPGProgram, window TimeRange, channelID string) []EPGProgram {
+	sorted := sortedPrograms(programs)
+	filler := func(start, stop int64) EPGProgram {
+		return EPGProgram{ChannelID: channelID, Name: NoInformationTitle, Start: start, Stop: stop}
+	}
+
+	var filled []EPGProgram
+	cursor := int64(0)
+	if !window.From.IsZero() {
+		cursor = window.From.Unix()
+	} else if len(sorted) > 0 {
+		cursor = sorted[0].Start
+	}
+	for _, p := range sorted {
+		if p.Start > cursor {
+			filled = append(filled, filler(cursor, p.Start))
+		}
+		filled = append(filled, p)
+		if p.Stop > cursor {
+			cursor = p.Stop
+		}
+	}
+	if !window.To.IsZero() && cursor < window.To.Unix() {
+		filled = append(filled, filler(cursor, window.To.Unix()))
+	}
+	return filled
+}
+
+/* GridRow prepares one channel's programs for a grid UI: aligned to slot, clipped to window, and gap-filled. */
+func GridRow(programs []EPGProgram, window TimeRange, slot time.Duration, channelID string) []EPGProgram {
+	return FillGuideGaps(ClipPrograms(AlignPrograms(programs, slot), window), window, channelID)
+}
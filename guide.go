@@ -0,0 +1,45 @@
+package stalkerlib
+
+import "time"
+
+/* GuideEntry holds the current and next program of a channel at a point in time. */
+type GuideEntry struct {
+	Channel Channel     // Channel the entry describes
+	Current *EPGProgram // Program airing at the requested time, nil if unknown
+	Next    *EPGProgram // First program after the current one, nil if unknown
+}
+
+/* guideLookahead bounds the EPG store query used to find the next program. */
+const guideLookahead = 24 * time.Hour
+
+/* BuildGuideGrid returns the now/next programs for every channel at the given time, using the EPG store when configured and the EPG cached by GetEPG otherwise. */
+func (c *StalkerClient) BuildGuideGrid(channels []Channel, at time.Time) []GuideEntry {
+	ts := at.Unix()
+	entries := make([]GuideEntry, 0, len(channels))
+	for _, ch := range channels {
+		entry := GuideEntry{Channel: ch}
+		programs, _ := c.epg.load(ch.ID)
+		if c.epgStore != nil {
+			programs, _ = c.epgStore.ProgramsBetween(ch.ID, at, at.Add(guideLookahead))
+		}
+
+		// Programs are not guaranteed to be ordered, so scan for both slots
+		for i := range programs {
+			if programs[i].Start <= ts && ts < programs[i].Stop {
+				entry.Current = &programs[i]
+				break
+			}
+		}
+		after := ts
+		if entry.Current != nil {
+			after = entry.Current.Stop
+		}
+		for i := range programs {
+			if programs[i].Start >= after && (entry.Next == nil || programs[i].Start < entry.Next.Start) {
+				entry.Next = &programs[i]
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
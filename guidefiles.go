@@ -0,0 +1,126 @@
+package stalkerlib
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ericcmi/stalkerlib/matching"
+)
+
+/* GuideSplit selects how WriteGuideFiles divides a guide into files. */
+type GuideSplit int
+
+const (
+	GuideSingle   GuideSplit = iota // One guide.xml
+	GuideByDay                      // One guide-YYYY-MM-DD.xml per day programs start on, time-shifted programs by their shifted start
+	GuideByGroup                    // One guide-<group>.xml per channel group
+	GuideByRegion                   // One guide-<region>.xml per channel region tag
+)
+
+/* GuideFileOptions configures WriteGuideFiles. */
+type GuideFileOptions struct {
+	Split      GuideSplit        // How the guide is divided
+	Gzip       bool              // Compress each file, adding a .gz suffix
+	GroupNames map[string]string // Genre ID to group name, for GuideByGroup
+}
+
+/* WriteGuideFiles writes the XMLTV guide of channels into dir as one or several files, optionally gzipped, for consumers that cannot load one huge guide; it returns the written file names. */
+func (c *StalkerClient) WriteGuideFiles(dir string, channels []Channel, programs map[string][]EPGProgram, opts XMLTVOptions, files GuideFileOptions) ([]string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory %s: %w", dir, err)
+	}
+	parts, err := c.guideParts(channels, programs, opts, files)
+	if err != nil {
+		return nil, err
+	}
+	var written []string
+	for _, part := range parts {
+		name := part.name + ".xml"
+		if files.Gzip {
+			name += ".gz"
+		}
+		filename := filepath.Join(dir, name)
+		err := writeFileAtomic(filename, 0644, func(w io.Writer) error {
+			if !files.Gzip {
+				return c.ExportXMLTV(w, part.channels, part.programs, part.opts)
+			}
+			zw := gzip.NewWriter(w)
+			if err := c.ExportXMLTV(zw, part.channels, part.programs, part.opts); err != nil {
+				return err
+			}
+			return zw.Close()
+		})
+		if err != nil {
+			return written, err
+		}
+		written = append(written, filename)
+	}
+	return written, nil
+}
+
+/* guidePart is the content of one guide file. */
+type guidePart struct {
+	name     string
+	channels []Channel
+	programs map[string][]EPGProgram
+	opts     XMLTVOptions // Export options of the part
+}
+
+/* guideParts divides the guide as files.Split asks, in file name order. */
+func (c *StalkerClient) guideParts(channels []Channel, programs map[string][]EPGProgram, opts XMLTVOptions, files GuideFileOptions) ([]guidePart, error) {
+	switch files.Split {
+	case GuideByDay:
+		loc, err := c.xmltvLocation(opts)
+		if err != nil {
+			return nil, err
+		}
+		// Select and shift the channels up front, so time-shifted programs land on the day they air
+		entries := opts.Timeshift.expand(c.exportChannels(channels, opts.Regions, opts.Collapse, opts.Sort), opts.URLs == URLProxied)
+		expanded := make([]Channel, len(entries))
+		days := make(map[string]map[string][]EPGProgram)
+		for i, entry := range entries {
+			expanded[i] = entry.Channel
+			for _, p := range entry.programs(programs) {
+				day := time.Unix(p.Start, 0).In(loc).Format("2006-01-02")
+				if days[day] == nil {
+					days[day] = make(map[string][]EPGProgram)
+				}
+				days[day][entry.ID] = append(days[day][entry.ID], p)
+			}
+		}
+		partOpts := opts
+		partOpts.Regions, partOpts.Collapse, partOpts.Sort, partOpts.Timeshift = nil, QualityCollapse{}, ChannelSort{}, TimeshiftOptions{}
+		parts := make([]guidePart, 0, len(days))
+		for day, progs := range days {
+			parts = append(parts, guidePart{name: "guide-" + day, channels: expanded, programs: progs, opts: partOpts})
+		}
+		sort.Slice(parts, func(i, j int) bool { return parts[i].name < parts[j].name })
+		return parts, nil
+	case GuideByGroup, GuideByRegion:
+		index := make(map[string]int)
+		var parts []guidePart
+		for _, ch := range channels {
+			group := strings.ToLower(ch.Region)
+			if files.Split == GuideByGroup {
+				group = strings.Join(matching.Tokens(firstNonEmpty(files.GroupNames[ch.GenreID], ch.GenreID)), "-")
+			}
+			name := "guide-" + firstNonEmpty(group, "other")
+			i, ok := index[name]
+			if !ok {
+				i = len(parts)
+				index[name] = i
+				parts = append(parts, guidePart{name: name, programs: programs, opts: opts})
+			}
+			parts[i].channels = append(parts[i].channels, ch)
+		}
+		sort.Slice(parts, func(i, j int) bool { return parts[i].name < parts[j].name })
+		return parts, nil
+	}
+	return []guidePart{{name: "guide", channels: channels, programs: programs, opts: opts}}, nil
+}
@@ -0,0 +1,64 @@
+package stalkerlib
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+/* tokenState coordinates token refreshes, so concurrent calls rejected with the same expired token share one handshake instead of replacing each other's tokens. */
+type tokenState struct {
+	mu    sync.Mutex
+	gen   atomic.Uint64          // Number of tokens obtained so far
+	token atomic.Pointer[string] // Current token, nil before the first handshake
+}
+
+/* CurrentToken returns the current authentication token, empty before the first handshake. */
+func (c *StalkerClient) CurrentToken() string {
+	if t := c.auth.token.Load(); t != nil {
+		return *t
+	}
+	return ""
+}
+
+/* setToken installs a new token and mirrors it to the deprecated Token field; the caller holds auth.mu. */
+func (c *StalkerClient) setToken(token string) {
+	c.auth.token.Store(&token)
+	c.Token = token
+	c.auth.gen.Add(1)
+}
+
+/* renewToken handshakes for a new token unless one was obtained since generation gen, which the caller read before using the token that failed; a client without a token first tries the stored session. */
+func (c *StalkerClient) renewToken(ctx context.Context, gen uint64) error {
+	c.auth.mu.Lock()
+	defer c.auth.mu.Unlock()
+	if c.auth.gen.Load() != gen {
+		return nil
+	}
+	if c.CurrentToken() == "" && c.Token != "" {
+		// A token set on the deprecated field before the first call
+		c.setToken(c.Token)
+		return nil
+	}
+	if c.CurrentToken() == "" && c.loadSession() {
+		return nil
+	}
+	return c.handshakeLocked(ctx)
+}
+
+/* TokenGeneration returns a counter incremented by every successful handshake, letting long-running consumers such as relays notice that the token changed under them. */
+func (c *StalkerClient) TokenGeneration() uint64 {
+	return c.auth.gen.Load()
+}
+
+/* Refresh re-issues create_link for the session's channel with the current token, keeping its stream slot, and returns the new playback URL; relays call it to resume a stream whose link expired with a token refresh. */
+func (s *PlaybackSession) Refresh(ctx context.Context) (string, error) {
+	playURL, err := s.client.getPlaybackURL(ctx, s.ChannelCmd)
+	if err != nil {
+		return "", err
+	}
+	s.mu.Lock()
+	s.playURL = playURL
+	s.mu.Unlock()
+	return playURL, nil
+}
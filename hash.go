@@ -0,0 +1,45 @@
+package stalkerlib
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+	"time"
+)
+
+/* HashChannels returns a stable hex digest of a channel list, so consumers can skip regenerating outputs when nothing changed; it depends on channel order. */
+func HashChannels(channels []Channel) string {
+	h := sha256.New()
+	enc := json.NewEncoder(h)
+	for _, ch := range channels {
+		enc.Encode(ch)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+/* HashEPGDay returns a stable hex digest of the programs starting on the calendar day of day, in day's location, independent of their order. */
+func HashEPGDay(programs []EPGProgram, day time.Time) string {
+	y, m, d := day.Date()
+	from := time.Date(y, m, d, 0, 0, 0, 0, day.Location()).Unix()
+	to := time.Date(y, m, d+1, 0, 0, 0, 0, day.Location()).Unix()
+	var selected []EPGProgram
+	for _, p := range programs {
+		if p.Start >= from && p.Start < to {
+			selected = append(selected, p)
+		}
+	}
+	sort.SliceStable(selected, func(i, j int) bool {
+		if selected[i].Start != selected[j].Start {
+			return selected[i].Start < selected[j].Start
+		}
+		return selected[i].ChannelID < selected[j].ChannelID
+	})
+
+	h := sha256.New()
+	enc := json.NewEncoder(h)
+	for _, p := range selected {
+		enc.Encode(p)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
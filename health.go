@@ -0,0 +1,258 @@
+package stalkerlib
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+/* CheckStream resolves the playback URL of ch and opens the stream, reporting an error unless the stream server answers 200 with data; the check occupies a stream slot while it runs, and only failures of the stream itself satisfy IsStreamFailure. */
+func (c *StalkerClient) CheckStream(ctx context.Context, ch Channel) error {
+	if err := c.resolveStreamLimit(ctx); err != nil {
+		return err
+	}
+	s := &PlaybackSession{ChannelCmd: ch.Cmd, client: c}
+	if err := c.slots.acquire(ctx, s); err != nil {
+		return err
+	}
+	defer s.Close()
+
+	// Checks must not show up in the playback history or be reported to the portal
+	playURL, err := c.resolvePlaybackURL(ctx, ch.Cmd)
+	if err != nil {
+		return fmt.Errorf("failed to resolve channel %s: %w", ch.Name, err)
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", streamLocation(playURL), nil)
+	if err != nil {
+		return &streamFailure{fmt.Errorf("failed to create stream request for %s: %w", ch.Name, err)}
+	}
+	req.Header.Set("User-Agent", STBUserAgent)
+	resp, err := c.client().Do(req)
+	if err != nil {
+		return &streamFailure{fmt.Errorf("stream of %s failed: %w", ch.Name, err)}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return &streamFailure{fmt.Errorf("stream of %s failed: status %d", ch.Name, resp.StatusCode)}
+	}
+	if _, err := io.ReadFull(resp.Body, make([]byte, 1)); err != nil {
+		return &streamFailure{fmt.Errorf("stream of %s sent no data: %w", ch.Name, err)}
+	}
+	return nil
+}
+
+/* streamFailure marks a CheckStream error of the stream server, as opposed to the stream slots or the portal. */
+type streamFailure struct {
+	err error
+}
+
+/* Error returns the underlying error message. */
+func (e *streamFailure) Error() string {
+	return e.err.Error()
+}
+
+/* Unwrap returns the underlying error. */
+func (e *streamFailure) Unwrap() error {
+	return e.err
+}
+
+/* IsStreamFailure reports whether a CheckStream error says the channel's stream is broken, rather than that no slot was free or the portal failed. */
+func IsStreamFailure(err error) bool {
+	var f *streamFailure
+	return errors.As(err, &f)
+}
+
+/* AvailabilityOptions configures an AvailabilityMonitor. */
+type AvailabilityOptions struct {
+	Interval time.Duration         // Time between sweeps of the lineup; 0 for one hour
+	Failures int                   // Consecutive failed checks before a channel is marked dead; 0 for 3
+	Timeout  time.Duration         // Limit of each stream check; 0 for 15 seconds
+	Filter   func(ch Channel) bool // Selects the channels to check; nil checks the whole lineup
+	Prune    bool                  // Leave dead channels out of ExportM3U and ExportXMLTV until they recover
+}
+
+/* ChannelHealth is the availability record of one checked channel. */
+type ChannelHealth struct {
+	ChannelID   string    // Checked channel
+	Failures    int       // Consecutive failed checks, reset by a successful one
+	Dead        bool      // Whether Failures reached the configured threshold
+	LastChecked time.Time // When the channel was last checked
+	LastErr     error     // Failure of the last check, nil when it succeeded
+}
+
+/* AvailabilityMonitor runs CheckStream across the lineup on a schedule and tracks which channels have gone dead. */
+type AvailabilityMonitor struct {
+	client *StalkerClient
+	opts   AvailabilityOptions
+
+	mu     sync.Mutex
+	health map[string]*ChannelHealth
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+/* NewAvailabilityMonitor creates a lineup checker; with opts.Prune the client's exports skip the channels it marks dead. It is stopped when the client shuts down. */
+func (c *StalkerClient) NewAvailabilityMonitor(opts AvailabilityOptions) *AvailabilityMonitor {
+	if opts.Interval <= 0 {
+		opts.Interval = time.Hour
+	}
+	if opts.Failures <= 0 {
+		opts.Failures = 3
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 15 * time.Second
+	}
+	m := &AvailabilityMonitor{
+		client: c,
+		opts:   opts,
+		health: make(map[string]*ChannelHealth),
+	}
+	if opts.Prune {
+		c.availability.Store(m)
+	}
+	c.onShutdown(m.Stop)
+	return m
+}
+
+/* Check sweeps the lineup once, checking the selected channels one at a time so the sweep holds at most one stream slot; only stream failures count against a channel, and the sweep stops early when no slot frees up or the portal fails. */
+func (m *AvailabilityMonitor) Check(ctx context.Context) error {
+	channels, err := m.client.getChannels(ctx)
+	if err != nil {
+		return err
+	}
+	for _, ch := range channels {
+		if m.opts.Filter != nil && !m.opts.Filter(ch) {
+			continue
+		}
+		checkCtx, cancel := context.WithTimeout(ctx, m.opts.Timeout)
+		err := m.client.CheckStream(checkCtx, ch)
+		cancel()
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		// A busy account or failing portal says nothing about the channel, and would fail the rest of the sweep alike
+		if err != nil && !IsStreamFailure(err) {
+			return fmt.Errorf("availability sweep stopped at %s: %w", ch.Name, err)
+		}
+		m.record(ch.ID, err)
+	}
+	return nil
+}
+
+/* record updates the health of one channel with a check result, emitting an event when it dies or recovers. */
+func (m *AvailabilityMonitor) record(channelID string, err error) {
+	m.mu.Lock()
+	h := m.health[channelID]
+	if h == nil {
+		h = &ChannelHealth{ChannelID: channelID}
+		m.health[channelID] = h
+	}
+	wasDead := h.Dead
+	h.LastChecked, h.LastErr = time.Now(), err
+	if err != nil {
+		h.Failures++
+	} else {
+		h.Failures = 0
+	}
+	h.Dead = h.Failures >= m.opts.Failures
+	dead := h.Dead
+	m.mu.Unlock()
+
+	switch {
+	case dead && !wasDead:
+		m.client.emit(Event{Type: EventChannelDead, ChannelID: channelID, Err: err})
+	case !dead && wasDead:
+		m.client.emit(Event{Type: EventChannelRecovered, ChannelID: channelID})
+	}
+}
+
+/* Dead reports whether the channel with the given ID is currently marked dead. */
+func (m *AvailabilityMonitor) Dead(channelID string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	h := m.health[channelID]
+	return h != nil && h.Dead
+}
+
+/* Health returns the records of every checked channel, sorted by channel ID. */
+func (m *AvailabilityMonitor) Health() []ChannelHealth {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	records := make([]ChannelHealth, 0, len(m.health))
+	for _, h := range m.health {
+		records = append(records, *h)
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].ChannelID < records[j].ChannelID })
+	return records
+}
+
+/* Start sweeps the lineup every interval in a background goroutine until Stop is called. */
+func (m *AvailabilityMonitor) Start() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.stop != nil {
+		return
+	}
+	m.stop = make(chan struct{})
+	m.done = make(chan struct{})
+	go m.run(m.stop, m.done)
+}
+
+/* Stop halts the background sweeps started by Start, canceling a running one, and waits for them to exit. */
+func (m *AvailabilityMonitor) Stop() {
+	m.mu.Lock()
+	stop, done := m.stop, m.done
+	m.stop, m.done = nil, nil
+	m.mu.Unlock()
+	if stop == nil {
+		return
+	}
+	close(stop)
+	<-done
+}
+
+/* run is the background loop behind Start. */
+func (m *AvailabilityMonitor) run(stop, done chan struct{}) {
+	defer close(done)
+	ctx, cancel := context.WithCancel(m.client.baseContext())
+	defer cancel()
+	go func() {
+		select {
+		case <-stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	ticker := time.NewTicker(m.opts.Interval)
+	defer ticker.Stop()
+	for {
+		m.Check(ctx)
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+/* availableChannels drops the channels a pruning AvailabilityMonitor has marked dead. */
+func (c *StalkerClient) availableChannels(channels []Channel) []Channel {
+	m := c.availability.Load()
+	if m == nil {
+		return channels
+	}
+	live := make([]Channel, 0, len(channels))
+	for _, ch := range channels {
+		if !m.Dead(ch.ID) {
+			live = append(live, ch)
+		}
+	}
+	return live
+}
@@ -0,0 +1,224 @@
+package stalkerlib
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+/* WatchRecord is one playback resolution recorded in the watch history. */
+type WatchRecord struct {
+	Time        time.Time `json:"time"`
+	ChannelID   string    `json:"channel_id,omitempty"`
+	ChannelName string    `json:"channel_name,omitempty"`
+	Cmd         string    `json:"cmd"`
+	Program     string    `json:"program,omitempty"` // Title airing at resolution time, if the EPG was cached
+}
+
+/* HistoryQuery filters watch records; zero fields match everything. */
+type HistoryQuery struct {
+	ChannelID string    // Only records for this channel
+	Since     time.Time // Only records at or after this time
+	Until     time.Time // Only records before this time
+	Limit     int       // Maximum number of records, newest first
+}
+
+/* matches reports whether r satisfies the query filters. */
+func (q HistoryQuery) matches(r WatchRecord) bool {
+	if q.ChannelID != "" && r.ChannelID != q.ChannelID {
+		return false
+	}
+	if !q.Since.IsZero() && r.Time.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && !r.Time.Before(q.Until) {
+		return false
+	}
+	return true
+}
+
+/* HistoryStore persists watch records. */
+type HistoryStore interface {
+	Record(r WatchRecord) error                    // Append a record
+	Records(q HistoryQuery) ([]WatchRecord, error) // Matching records, newest first
+}
+
+/* WithHistory records every successful GetPlaybackURL call in store. */
+func WithHistory(store HistoryStore) Option {
+	return func(c *StalkerClient) {
+		c.history = store
+	}
+}
+
+/* recordPlayback adds a history entry for a resolved channel command, enriched from the cached lineup and EPG. */
+func (c *StalkerClient) recordPlayback(channelCmd string) {
+	if c.history == nil {
+		return
+	}
+	r := WatchRecord{Time: time.Now(), Cmd: channelCmd}
+	if ch, ok := c.channelByCmd(channelCmd); ok {
+		r.ChannelID, r.ChannelName = ch.ID, ch.Name
+	}
+	if r.ChannelID != "" {
+		programs, _ := c.epg.load(r.ChannelID)
+		now := r.Time.Unix()
+		for _, p := range programs {
+			if p.Start <= now && now < p.Stop {
+				r.Program = p.Name
+				break
+			}
+		}
+	}
+	c.history.Record(r)
+}
+
+/* WatchHistory returns recorded playbacks matching q. */
+func (c *StalkerClient) WatchHistory(q HistoryQuery) ([]WatchRecord, error) {
+	if c.history == nil {
+		return nil, fmt.Errorf("no history store configured")
+	}
+	return c.history.Records(q)
+}
+
+/* RecentlyWatched returns the latest record of each of the n most recently watched channels. */
+func (c *StalkerClient) RecentlyWatched(n int) ([]WatchRecord, error) {
+	records, err := c.WatchHistory(HistoryQuery{})
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	var recent []WatchRecord
+	for _, r := range records {
+		key := r.ChannelID
+		if key == "" {
+			key = r.Cmd
+		}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		recent = append(recent, r)
+		if n > 0 && len(recent) == n {
+			break
+		}
+	}
+	return recent, nil
+}
+
+/* WatchCounts returns the number of playbacks per channel ID since the given time. */
+func (c *StalkerClient) WatchCounts(since time.Time) (map[string]int, error) {
+	records, err := c.WatchHistory(HistoryQuery{Since: since})
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int)
+	for _, r := range records {
+		counts[r.ChannelID]++
+	}
+	return counts, nil
+}
+
+/* MemoryHistory is an in-memory HistoryStore keeping the most recent records up to a capacity. */
+type MemoryHistory struct {
+	mu       sync.Mutex
+	capacity int
+	records  []WatchRecord
+}
+
+/* NewMemoryHistory creates a MemoryHistory holding at most capacity records (unbounded when 0). */
+func NewMemoryHistory(capacity int) *MemoryHistory {
+	return &MemoryHistory{capacity: capacity}
+}
+
+/* Record appends a record, evicting the oldest one when full. */
+func (m *MemoryHistory) Record(r WatchRecord) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.records = append(m.records, r)
+	if m.capacity > 0 && len(m.records) > m.capacity {
+		m.records = m.records[len(m.records)-m.capacity:]
+	}
+	return nil
+}
+
+/* Records returns matching records, newest first. */
+func (m *MemoryHistory) Records(q HistoryQuery) ([]WatchRecord, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return filterHistory(m.records, q), nil
+}
+
+/* FileHistory is a HistoryStore appending JSON lines to a file. */
+type FileHistory struct {
+	mu   sync.Mutex
+	path string
+}
+
+/* NewFileHistory creates a FileHistory backed by the file at path, created on first write. */
+func NewFileHistory(path string) *FileHistory {
+	return &FileHistory{path: path}
+}
+
+/* Record appends a record to the file. */
+func (f *FileHistory) Record(r WatchRecord) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("failed to encode watch record: %w", err)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	out, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open history file %s: %w", f.path, err)
+	}
+	if _, err := out.Write(append(data, '\n')); err != nil {
+		out.Close()
+		return fmt.Errorf("failed to write history file %s: %w", f.path, err)
+	}
+	return out.Close()
+}
+
+/* Records reads matching records from the file, newest first; malformed lines are skipped. */
+func (f *FileHistory) Records(q HistoryQuery) ([]WatchRecord, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	in, err := os.Open(f.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open history file %s: %w", f.path, err)
+	}
+	defer in.Close()
+
+	var records []WatchRecord
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		var r WatchRecord
+		if json.Unmarshal(scanner.Bytes(), &r) == nil {
+			records = append(records, r)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read history file %s: %w", f.path, err)
+	}
+	return filterHistory(records, q), nil
+}
+
+/* filterHistory returns the records matching q, newest first, from records stored oldest first. */
+func filterHistory(records []WatchRecord, q HistoryQuery) []WatchRecord {
+	var out []WatchRecord
+	for i := len(records) - 1; i >= 0; i-- {
+		if !q.matches(records[i]) {
+			continue
+		}
+		out = append(out, records[i])
+		if q.Limit > 0 && len(out) == q.Limit {
+			break
+		}
+	}
+	return out
+}
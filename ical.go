@@ -0,0 +1,90 @@
+package stalkerlib
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+/* TimeRange bounds a time window; a zero From or To leaves that side open. */
+type TimeRange struct {
+	From time.Time
+	To   time.Time
+}
+
+/* Overlaps reports whether the interval [start, stop) intersects the range. */
+func (r TimeRange) Overlaps(start, stop time.Time) bool {
+	if !r.From.IsZero() && !stop.After(r.From) {
+		return false
+	}
+	if !r.To.IsZero() && !start.Before(r.To) {
+		return false
+	}
+	return true
+}
+
+/* ExportICal writes an iCalendar feed with one VEVENT per program of the given channels within timeRange. */
+func (c *StalkerClient) ExportICal(w io.Writer, channels []Channel, timeRange TimeRange) error {
+	bw := bufio.NewWriter(w)
+	stamp := time.Now().UTC().Format("20060102T150405Z")
+
+	writeICalLine(bw, "BEGIN:VCALENDAR")
+	writeICalLine(bw, "VERSION:2.0")
+	writeICalLine(bw, "PRODID:-//stalkerlib//EPG//EN")
+	writeICalLine(bw, "CALSCALE:GREGORIAN")
+	for _, ch := range channels {
+		programs, err := c.GetEPG(ch.ID)
+		if err != nil {
+			return fmt.Errorf("failed to fetch EPG for channel %s: %w", ch.Name, err)
+		}
+		for _, p := range programs {
+			start, stop := time.Unix(p.Start, 0).UTC(), time.Unix(p.Stop, 0).UTC()
+			if !timeRange.Overlaps(start, stop) {
+				continue
+			}
+			writeICalLine(bw, "BEGIN:VEVENT")
+			writeICalLine(bw, fmt.Sprintf("UID:%s-%d@stalkerlib", ch.ID, p.Start))
+			writeICalLine(bw, "DTSTAMP:"+stamp)
+			writeICalLine(bw, "DTSTART:"+start.Format("20060102T150405Z"))
+			writeICalLine(bw, "DTEND:"+stop.Format("20060102T150405Z"))
+			writeICalLine(bw, "SUMMARY:"+escapeICalText(p.Name))
+			writeICalLine(bw, "LOCATION:"+escapeICalText(ch.Name))
+			if p.Desc != "" {
+				writeICalLine(bw, "DESCRIPTION:"+escapeICalText(p.Desc))
+			}
+			if category := c.exportCategory(p.Category); category != "" {
+				writeICalLine(bw, "CATEGORIES:"+escapeICalText(category))
+			}
+			writeICalLine(bw, "END:VEVENT")
+		}
+	}
+	writeICalLine(bw, "END:VCALENDAR")
+
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("failed to write iCal output: %w", err)
+	}
+	return nil
+}
+
+/* escapeICalText escapes a value for use in an iCalendar TEXT property. */
+func escapeICalText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
+
+/* writeICalLine writes a content line, folding it at 75 octets as required by RFC 5545. */
+func writeICalLine(w *bufio.Writer, line string) {
+	limit := 75
+	for len(line) > limit {
+		// Avoid splitting a multi-byte UTF-8 sequence
+		cut := limit
+		for cut > 0 && line[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		w.WriteString(line[:cut] + "\r\n ")
+		line = line[cut:]
+		limit = 74 // continuation lines start with a space
+	}
+	w.WriteString(line + "\r\n")
+}
@@ -0,0 +1,62 @@
+package stalkerlib
+
+import "encoding/json"
+
+/* DeviceIdentity describes the emulated set-top box beyond its MAC address. */
+type DeviceIdentity struct {
+	MAC          string // MAC address, defaulting to the client's MAC
+	SerialNumber string // Device serial number ("sn")
+	Model        string // STB model, e.g. "MAG250"
+	DeviceID     string // Primary device ID
+	DeviceID2    string // Secondary device ID
+	Signature    string // Device signature
+}
+
+/* WithDeviceIdentity sets the identity presented to hardened portals, including the handshake metrics payload. */
+func WithDeviceIdentity(identity DeviceIdentity) Option {
+	return func(c *StalkerClient) {
+		c.device = identity
+	}
+}
+
+/* identity returns the configured device identity with the client's MAC filled in. */
+func (c *StalkerClient) identity() DeviceIdentity {
+	id := c.device
+	if id.MAC == "" {
+		id.MAC = c.MAC
+	}
+	return id
+}
+
+/* handshakeMetrics is the JSON "metrics" payload newer portals expect during handshake. */
+type handshakeMetrics struct {
+	MAC    string `json:"mac"`
+	SN     string `json:"sn"`
+	Model  string `json:"model"`
+	Type   string `json:"type"`
+	UID    string `json:"uid"`
+	Random string `json:"random"`
+}
+
+/* metrics encodes the handshake metrics payload, reporting false when no device identity is configured. */
+func (c *StalkerClient) metrics() (string, bool) {
+	if c.device == (DeviceIdentity{}) {
+		return "", false
+	}
+	id := c.identity()
+	model := id.Model
+	if model == "" {
+		model = "MAG250"
+	}
+	data, err := json.Marshal(handshakeMetrics{
+		MAC:   id.MAC,
+		SN:    id.SerialNumber,
+		Model: model,
+		Type:  "STB",
+		UID:   id.DeviceID,
+	})
+	if err != nil {
+		return "", false
+	}
+	return string(data), true
+}
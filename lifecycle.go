@@ -0,0 +1,124 @@
+package stalkerlib
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+)
+
+/* ErrClientClosed is returned for requests issued after Close or Shutdown. */
+var ErrClientClosed = errors.New("stalkerlib: client closed")
+
+/* lifecycle tracks in-flight requests and background goroutines so the client can be shut down cleanly. */
+type lifecycle struct {
+	mu       sync.Mutex
+	closed   bool
+	ctx      context.Context
+	cancel   context.CancelFunc
+	inflight sync.WaitGroup
+	stoppers []func()
+}
+
+/* baseContext returns the context all portal requests derive from; it is canceled when the client is closed. */
+func (c *StalkerClient) baseContext() context.Context {
+	c.life.mu.Lock()
+	defer c.life.mu.Unlock()
+	c.life.initLocked()
+	return c.life.ctx
+}
+
+/* initLocked lazily creates the base context; callers hold mu. */
+func (l *lifecycle) initLocked() {
+	if l.ctx == nil {
+		l.ctx, l.cancel = context.WithCancel(context.Background())
+	}
+}
+
+/* onShutdown registers a function that stops a background goroutine when the client shuts down. */
+func (c *StalkerClient) onShutdown(stop func()) {
+	c.life.mu.Lock()
+	closed := c.life.closed
+	if !closed {
+		c.life.stoppers = append(c.life.stoppers, stop)
+	}
+	c.life.mu.Unlock()
+	if closed {
+		stop()
+	}
+}
+
+/* beginRequest registers an in-flight request, returning the function that marks it finished. */
+func (c *StalkerClient) beginRequest() (func(), error) {
+	c.life.mu.Lock()
+	defer c.life.mu.Unlock()
+	if c.life.closed {
+		return nil, ErrClientClosed
+	}
+	c.life.inflight.Add(1)
+	return c.life.inflight.Done, nil
+}
+
+/* Close aborts in-flight requests and releases all client resources immediately. */
+func (c *StalkerClient) Close() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	c.Shutdown(ctx)
+	return nil
+}
+
+/* Shutdown stops background goroutines, drains in-flight requests until ctx is done, then flushes caches and closes idle connections. */
+func (c *StalkerClient) Shutdown(ctx context.Context) error {
+	c.life.mu.Lock()
+	c.life.initLocked()
+	c.life.closed = true
+	stoppers := c.life.stoppers
+	c.life.stoppers = nil
+	c.life.mu.Unlock()
+
+	for _, stop := range stoppers {
+		stop()
+	}
+
+	// Drain in-flight requests, aborting whatever is left when ctx expires
+	drained := make(chan struct{})
+	go func() {
+		c.life.inflight.Wait()
+		close(drained)
+	}()
+	var err error
+	select {
+	case <-drained:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	c.life.cancel()
+	<-drained
+
+	c.epg.flush()
+	c.channels.flush()
+	c.actions.flush()
+	c.client().CloseIdleConnections()
+	return err
+}
+
+/* trackingTransport registers each request with the client lifecycle until its response body is closed. */
+type trackingTransport struct {
+	base   http.RoundTripper
+	client *StalkerClient
+}
+
+func (t *trackingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	done, err := t.client.beginRequest()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		done()
+		t.client.reportTransportError(req, err)
+		return nil, err
+	}
+	resp.Body = &releaseOnClose{ReadCloser: resp.Body, release: done}
+	return resp, nil
+}
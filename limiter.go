@@ -0,0 +1,194 @@
+package stalkerlib
+
+import (
+	"container/heap"
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+/* WithMaxConcurrentRequests limits simultaneous in-flight requests (including body reads) to any single host, 0 meaning unlimited. */
+func WithMaxConcurrentRequests(n int) Option {
+	return func(c *StalkerClient) {
+		c.maxPerHost = n
+	}
+}
+
+/* hostLimiter holds one priority-ordered semaphore per host, which can also be paused while the host asks clients to slow down. */
+type hostLimiter struct {
+	limit int // Slots per host, 0 for unlimited
+	mu    sync.Mutex
+	slots map[string]*prioritySemaphore
+}
+
+/* pause holds back requests to host for d, extending but never shortening an existing pause. */
+func (l *hostLimiter) pause(host string, d time.Duration) {
+	sem := l.semaphore(host)
+	sem.mu.Lock()
+	defer sem.mu.Unlock()
+	if until := time.Now().Add(d); until.After(sem.paused) {
+		sem.paused = until
+	}
+}
+
+/* semaphore returns the semaphore for host, creating it on first use. */
+func (l *hostLimiter) semaphore(host string) *prioritySemaphore {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.slots == nil {
+		l.slots = make(map[string]*prioritySemaphore)
+	}
+	sem, ok := l.slots[host]
+	if !ok {
+		sem = &prioritySemaphore{limit: l.limit}
+		l.slots[host] = sem
+	}
+	return sem
+}
+
+/* prioritySemaphore is a counting semaphore that hands freed slots to the highest-priority waiter, FIFO within a priority. */
+type prioritySemaphore struct {
+	mu      sync.Mutex
+	limit   int
+	inUse   int
+	seq     uint64
+	waiters waiterHeap
+	paused  time.Time // Requests wait until then, zero when not paused
+}
+
+/* acquire blocks until the pause, if any, ends and a slot is granted, or ctx is done. */
+func (s *prioritySemaphore) acquire(ctx context.Context, priority Priority) error {
+	if err := s.waitPause(ctx); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	if (s.limit <= 0 || s.inUse < s.limit) && len(s.waiters) == 0 {
+		s.inUse++
+		s.mu.Unlock()
+		return nil
+	}
+	s.seq++
+	w := &waiter{priority: priority, seq: s.seq, ready: make(chan struct{})}
+	heap.Push(&s.waiters, w)
+	s.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		granted := w.index < 0
+		if !granted {
+			heap.Remove(&s.waiters, w.index)
+		}
+		s.mu.Unlock()
+		if granted {
+			// The slot was handed over while we gave up; pass it on
+			s.release()
+		}
+		return ctx.Err()
+	}
+}
+
+/* waitPause blocks until the pause ends or ctx is done. */
+func (s *prioritySemaphore) waitPause(ctx context.Context) error {
+	s.mu.Lock()
+	d := time.Until(s.paused)
+	s.mu.Unlock()
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+/* release frees a slot, transferring it directly to the next waiter if there is one. */
+func (s *prioritySemaphore) release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.waiters) > 0 {
+		w := heap.Pop(&s.waiters).(*waiter)
+		close(w.ready)
+		return
+	}
+	s.inUse--
+}
+
+/* waiter is a blocked acquire call. */
+type waiter struct {
+	priority Priority
+	seq      uint64
+	ready    chan struct{}
+	index    int // Position in the heap, -1 once granted
+}
+
+/* waiterHeap orders waiters by descending priority, then by arrival. */
+type waiterHeap []*waiter
+
+func (h waiterHeap) Len() int { return len(h) }
+func (h waiterHeap) Less(i, j int) bool {
+	if h[i].priority != h[j].priority {
+		return h[i].priority > h[j].priority
+	}
+	return h[i].seq < h[j].seq
+}
+func (h waiterHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+func (h *waiterHeap) Push(x interface{}) {
+	w := x.(*waiter)
+	w.index = len(*h)
+	*h = append(*h, w)
+}
+func (h *waiterHeap) Pop() interface{} {
+	old := *h
+	w := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	w.index = -1
+	return w
+}
+
+/* limitTransport waits out host pauses and acquires a per-host slot before each request, returning the slot when the response body is closed. */
+type limitTransport struct {
+	base    http.RoundTripper
+	limiter *hostLimiter
+}
+
+func (t *limitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	sem := t.limiter.semaphore(req.URL.Host)
+	if err := sem.acquire(req.Context(), priorityFromContext(req.Context())); err != nil {
+		return nil, err
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		sem.release()
+		return nil, err
+	}
+	resp.Body = &releaseOnClose{ReadCloser: resp.Body, release: sem.release}
+	return resp, nil
+}
+
+/* releaseOnClose runs release exactly once when the wrapped body is closed. */
+type releaseOnClose struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+func (r *releaseOnClose) Close() error {
+	err := r.ReadCloser.Close()
+	r.once.Do(r.release)
+	return err
+}
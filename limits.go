@@ -0,0 +1,83 @@
+package stalkerlib
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+/* ErrResponseTooLarge is returned when a portal response exceeds the size limit of its action. */
+var ErrResponseTooLarge = errors.New("stalkerlib: response too large")
+
+/* defaultMaxResponseSize bounds portal API responses unless overridden; file downloads are not limited by default. */
+const defaultMaxResponseSize = 64 << 20
+
+/* WithMaxResponseSize limits response bodies of action (e.g. "get_all_channels", or "download" for DownloadFile) to limit bytes; an empty action sets the default for all API actions, and a limit of 0 removes the cap. */
+func WithMaxResponseSize(action string, limit int64) Option {
+	return func(c *StalkerClient) {
+		if c.maxResponseSizes == nil {
+			c.maxResponseSizes = make(map[string]int64)
+		}
+		c.maxResponseSizes[action] = limit
+	}
+}
+
+/* maxResponseSize returns the body limit of action, 0 meaning unlimited. */
+func (c *StalkerClient) maxResponseSize(action string) int64 {
+	if limit, ok := c.maxResponseSizes[action]; ok {
+		return limit
+	}
+	if action == "download" {
+		return 0
+	}
+	if limit, ok := c.maxResponseSizes[""]; ok {
+		return limit
+	}
+	return defaultMaxResponseSize
+}
+
+/* capResponse enforces the action's size limit, failing fast on an oversized Content-Length and otherwise making reads past the limit fail with ErrResponseTooLarge. */
+func (c *StalkerClient) capResponse(action string, resp *http.Response) error {
+	limit := c.maxResponseSize(action)
+	if limit <= 0 {
+		return nil
+	}
+	if resp.ContentLength > limit {
+		resp.Body.Close()
+		return fmt.Errorf("%w: %s response of %d bytes exceeds %d", ErrResponseTooLarge, action, resp.ContentLength, limit)
+	}
+	resp.Body = &cappedBody{body: resp.Body, remaining: limit, action: action, limit: limit}
+	return nil
+}
+
+/* cappedBody is a response body that fails once more than limit bytes are read. */
+type cappedBody struct {
+	body      io.ReadCloser
+	remaining int64
+	action    string
+	limit     int64
+}
+
+/* Read reads from the body, failing with ErrResponseTooLarge past the limit. */
+func (b *cappedBody) Read(p []byte) (int, error) {
+	if b.remaining <= 0 {
+		// Probe for one more byte to tell an exact-size body from an oversized one
+		var one [1]byte
+		if n, err := b.body.Read(one[:]); n == 0 {
+			return 0, err
+		}
+		return 0, fmt.Errorf("%w: %s response exceeds %d bytes", ErrResponseTooLarge, b.action, b.limit)
+	}
+	if int64(len(p)) > b.remaining {
+		p = p[:b.remaining]
+	}
+	n, err := b.body.Read(p)
+	b.remaining -= int64(n)
+	return n, err
+}
+
+/* Close closes the underlying body. */
+func (b *cappedBody) Close() error {
+	return b.body.Close()
+}
@@ -0,0 +1,137 @@
+package stalkerlib
+
+import (
+	"context"
+	"errors"
+
+	"github.com/ericcmi/stalkerlib/matching"
+)
+
+/* ErrChannelNotFound is returned when a requested channel is not in the lineup. */
+var ErrChannelNotFound = errors.New("stalkerlib: channel not found")
+
+/* Lineup is an immutable channel list indexed by ID, number, and normalized name. */
+type Lineup struct {
+	channels []Channel
+	byID     map[string]int
+	byNumber map[string]int
+	byName   map[string]int
+	hash     string
+	version  uint64
+}
+
+/* NewLineup indexes channels; when several share a number or normalized name, the first one wins. */
+func NewLineup(channels []Channel) *Lineup {
+	l := &Lineup{
+		channels: channels,
+		byID:     make(map[string]int, len(channels)),
+		byNumber: make(map[string]int, len(channels)),
+		byName:   make(map[string]int, len(channels)),
+	}
+	for i, ch := range channels {
+		addIndex(l.byID, ch.ID, i)
+		addIndex(l.byNumber, ch.Number, i)
+		addIndex(l.byName, matching.Normalize(ch.Name), i)
+	}
+	l.hash = HashChannels(channels)
+	l.version = 1
+	return l
+}
+
+/* addIndex records key to i unless key is empty or already taken. */
+func addIndex(index map[string]int, key string, i int) {
+	if _, ok := index[key]; key != "" && !ok {
+		index[key] = i
+	}
+}
+
+/* Channels returns the channels in portal order; the slice must not be modified. */
+func (l *Lineup) Channels() []Channel {
+	return l.channels
+}
+
+/* Len returns the number of channels in the lineup. */
+func (l *Lineup) Len() int {
+	return len(l.channels)
+}
+
+/* Hash returns the HashChannels digest of the lineup. */
+func (l *Lineup) Hash() string {
+	return l.hash
+}
+
+/* Version returns a counter stamped by the client, starting at 1 and incremented each time the lineup content changes. */
+func (l *Lineup) Version() uint64 {
+	return l.version
+}
+
+/* ByID returns the channel with the given portal ID. */
+func (l *Lineup) ByID(id string) (Channel, bool) {
+	return l.lookup(l.byID, id)
+}
+
+/* ByNumber returns the channel with the given channel number. */
+func (l *Lineup) ByNumber(number string) (Channel, bool) {
+	return l.lookup(l.byNumber, number)
+}
+
+/* ByName returns the channel whose name normalizes (see matching.Normalize) to the same form as name. */
+func (l *Lineup) ByName(name string) (Channel, bool) {
+	return l.lookup(l.byName, matching.Normalize(name))
+}
+
+/* lookup returns the channel at index[key]. */
+func (l *Lineup) lookup(index map[string]int, key string) (Channel, bool) {
+	i, ok := index[key]
+	if !ok {
+		return Channel{}, false
+	}
+	return l.channels[i], true
+}
+
+/* Lineup returns the indexed channel list, built from the cached channels when present and fetched otherwise. */
+func (c *StalkerClient) Lineup() (*Lineup, error) {
+	return c.getLineup(context.Background())
+}
+
+/* GetChannelByID returns one channel by portal ID, or ErrChannelNotFound. */
+func (c *StalkerClient) GetChannelByID(id string) (Channel, error) {
+	lineup, err := c.getLineup(context.Background())
+	if err != nil {
+		return Channel{}, err
+	}
+	ch, ok := lineup.ByID(id)
+	if !ok {
+		return Channel{}, ErrChannelNotFound
+	}
+	return ch, nil
+}
+
+/* getLineup implements Lineup under the given context, reusing the last index while the channel list is unchanged. */
+func (c *StalkerClient) getLineup(ctx context.Context) (*Lineup, error) {
+	channels, ok := c.channels.load()
+	if !ok {
+		var err error
+		if channels, err = c.getChannels(ctx); err != nil {
+			return nil, err
+		}
+	}
+	prev := c.lineup.Load()
+	if prev != nil && sameChannels(prev.channels, channels) {
+		return prev, nil
+	}
+	l := NewLineup(channels)
+	if prev != nil {
+		l.version = prev.version
+		if l.hash != prev.hash {
+			l.version++
+		}
+	}
+	c.lineup.Store(l)
+	return l, nil
+}
+
+/* sameChannels reports whether a and b are the same slice, which holds while the channel cache is not replaced. */
+func sameChannels(a, b []Channel) bool {
+	return len(a) == len(b) && (len(a) == 0 || &a[0] == &b[0])
+}
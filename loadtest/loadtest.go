@@ -0,0 +1,216 @@
+/* Package loadtest exercises a portal with concurrent authentication, channel list, and create_link calls and reports latency percentiles, for sizing deployments and checking the client's own concurrency behavior. */
+package loadtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ericcmi/stalkerlib"
+)
+
+/* Scenario names one portal operation exercised by Run. */
+type Scenario string
+
+const (
+	ScenarioAuth       Scenario = "auth"        // Handshake
+	ScenarioChannels   Scenario = "channels"    // get_all_channels
+	ScenarioCreateLink Scenario = "create_link" // Playback URL of a channel from the lineup
+)
+
+/* Config controls a load test run. */
+type Config struct {
+	Concurrency int        // Parallel workers per scenario (1 when not positive)
+	Requests    int        // Calls per scenario (Concurrency when not positive)
+	Scenarios   []Scenario // Scenarios run in order (all three when empty)
+	Shared      bool       // Share one client across workers instead of one client per worker; the auth scenario still handshakes on per-worker clients, since each handshake replaces the shared token
+}
+
+/* Result summarizes the calls of one scenario. */
+type Result struct {
+	Scenario   Scenario
+	Requests   int
+	Errors     int
+	FirstError error
+	Elapsed    time.Duration // Wall time of the whole scenario
+	P50        time.Duration
+	P90        time.Duration
+	P99        time.Duration
+	Max        time.Duration
+}
+
+/* Throughput returns completed calls per second. */
+func (r Result) Throughput() float64 {
+	if r.Elapsed <= 0 {
+		return 0
+	}
+	return float64(r.Requests) / r.Elapsed.Seconds()
+}
+
+/* Report holds the results of a run, one per scenario. */
+type Report struct {
+	Results []Result
+}
+
+/* WriteTo writes the report as an aligned text table. */
+func (r *Report) WriteTo(w io.Writer) (int64, error) {
+	var total int64
+	n, err := fmt.Fprintf(w, "%-12s %8s %7s %10s %10s %10s %10s %9s\n", "scenario", "requests", "errors", "p50", "p90", "p99", "max", "req/s")
+	total += int64(n)
+	if err != nil {
+		return total, err
+	}
+	for _, res := range r.Results {
+		n, err := fmt.Fprintf(w, "%-12s %8d %7d %10s %10s %10s %10s %9.1f\n", res.Scenario, res.Requests, res.Errors,
+			res.P50.Round(time.Microsecond), res.P90.Round(time.Microsecond), res.P99.Round(time.Microsecond),
+			res.Max.Round(time.Microsecond), res.Throughput())
+		total += int64(n)
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+/* Run executes the configured scenarios under ctx against clients built by newClient, which is called once per worker (or once overall when Shared is set, plus once per worker for the auth scenario). */
+func Run(ctx context.Context, newClient func() *stalkerlib.StalkerClient, cfg Config) (*Report, error) {
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = 1
+	}
+	if cfg.Requests <= 0 {
+		cfg.Requests = cfg.Concurrency
+	}
+	if len(cfg.Scenarios) == 0 {
+		cfg.Scenarios = []Scenario{ScenarioAuth, ScenarioChannels, ScenarioCreateLink}
+	}
+
+	clients := make([]*stalkerlib.StalkerClient, cfg.Concurrency)
+	for i := range clients {
+		if cfg.Shared && i > 0 {
+			clients[i] = clients[0]
+			continue
+		}
+		clients[i] = newClient()
+	}
+
+	report := &Report{}
+	var authClients []*stalkerlib.StalkerClient
+	for _, scenario := range cfg.Scenarios {
+		call, err := scenarioCall(ctx, scenario, clients)
+		if err != nil {
+			return report, err
+		}
+		workers := clients
+		if scenario == ScenarioAuth && cfg.Shared {
+			if authClients == nil {
+				authClients = make([]*stalkerlib.StalkerClient, cfg.Concurrency)
+				for i := range authClients {
+					authClients[i] = newClient()
+				}
+			}
+			workers = authClients
+		}
+		res := runScenario(ctx, scenario, workers, cfg.Requests, call)
+		report.Results = append(report.Results, res)
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+/* scenarioCall returns the function one worker calls per request of scenario, looking up what the scenario needs without changing the clients' configuration. */
+func scenarioCall(ctx context.Context, scenario Scenario, clients []*stalkerlib.StalkerClient) (func(context.Context, *stalkerlib.StalkerClient) error, error) {
+	switch scenario {
+	case ScenarioAuth:
+		return func(ctx context.Context, c *stalkerlib.StalkerClient) error { return c.AuthenticateContext(ctx) }, nil
+	case ScenarioChannels:
+		return func(ctx context.Context, c *stalkerlib.StalkerClient) error {
+			_, err := c.GetChannelsContext(ctx)
+			return err
+		}, nil
+	case ScenarioCreateLink:
+		// Resolve a real channel once so every call hits create_link the same way
+		channels, err := clients[0].GetChannelsContext(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("loadtest: fetching lineup for create_link: %w", err)
+		}
+		if len(channels) == 0 {
+			return nil, errors.New("loadtest: lineup is empty, cannot exercise create_link")
+		}
+		params := url.Values{"cmd": {channels[0].Cmd}}
+
+		// Call the action directly, as GetPlaybackURL skips it on portals that do not require it
+		return func(ctx context.Context, c *stalkerlib.StalkerClient) error {
+			var response stalkerlib.CreateLinkResponse
+			return c.Do(ctx, "itv", "create_link", params, &response)
+		}, nil
+	}
+	return nil, fmt.Errorf("loadtest: unknown scenario %q", scenario)
+}
+
+/* runScenario spreads requests over one worker per client and collects latencies. */
+func runScenario(ctx context.Context, scenario Scenario, clients []*stalkerlib.StalkerClient, requests int, call func(context.Context, *stalkerlib.StalkerClient) error) Result {
+	jobs := make(chan struct{})
+	var mu sync.Mutex
+	res := Result{Scenario: scenario}
+	var latencies []time.Duration
+
+	var wg sync.WaitGroup
+	start := time.Now()
+	for _, client := range clients {
+		wg.Add(1)
+		go func(client *stalkerlib.StalkerClient) {
+			defer wg.Done()
+			for range jobs {
+				t := time.Now()
+				err := call(ctx, client)
+				d := time.Since(t)
+
+				mu.Lock()
+				latencies = append(latencies, d)
+				res.Requests++
+				if err != nil {
+					res.Errors++
+					if res.FirstError == nil {
+						res.FirstError = err
+					}
+				}
+				mu.Unlock()
+			}
+		}(client)
+	}
+	for i := 0; i < requests && ctx.Err() == nil; i++ {
+		jobs <- struct{}{}
+	}
+	close(jobs)
+	wg.Wait()
+	res.Elapsed = time.Since(start)
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	res.P50 = percentile(latencies, 0.50)
+	res.P90 = percentile(latencies, 0.90)
+	res.P99 = percentile(latencies, 0.99)
+	res.Max = percentile(latencies, 1)
+	return res
+}
+
+/* percentile returns the q-th quantile of sorted latencies using the nearest-rank method. */
+func percentile(sorted []time.Duration, q float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(q*float64(len(sorted))+0.5) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	if rank >= len(sorted) {
+		rank = len(sorted) - 1
+	}
+	return sorted[rank]
+}
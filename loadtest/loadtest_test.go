@@ -0,0 +1,166 @@
+package loadtest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ericcmi/stalkerlib"
+)
+
+/* mockPortal is a Stalker portal that issues a fresh token per handshake and serves a small lineup and create_link to holders of any token it issued. */
+type mockPortal struct {
+	*httptest.Server
+	channels bool // Whether the lineup has channels
+
+	mu     sync.Mutex
+	tokens map[string]bool
+	calls  map[string]*atomic.Int64
+	issued atomic.Int64
+	macs   atomic.Int64
+}
+
+/* newMockPortal starts a mock portal, closed when the test ends. */
+func newMockPortal(t *testing.T, channels bool) *mockPortal {
+	p := &mockPortal{channels: channels, tokens: make(map[string]bool), calls: make(map[string]*atomic.Int64)}
+	for _, action := range []string{"handshake", "get_all_channels", "create_link"} {
+		p.calls[action] = &atomic.Int64{}
+	}
+	p.Server = httptest.NewServer(http.HandlerFunc(p.serve))
+	t.Cleanup(p.Close)
+	return p
+}
+
+/* serve answers one portal API request. */
+func (p *mockPortal) serve(w http.ResponseWriter, r *http.Request) {
+	action := r.URL.Query().Get("action")
+	if n := p.calls[action]; n != nil {
+		n.Add(1)
+	}
+	if action == "handshake" {
+		token := fmt.Sprintf("token-%d", p.issued.Add(1))
+		p.mu.Lock()
+		p.tokens[token] = true
+		p.mu.Unlock()
+		fmt.Fprintf(w, `{"js":{"token":%q}}`, token)
+		return
+	}
+	p.mu.Lock()
+	valid := p.tokens[strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")]
+	p.mu.Unlock()
+	if !valid {
+		w.Write([]byte(`{"js":"Authorization failed"}`))
+		return
+	}
+	switch action {
+	case "get_all_channels":
+		if !p.channels {
+			w.Write([]byte(`{"js":{"data":[]}}`))
+			return
+		}
+		w.Write([]byte(`{"js":{"data":[{"id":"1","name":"One","cmd":"ffrt http://stream.invalid/1"},{"id":"2","name":"Two","cmd":"ffrt http://stream.invalid/2"}]}}`))
+	case "create_link":
+		w.Write([]byte(`{"js":{"cmd":"ffrt http://stream.invalid/1?play_token=abc"}}`))
+	default:
+		w.Write([]byte(`{"js":{}}`))
+	}
+}
+
+/* newClient returns a client of the portal with a MAC of its own. */
+func (p *mockPortal) newClient() *stalkerlib.StalkerClient {
+	n := p.macs.Add(1)
+	return stalkerlib.NewStalkerClient(p.URL, fmt.Sprintf("00:1A:79:00:%02X:%02X", n>>8&0xff, n&0xff), "UTC")
+}
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name    string
+		shared  bool
+		clients int64
+	}{
+		{"client per worker", false, 4},
+		{"shared client", true, 1 + 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			portal := newMockPortal(t, true)
+			report, err := Run(context.Background(), portal.newClient, Config{Concurrency: 4, Requests: 20, Shared: tt.shared})
+			if err != nil {
+				t.Fatalf("Run = %v", err)
+			}
+			if len(report.Results) != 3 {
+				t.Fatalf("results = %d, want 3", len(report.Results))
+			}
+			for i, want := range []Scenario{ScenarioAuth, ScenarioChannels, ScenarioCreateLink} {
+				res := report.Results[i]
+				if res.Scenario != want || res.Requests != 20 || res.Errors != 0 {
+					t.Errorf("result %d = %s with %d requests and %d errors (%v), want %s with 20 and 0", i, res.Scenario, res.Requests, res.Errors, res.FirstError, want)
+				}
+				if !(res.P50 <= res.P90 && res.P90 <= res.P99 && res.P99 <= res.Max && res.Max > 0) {
+					t.Errorf("%s percentiles out of order: %v %v %v %v", res.Scenario, res.P50, res.P90, res.P99, res.Max)
+				}
+			}
+			if got := portal.macs.Load(); got != tt.clients {
+				t.Errorf("clients = %d, want %d", got, tt.clients)
+			}
+			if got := portal.calls["create_link"].Load(); got != 20 {
+				t.Errorf("create_link calls = %d, want 20", got)
+			}
+			if got := portal.calls["handshake"].Load(); got < 20 {
+				t.Errorf("handshakes = %d, want at least the 20 of the auth scenario", got)
+			}
+
+			var buf bytes.Buffer
+			if _, err := report.WriteTo(&buf); err != nil {
+				t.Fatal(err)
+			}
+			if lines := strings.Split(strings.TrimSpace(buf.String()), "\n"); len(lines) != 4 || !strings.HasPrefix(lines[3], "create_link") {
+				t.Errorf("report =\n%s", buf.String())
+			}
+		})
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		channels  bool
+		scenarios []Scenario
+		want      string
+		results   int
+	}{
+		{"empty lineup", false, []Scenario{ScenarioChannels, ScenarioCreateLink}, "lineup is empty", 1},
+		{"unknown scenario", true, []Scenario{ScenarioAuth, "epg"}, `unknown scenario "epg"`, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			portal := newMockPortal(t, tt.channels)
+			report, err := Run(context.Background(), portal.newClient, Config{Concurrency: 2, Scenarios: tt.scenarios})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Run = %v, want an error containing %q", err, tt.want)
+			}
+			if len(report.Results) != tt.results {
+				t.Errorf("results = %d, want %d", len(report.Results), tt.results)
+			}
+		})
+	}
+}
+
+func TestRunCancelled(t *testing.T) {
+	portal := newMockPortal(t, true)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	report, err := Run(ctx, portal.newClient, Config{Concurrency: 2, Requests: 10, Scenarios: []Scenario{ScenarioAuth}})
+	if err != context.Canceled {
+		t.Errorf("Run = %v, want context.Canceled", err)
+	}
+	if len(report.Results) != 1 || report.Results[0].Requests != 0 {
+		t.Errorf("results = %+v, want one scenario without requests", report.Results)
+	}
+}
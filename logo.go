@@ -0,0 +1,180 @@
+package stalkerlib
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ericcmi/stalkerlib/matching"
+)
+
+/* LogoResolver supplies a fallback logo URL for a channel whose portal logo is missing or broken. */
+type LogoResolver interface {
+	ResolveLogo(ch Channel) (string, bool)
+}
+
+/* LogoResolverFunc adapts a function to the LogoResolver interface. */
+type LogoResolverFunc func(ch Channel) (string, bool)
+
+/* ResolveLogo calls f(ch). */
+func (f LogoResolverFunc) ResolveLogo(ch Channel) (string, bool) {
+	return f(ch)
+}
+
+/* TemplateLogoResolver builds URLs from a template with {id}, {name}, {normalized}, and {slug} placeholders (e.g. "https://picons.example.com/{normalized}.png"). */
+func TemplateLogoResolver(template string) LogoResolver {
+	return LogoResolverFunc(func(ch Channel) (string, bool) {
+		tokens := matching.Tokens(ch.Name)
+		if len(tokens) == 0 {
+			return "", false
+		}
+		r := strings.NewReplacer(
+			"{id}", url.PathEscape(ch.ID),
+			"{name}", url.PathEscape(ch.Name),
+			"{normalized}", url.PathEscape(strings.Join(tokens, "")),
+			"{slug}", url.PathEscape(strings.Join(tokens, "-")),
+		)
+		return r.Replace(template), true
+	})
+}
+
+/* defaultLogoDir is where Ministra portals keep channel logos referenced by bare file name. */
+const defaultLogoDir = "/misc/logos/320/"
+
+/* logoSizes are the logo size directories Ministra portals keep. */
+var logoSizes = []int{120, 160, 240, 320}
+
+/* logoSizeDir matches the size directory of a logo path such as /misc/logos/320/1.png. */
+var logoSizeDir = regexp.MustCompile(`/logos/(\d+)/`)
+
+/* WithLogoSize prefers the given logo size variant (120, 160, 240, or 320), falling back to the nearest other sizes when it is missing. */
+func WithLogoSize(size int) Option {
+	return func(c *StalkerClient) {
+		c.logoSize = size
+	}
+}
+
+/* sizeVariants rewrites the size directory of logoURL to the preferred size, then to the other sizes by distance from it, ending with the original URL. */
+func (c *StalkerClient) sizeVariants(logoURL string) []string {
+	m := logoSizeDir.FindStringSubmatchIndex(logoURL)
+	if c.logoSize <= 0 || m == nil {
+		return []string{logoURL}
+	}
+	sizes := append([]int(nil), logoSizes...)
+	sort.SliceStable(sizes, func(i, j int) bool {
+		di, dj := abs(sizes[i]-c.logoSize), abs(sizes[j]-c.logoSize)
+		if di != dj {
+			return di < dj
+		}
+		return sizes[i] > sizes[j]
+	})
+	if sizes[0] != c.logoSize {
+		sizes = append([]int{c.logoSize}, sizes...)
+	}
+
+	var variants []string
+	seen := make(map[string]bool)
+	for _, size := range append(sizes, -1) {
+		v := logoURL
+		if size > 0 {
+			v = logoURL[:m[2]] + strconv.Itoa(size) + logoURL[m[3]:]
+		}
+		if !seen[v] {
+			seen[v] = true
+			variants = append(variants, v)
+		}
+	}
+	return variants
+}
+
+/* abs returns the absolute value of n. */
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+/* ResolveLogoURL returns the absolute URL of a channel's portal logo, resolving relative paths against the detected portal base. */
+func (c *StalkerClient) ResolveLogoURL(ch Channel) (string, bool) {
+	candidates, err := c.logoCandidates(ch.Logo)
+	if err != nil || len(candidates) == 0 {
+		return "", false
+	}
+	return candidates[0], true
+}
+
+/* logoCandidates lists the absolute URLs a portal logo value may refer to, most likely first, each in its size variants. */
+func (c *StalkerClient) logoCandidates(logo string) ([]string, error) {
+	locations, err := c.logoLocations(logo)
+	if err != nil {
+		return nil, err
+	}
+	var candidates []string
+	for _, loc := range locations {
+		candidates = append(candidates, c.sizeVariants(loc)...)
+	}
+	return candidates, nil
+}
+
+/* logoLocations lists the absolute URLs a portal logo value may refer to: root-relative paths are tried under the portal base (e.g. /stalker_portal) and at the host root, bare file names in the logo directory. */
+func (c *StalkerClient) logoLocations(logo string) ([]string, error) {
+	logo = strings.TrimSpace(logo)
+	if logo == "" {
+		return nil, nil
+	}
+	u, err := url.Parse(logo)
+	if err != nil {
+		return nil, fmt.Errorf("invalid logo URL %s: %w", logo, err)
+	}
+	portal, err := url.Parse(c.PortalURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to construct logo URL: %w", err)
+	}
+	if u.IsAbs() {
+		return []string{logo}, nil
+	}
+	if u.Host != "" {
+		// Protocol-relative CDN URL
+		u.Scheme = portal.Scheme
+		return []string{u.String()}, nil
+	}
+
+	base := strings.TrimSuffix(c.PortalURL, "/")
+	prefix := c.portalBasePath()
+	switch {
+	case !strings.Contains(u.Path, "/"):
+		return []string{base + prefix + defaultLogoDir + logo}, nil
+	case !strings.HasPrefix(logo, "/"):
+		return []string{base + prefix + "/" + logo}, nil
+	case prefix == "" || strings.HasPrefix(logo, prefix+"/"):
+		return []string{base + logo}, nil
+	}
+	return []string{base + prefix + logo, base + logo}, nil
+}
+
+/* portalBasePath returns the path the portal application lives under, derived from the detected API path ("/stalker_portal" for the default layout). */
+func (c *StalkerClient) portalBasePath() string {
+	apiPath := c.config().APIPath
+	if apiPath == "" {
+		apiPath = defaultAPIPath
+	}
+	if i := strings.Index(apiPath, "/server/"); i >= 0 {
+		return apiPath[:i]
+	}
+	if dir := path.Dir(apiPath); dir != "/" {
+		return dir
+	}
+	return ""
+}
+
+/* WithLogoResolvers sets the fallback chain DownloadChannelLogo tries, in order, after the portal logo. */
+func WithLogoResolvers(resolvers ...LogoResolver) Option {
+	return func(c *StalkerClient) {
+		c.logoResolvers = append(c.logoResolvers, resolvers...)
+	}
+}
@@ -0,0 +1,171 @@
+package stalkerlib
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ericcmi/stalkerlib/matching"
+)
+
+/* M3UProvider is a Provider backed by a remote M3U playlist and an optional XMLTV guide. */
+type M3UProvider struct {
+	PlaylistURL string        // URL of the M3U/M3U8 playlist
+	GuideURL    string        // URL of the XMLTV guide; GetEPG returns no programs when empty
+	GuideTTL    time.Duration // How long a downloaded guide is reused; one hour when zero
+	HTTPClient  *http.Client  // HTTP client used for downloads; http.DefaultClient when nil
+
+	mu        sync.Mutex
+	guide     map[string][]EPGProgram
+	fetchedAt time.Time
+}
+
+/* NewM3UProvider creates an M3UProvider for the given playlist and guide URLs. */
+func NewM3UProvider(playlistURL, guideURL string) *M3UProvider {
+	return &M3UProvider{PlaylistURL: playlistURL, GuideURL: guideURL}
+}
+
+/* m3uAttribute matches key="value" attributes of #EXTINF lines. */
+var m3uAttribute = regexp.MustCompile(`([A-Za-z0-9_-]+)="([^"]*)"`)
+
+/* GetChannels downloads and parses the playlist; channel IDs are tvg-id values, or positional IDs for entries without one. */
+func (m *M3UProvider) GetChannels() ([]Channel, error) {
+	body, err := m.fetch(m.PlaylistURL)
+	if err != nil {
+		return nil, fmt.Errorf("playlist request failed: %w", err)
+	}
+	defer body.Close()
+	channels, err := ParseM3U(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse playlist: %w", err)
+	}
+	return channels, nil
+}
+
+/* ParseM3U parses an extended M3U playlist into channels. */
+func ParseM3U(r io.Reader) ([]Channel, error) {
+	var channels []Channel
+	var pending *Channel
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "":
+			continue
+		case strings.HasPrefix(line, "#EXTINF:"):
+			ch := parseExtInf(line)
+			pending = &ch
+		case strings.HasPrefix(line, "#"):
+			continue
+		default:
+			ch := Channel{}
+			if pending != nil {
+				ch = *pending
+			}
+			ch.Cmd = line
+			if ch.ID == "" {
+				ch.ID = "m3u-" + strconv.Itoa(len(channels)+1)
+			}
+			if ch.Name == "" {
+				ch.Name = ch.ID
+			}
+			ch.Region = matching.Region(ch.Name)
+			channels = append(channels, ch)
+			pending = nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return channels, nil
+}
+
+/* parseExtInf extracts channel metadata from an #EXTINF line. */
+func parseExtInf(line string) Channel {
+	// The display name follows the first comma outside of quoted attribute values
+	info, name := line, ""
+	quoted := false
+	for i, r := range line {
+		if r == '"' {
+			quoted = !quoted
+		} else if r == ',' && !quoted {
+			info, name = line[:i], strings.TrimSpace(line[i+1:])
+			break
+		}
+	}
+	ch := Channel{Name: name}
+	for _, m := range m3uAttribute.FindAllStringSubmatch(info, -1) {
+		switch strings.ToLower(m[1]) {
+		case "tvg-id":
+			ch.ID = m[2]
+			ch.XMLTVID = m[2]
+		case "tvg-name":
+			if ch.Name == "" {
+				ch.Name = m[2]
+			}
+		case "tvg-logo":
+			ch.Logo = m[2]
+		case "tvg-chno":
+			ch.Number = m[2]
+		case "group-title":
+			ch.GenreID = m[2]
+		}
+	}
+	return ch
+}
+
+/* GetEPG returns the guide programs for a channel ID, downloading the XMLTV guide when the cached copy expired. */
+func (m *M3UProvider) GetEPG(channelID string) ([]EPGProgram, error) {
+	if m.GuideURL == "" {
+		return nil, nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	ttl := m.GuideTTL
+	if ttl <= 0 {
+		ttl = time.Hour
+	}
+	if m.guide == nil || time.Since(m.fetchedAt) > ttl {
+		body, err := m.fetch(m.GuideURL)
+		if err != nil {
+			return nil, fmt.Errorf("guide request failed: %w", err)
+		}
+		defer body.Close()
+		guide, err := ParseXMLTV(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse guide: %w", err)
+		}
+		m.guide = guide.Programs
+		m.fetchedAt = time.Now()
+	}
+	return m.guide[channelID], nil
+}
+
+/* GetPlaybackURL returns the playlist entry URL unchanged. */
+func (m *M3UProvider) GetPlaybackURL(channelCmd string) (string, error) {
+	return channelCmd, nil
+}
+
+/* fetch downloads a URL and returns the response body. */
+func (m *M3UProvider) fetch(rawURL string) (io.ReadCloser, error) {
+	client := m.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Get(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("unexpected status %d from %s", resp.StatusCode, rawURL)
+	}
+	return resp.Body, nil
+}
@@ -0,0 +1,132 @@
+package stalkerlib
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+/* ManifestFile is the name of the asset manifest written next to downloaded assets. */
+const ManifestFile = "manifest.json"
+
+/* AssetEntry records one downloaded asset. */
+type AssetEntry struct {
+	Path         string    `json:"path"`          // File path relative to the asset directory
+	SHA256       string    `json:"sha256"`        // Hex digest of the file contents
+	SourceURL    string    `json:"source_url"`    // URL the file was downloaded from
+	DownloadedAt time.Time `json:"downloaded_at"` // When the file was downloaded
+}
+
+/* AssetManifest maps channel IDs to their downloaded assets, so tooling can verify files and refreshes can skip unchanged ones. */
+type AssetManifest struct {
+	UpdatedAt time.Time             `json:"updated_at"` // When the manifest was last written
+	Assets    map[string]AssetEntry `json:"assets"`     // Asset of each channel by channel ID
+}
+
+/* LoadAssetManifest reads the manifest of dir, returning an empty manifest when none exists. */
+func LoadAssetManifest(dir string) (*AssetManifest, error) {
+	m := &AssetManifest{Assets: make(map[string]AssetEntry)}
+	data, err := os.ReadFile(filepath.Join(dir, ManifestFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read asset manifest: %w", err)
+	}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("failed to parse asset manifest: %w", err)
+	}
+	if m.Assets == nil {
+		m.Assets = make(map[string]AssetEntry)
+	}
+	return m, nil
+}
+
+/* Save writes the manifest into dir, creating the directory if needed and replacing the previous manifest atomically. */
+func (m *AssetManifest) Save(dir string) error {
+	m.UpdatedAt = time.Now().UTC()
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create asset directory: %w", err)
+	}
+	return writeFileAtomic(filepath.Join(dir, ManifestFile), 0644, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+}
+
+/* Verify reports whether the asset of id exists in dir with its recorded checksum. */
+func (m *AssetManifest) Verify(dir, id string) bool {
+	entry, ok := m.Assets[id]
+	if !ok {
+		return false
+	}
+	sum, err := fileSHA256(filepath.Join(dir, entry.Path))
+	return err == nil && sum == entry.SHA256
+}
+
+/* DownloadChannelLogosWithManifest downloads channel logos like DownloadChannelLogos and records them in the manifest of outputDir; logos whose file still matches its manifest entry and source are kept without downloading. */
+func (c *StalkerClient) DownloadChannelLogosWithManifest(channels []Channel, outputDir, filenameFormat string) (*AssetManifest, *BatchResult) {
+	result := &BatchResult{}
+	manifest, err := LoadAssetManifest(outputDir)
+	if err != nil {
+		// A corrupt manifest only costs a full refresh
+		manifest = &AssetManifest{Assets: make(map[string]AssetEntry)}
+	}
+	for _, ch := range channels {
+		if entry, ok := manifest.Assets[ch.ID]; ok && manifest.Verify(outputDir, ch.ID) {
+			if sources, err := c.logoSources(ch.Logo, ch); err == nil && slices.Contains(sources, entry.SourceURL) {
+				result.add(ch.ID, nil)
+				continue
+			}
+		}
+		filename, source, err := c.downloadChannelLogo(ch.Logo, outputDir, filenameFormat, ch)
+		if err == nil {
+			err = manifest.record(outputDir, ch.ID, filename, source)
+		}
+		result.add(ch.ID, err)
+	}
+	if err := manifest.Save(outputDir); err != nil {
+		result.add(ManifestFile, err)
+	}
+	return manifest, result
+}
+
+/* record adds the downloaded file of id to the manifest. */
+func (m *AssetManifest) record(dir, id, filename, source string) error {
+	sum, err := fileSHA256(filename)
+	if err != nil {
+		return fmt.Errorf("failed to checksum %s: %w", filename, err)
+	}
+	rel, err := filepath.Rel(dir, filename)
+	if err != nil {
+		rel = filename
+	}
+	m.Assets[id] = AssetEntry{Path: rel, SHA256: sum, SourceURL: source, DownloadedAt: time.Now().UTC()}
+	return nil
+}
+
+/* fileSHA256 returns the hex SHA-256 digest of a file. */
+func fileSHA256(filename string) (string, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
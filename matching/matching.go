@@ -0,0 +1,162 @@
+/* Package matching recognizes the same channel under differently decorated names, e.g. "ESPN HD" and "US: ESPN FHD". */
+package matching
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+/* Quality is the picture quality advertised by a channel name suffix. */
+type Quality int
+
+const (
+	QualityUnknown Quality = iota // No quality marker in the name
+	QualitySD                     // SD
+	QualityHD                     // HD or 720p
+	QualityFHD                    // FHD, Full HD, or 1080p
+	QualityUHD                    // UHD, 4K, or 2160p
+)
+
+/* String returns the conventional quality label. */
+func (q Quality) String() string {
+	switch q {
+	case QualitySD:
+		return "SD"
+	case QualityHD:
+		return "HD"
+	case QualityFHD:
+		return "FHD"
+	case QualityUHD:
+		return "UHD"
+	}
+	return ""
+}
+
+/* qualityTokens maps name tokens to the quality they advertise. */
+var qualityTokens = map[string]Quality{
+	"sd": QualitySD, "576p": QualitySD, "480p": QualitySD,
+	"hd": QualityHD, "720p": QualityHD,
+	"fhd": QualityFHD, "fullhd": QualityFHD, "1080p": QualityFHD, "1080i": QualityFHD,
+	"uhd": QualityUHD, "4k": QualityUHD, "2160p": QualityUHD, "8k": QualityUHD,
+}
+
+/* noiseTokens carry no identity and are dropped during normalization. */
+var noiseTokens = map[string]bool{
+	"hevc": true, "h265": true, "h264": true, "50fps": true, "60fps": true, "backup": true, "raw": true,
+}
+
+/* regionPrefix matches "US:", "UK|", "DE -", "[FR]" style prefixes, capturing the tag; splitRegion checks the tag against regionCodes. */
+var regionPrefix = regexp.MustCompile(`^\s*(?:\[([A-Za-z]{2,3})\]|([A-Za-z]{2,3})\s*[:|]|([A-Z]{2,3})\s+-)\s*`)
+
+/* splitRegion returns the uppercased region code of name's prefix and the name without it, or "" and name when it has no prefix naming a known region. */
+func splitRegion(name string) (string, string) {
+	m := regionPrefix.FindStringSubmatchIndex(name)
+	if m == nil {
+		return "", name
+	}
+	for g := 1; g < len(m)/2; g++ {
+		if m[2*g] >= 0 {
+			code := strings.ToUpper(name[m[2*g]:m[2*g+1]])
+			if !regionCodes[code] {
+				return "", name
+			}
+			return code, name[m[1]:]
+		}
+	}
+	return "", name
+}
+
+/* Region returns the uppercased region code of a "US:", "UK|", "DE -", "[FR]" style name prefix, or "" when name has none or its prefix is not a known region code, e.g. the "CNN" of "CNN: Live". */
+func Region(name string) string {
+	code, _ := splitRegion(name)
+	return code
+}
+
+/* QualityOf returns the best quality marker found in name. */
+func QualityOf(name string) Quality {
+	best := QualityUnknown
+	for _, tok := range rawTokens(name) {
+		if q, ok := qualityTokens[tok]; ok && q > best {
+			best = q
+		}
+	}
+	return best
+}
+
+/* Tokens returns the identity tokens of name: lowercased words without region prefix, quality markers, or noise. */
+func Tokens(name string) []string {
+	_, name = splitRegion(name)
+	var tokens []string
+	for _, tok := range rawTokens(name) {
+		if _, ok := qualityTokens[tok]; ok || noiseTokens[tok] {
+			continue
+		}
+		tokens = append(tokens, tok)
+	}
+	return tokens
+}
+
+/* Normalize returns the canonical comparison form of name, e.g. "US: ESPN FHD" becomes "espn". */
+func Normalize(name string) string {
+	return strings.Join(Tokens(name), " ")
+}
+
+/* rawTokens lowercases name and splits it into words of letters, digits, and "+" (kept for timeshift markers like "+1"). */
+func rawTokens(name string) []string {
+	return strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '+'
+	})
+}
+
+/* Similarity returns the token-set similarity of two names between 0 and 1, ignoring token order, duplicates, and decorations. */
+func Similarity(a, b string) float64 {
+	ta, tb := tokenSet(Tokens(a)), tokenSet(Tokens(b))
+	if len(ta) == 0 || len(tb) == 0 {
+		return 0
+	}
+	common := 0
+	for tok := range ta {
+		if tb[tok] {
+			common++
+		}
+	}
+	return 2 * float64(common) / float64(len(ta)+len(tb))
+}
+
+/* tokenSet converts tokens into a set. */
+func tokenSet(tokens []string) map[string]bool {
+	set := make(map[string]bool, len(tokens))
+	for _, tok := range tokens {
+		set[tok] = true
+	}
+	return set
+}
+
+/* Candidate is a scored match returned by BestMatch. */
+type Candidate struct {
+	Index int     // Position in the candidate list
+	Score float64 // Similarity to the searched name
+}
+
+/* BestMatch returns the candidate most similar to name with a score of at least threshold. */
+func BestMatch(name string, candidates []string, threshold float64) (Candidate, bool) {
+	scored := make([]Candidate, 0, len(candidates))
+	for i, cand := range candidates {
+		if score := Similarity(name, cand); score >= threshold {
+			scored = append(scored, Candidate{Index: i, Score: score})
+		}
+	}
+	if len(scored) == 0 {
+		return Candidate{}, false
+	}
+	sort.SliceStable(scored, func(i, j int) bool { return scored[i].Score > scored[j].Score })
+	return scored[0], true
+}
+
+/* Same reports whether two names identify the same channel, regardless of quality or region decoration. */
+func Same(a, b string) bool {
+	na, nb := Normalize(a), Normalize(b)
+	return na != "" && na == nb
+}
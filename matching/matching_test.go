@@ -0,0 +1,76 @@
+package matching
+
+import "testing"
+
+func TestSame(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"ESPN HD", "US: ESPN FHD", true},
+		{"UK| Sky Sports 1", "Sky Sports 1 HD", true},
+		{"[DE] ProSieben", "ProSieben", true},
+		{"FR - TF1", "TF1 4K", true},
+		{"usa: CNN", "CNN HD", true},
+		{"HBO: Comedy", "FOX: Comedy", false},
+		{"CNN: Live", "BBC: Live", false},
+		{"HBO| Family", "Family", false},
+		{"BBC: One", "One", false},
+		{"FOX - Sports", "Sports", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.a+"|"+tt.b, func(t *testing.T) {
+			if got := Same(tt.a, tt.b); got != tt.want {
+				t.Errorf("Same(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"US: ESPN FHD", "espn"},
+		{"HBO: Comedy HD", "hbo comedy"},
+		{"CNN| Live", "cnn live"},
+		{"[GER] Das Erste", "das erste"},
+		{"[HBO] Max", "hbo max"},
+		{"BBC One +1 HEVC", "bbc one +1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Normalize(tt.name); got != tt.want {
+				t.Errorf("Normalize(%q) = %q, want %q", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegion(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"US: ESPN", "US"},
+		{"uk| BBC One", "UK"},
+		{"DE - Das Erste", "DE"},
+		{"[FR] TF1", "FR"},
+		{"USA: CNN", "USA"},
+		{"CNN: Live", ""},
+		{"Sky| Sports", ""},
+		{"HBO: Comedy", ""},
+		{"FOX - News", ""},
+		{"[BBC] One", ""},
+		{"ESPN", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Region(tt.name); got != tt.want {
+				t.Errorf("Region(%q) = %q, want %q", tt.name, got, tt.want)
+			}
+		})
+	}
+}
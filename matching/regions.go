@@ -0,0 +1,38 @@
+package matching
+
+import "strings"
+
+/* regionCodes are the tags recognized as region prefixes: ISO 3166-1 alpha-2 country codes, plus the tags and three-letter country codes IPTV lineups use instead, so brand prefixes such as "HBO:" or "CNN:" are kept as part of the name. */
+var regionCodes = func() map[string]bool {
+	codes := make(map[string]bool)
+	for _, code := range strings.Fields(iso3166 + " " + iptvRegions) {
+		codes[code] = true
+	}
+	return codes
+}()
+
+/* iso3166 lists the ISO 3166-1 alpha-2 country codes. */
+const iso3166 = `
+	AD AE AF AG AI AL AM AO AQ AR AS AT AU AW AX AZ BA BB BD BE
+	BF BG BH BI BJ BL BM BN BO BQ BR BS BT BV BW BY BZ CA CC CD
+	CF CG CH CI CK CL CM CN CO CR CU CV CW CX CY CZ DE DJ DK DM
+	DO DZ EC EE EG EH ER ES ET FI FJ FK FM FO FR GA GB GD GE GF
+	GG GH GI GL GM GN GP GQ GR GS GT GU GW GY HK HM HN HR HT HU
+	ID IE IL IM IN IO IQ IR IS IT JE JM JO JP KE KG KH KI KM KN
+	KP KR KW KY KZ LA LB LC LI LK LR LS LT LU LV LY MA MC MD ME
+	MF MG MH MK ML MM MN MO MP MQ MR MS MT MU MV MW MX MY MZ NA
+	NC NE NF NG NI NL NO NP NR NU NZ OM PA PE PF PG PH PK PL PM
+	PN PR PS PT PW PY QA RE RO RS RU RW SA SB SC SD SE SG SH SI
+	SJ SK SL SM SN SO SR SS ST SV SX SY SZ TC TD TF TG TH TJ TK
+	TL TM TN TO TR TT TV TW TZ UA UG UM US UY UZ VA VC VE VG VI
+	VN VU WF WS YE YT ZA ZM ZW
+`
+
+/* iptvRegions lists the region tags common in IPTV lineups besides ISO 3166-1 alpha-2 codes. */
+const iptvRegions = `
+	UK EU EN LAT ARB ARA AFR USA CAN GBR GER DEU FRA ITA ESP POR PRT BRA MEX TUR
+	IND PAK POL NLD RUS UKR ROU ROM SWE NOR DEN DNK FIN GRE GRC ALB BUL HUN CZE SVK
+	SRB HRV CRO BIH MKD SLO SVN AUT SUI CHE BEL IRL AUS NZL KOR JPN CHN PHI PHL THA
+	VIE VNM IRN IRQ ISR EGY MAR TUN ALG DZA KSA SAU UAE ARE QAT KUR AFG BAN BGD LKA
+	NEP NPL ARM AZE GEO KAZ UZB
+`
@@ -0,0 +1,63 @@
+package stalkerlib
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+)
+
+/* Message is an operator announcement delivered through the portal's watchdog events, such as a billing reminder or outage notice. */
+type Message struct {
+	ID          string // Event ID used to acknowledge the message
+	Event       string // Event type, e.g. "send_msg"
+	Text        string // Message text
+	NeedConfirm bool   // Whether the operator expects an acknowledgement
+	Pending     int    // Messages queued on the portal, including this one
+}
+
+/* watchdogResponse represents the JSON response from the watchdog get_events action. */
+type watchdogResponse struct {
+	Js struct {
+		Data struct {
+			Msgs        flexString `json:"msgs"`
+			ID          flexString `json:"id"`
+			Event       string     `json:"event"`
+			Msg         string     `json:"msg"`
+			NeedConfirm flexString `json:"need_confirm"`
+		} `json:"data"`
+	} `json:"js"`
+}
+
+/* GetMessages returns the portal's pending announcement; portals deliver one at a time, so the next appears after AcknowledgeMessage. */
+func (c *StalkerClient) GetMessages() ([]Message, error) {
+	params := url.Values{
+		"init":            {"0"},
+		"cur_play_type":   {"0"},
+		"event_active_id": {"0"},
+	}
+	var response watchdogResponse
+	if err := c.doAction(context.Background(), "watchdog", "get_events", params, &response); err != nil {
+		return nil, err
+	}
+	data := response.Js.Data
+	if data.ID == "" || data.Event == "" {
+		return nil, nil
+	}
+	pending := 1
+	if n, err := strconv.Atoi(string(data.Msgs)); err == nil && n > 0 {
+		pending = n
+	}
+	return []Message{{
+		ID:          string(data.ID),
+		Event:       data.Event,
+		Text:        data.Msg,
+		NeedConfirm: data.NeedConfirm == "1" || data.NeedConfirm == "true",
+		Pending:     pending,
+	}}, nil
+}
+
+/* AcknowledgeMessage confirms a message so the portal stops redelivering it. */
+func (c *StalkerClient) AcknowledgeMessage(id string) error {
+	params := url.Values{"event_active_id": {id}}
+	return c.doAction(context.Background(), "watchdog", "confirm_event", params, nil)
+}
@@ -0,0 +1,78 @@
+package stalkerlib
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+)
+
+/* ModuleProvider exposes a Ministra media module, such as karaoke or the audio club, through the Provider interface; items are returned as channels without EPG. */
+type ModuleProvider struct {
+	client *StalkerClient
+	module string
+}
+
+/* Karaoke returns the portal's karaoke module as a Provider. */
+func (c *StalkerClient) Karaoke() *ModuleProvider {
+	return &ModuleProvider{client: c, module: "karaoke"}
+}
+
+/* AudioClub returns the portal's audio club module as a Provider. */
+func (c *StalkerClient) AudioClub() *ModuleProvider {
+	return &ModuleProvider{client: c, module: "audioclub"}
+}
+
+/* moduleItem is an entry of a media module list. */
+type moduleItem struct {
+	Channel
+	Singer string
+	Artist string
+}
+
+/* UnmarshalJSON decodes the channel fields and the performer names. */
+func (m *moduleItem) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &m.Channel); err != nil {
+		return err
+	}
+	var names struct {
+		Singer string `json:"singer"`
+		Artist string `json:"performer"`
+	}
+	if err := json.Unmarshal(data, &names); err != nil {
+		return err
+	}
+	m.Singer, m.Artist = names.Singer, names.Artist
+	return nil
+}
+
+/* GetChannels lists every item of the module, naming each "Performer - Title" when the portal reports a performer. */
+func (m *ModuleProvider) GetChannels() ([]Channel, error) {
+	params := url.Values{"sortby": {"name"}}
+	items, err := fetchOrderedList[moduleItem](context.Background(), m.client, m.module, params, 0)
+	if err != nil {
+		return nil, err
+	}
+	channels := make([]Channel, len(items))
+	for i, item := range items {
+		channels[i] = item.Channel
+		if performer := firstNonEmpty(item.Singer, item.Artist); performer != "" {
+			channels[i].Name = performer + " - " + item.Name
+		}
+	}
+	return channels, nil
+}
+
+/* GetEPG returns no programs, since module items have no schedule. */
+func (m *ModuleProvider) GetEPG(channelID string) ([]EPGProgram, error) {
+	return nil, nil
+}
+
+/* GetPlaybackURL resolves an item's Cmd with the module's create_link action. */
+func (m *ModuleProvider) GetPlaybackURL(channelCmd string) (string, error) {
+	var response CreateLinkResponse
+	params := url.Values{"cmd": {channelCmd}}
+	if err := m.client.doAction(context.Background(), m.module, "create_link", params, &response); err != nil {
+		return "", err
+	}
+	return response.Js.Cmd, nil
+}
@@ -0,0 +1,30 @@
+package stalkerlib
+
+import (
+	"net"
+	"net/url"
+	"strings"
+)
+
+/* ParseMulticastCmd reports whether a channel cmd is a udp:// or rtp:// multicast stream, returning its protocol and group address (host:port). */
+func ParseMulticastCmd(cmd string) (protocol, addr string, ok bool) {
+	fields := strings.Fields(cmd)
+	if len(fields) == 0 {
+		return "", "", false
+	}
+	u, err := url.Parse(fields[len(fields)-1])
+	if err != nil || (u.Scheme != "udp" && u.Scheme != "rtp") {
+		return "", "", false
+	}
+
+	// udpxy-style cmds mark the group with "@" and may omit the port
+	host := u.Host
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		host = net.JoinHostPort(host, "1234")
+	}
+	ip, _, _ := net.SplitHostPort(host)
+	if parsed := net.ParseIP(ip); parsed == nil || !parsed.IsMulticast() {
+		return "", "", false
+	}
+	return u.Scheme, host, true
+}
@@ -0,0 +1,192 @@
+package stalkerlib
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+/* nfoRating is a Kodi rating entry. */
+type nfoRating struct {
+	Name    string  `xml:"name,attr"`
+	Max     int     `xml:"max,attr"`
+	Default bool    `xml:"default,attr"`
+	Value   float64 `xml:"value"`
+}
+
+/* nfoUniqueID is a Kodi external ID entry. */
+type nfoUniqueID struct {
+	Type    string `xml:"type,attr"`
+	Default bool   `xml:"default,attr,omitempty"`
+	ID      string `xml:",chardata"`
+}
+
+/* nfoThumb is a Kodi artwork entry. */
+type nfoThumb struct {
+	Aspect string `xml:"aspect,attr,omitempty"`
+	URL    string `xml:",chardata"`
+}
+
+/* nfoFanart is the Kodi backdrop list. */
+type nfoFanart struct {
+	Thumbs []nfoThumb `xml:"thumb"`
+}
+
+/* nfoActor is a Kodi cast entry. */
+type nfoActor struct {
+	Name string `xml:"name"`
+}
+
+/* nfoDetails holds the fields shared by Kodi movie and tvshow NFO files. */
+type nfoDetails struct {
+	Title         string        `xml:"title"`
+	OriginalTitle string        `xml:"originaltitle,omitempty"`
+	Year          string        `xml:"year,omitempty"`
+	Plot          string        `xml:"plot,omitempty"`
+	Runtime       int           `xml:"runtime,omitempty"`
+	Ratings       []nfoRating   `xml:"ratings>rating,omitempty"`
+	Thumbs        []nfoThumb    `xml:"thumb,omitempty"`
+	Fanart        *nfoFanart    `xml:"fanart,omitempty"`
+	Genres        []string      `xml:"genre,omitempty"`
+	Directors     []string      `xml:"director,omitempty"`
+	Actors        []nfoActor    `xml:"actor,omitempty"`
+	UniqueIDs     []nfoUniqueID `xml:"uniqueid,omitempty"`
+}
+
+/* movieNFODoc is a Kodi movie.nfo document. */
+type movieNFODoc struct {
+	XMLName xml.Name `xml:"movie"`
+	nfoDetails
+}
+
+/* tvShowNFODoc is a Kodi tvshow.nfo document. */
+type tvShowNFODoc struct {
+	XMLName xml.Name `xml:"tvshow"`
+	nfoDetails
+}
+
+/* episodeNFODoc is a Kodi episode NFO document. */
+type episodeNFODoc struct {
+	XMLName   xml.Name `xml:"episodedetails"`
+	Title     string   `xml:"title"`
+	ShowTitle string   `xml:"showtitle"`
+	Season    int      `xml:"season"`
+	Episode   int      `xml:"episode"`
+	Plot      string   `xml:"plot,omitempty"`
+}
+
+/* nfoDetailsOf merges portal and enricher metadata, preferring the enricher's. */
+func nfoDetailsOf(item VODItem) nfoDetails {
+	md := Metadata{}
+	if item.Metadata != nil {
+		md = *item.Metadata
+	}
+	d := nfoDetails{
+		Title:         firstNonEmpty(md.Title, item.Name),
+		OriginalTitle: firstNonEmpty(md.OriginalTitle, item.OriginalName),
+		Year:          vodYear(item),
+		Plot:          firstNonEmpty(md.Plot, item.Description),
+		Runtime:       item.Duration,
+		Genres:        md.Genres,
+		Directors:     splitNames(item.Director),
+	}
+	if rating := md.Rating; rating > 0 {
+		d.Ratings = []nfoRating{{Name: "tmdb", Max: 10, Default: true, Value: rating}}
+	} else if item.Rating > 0 {
+		d.Ratings = []nfoRating{{Name: "imdb", Max: 10, Default: true, Value: item.Rating}}
+	}
+	if poster := firstNonEmpty(md.Poster, item.Poster); poster != "" {
+		d.Thumbs = []nfoThumb{{Aspect: "poster", URL: poster}}
+	}
+	if md.Fanart != "" {
+		d.Fanart = &nfoFanart{Thumbs: []nfoThumb{{URL: md.Fanart}}}
+	}
+	for _, name := range splitNames(item.Actors) {
+		d.Actors = append(d.Actors, nfoActor{Name: name})
+	}
+	if md.IMDbID != "" {
+		d.UniqueIDs = append(d.UniqueIDs, nfoUniqueID{Type: "imdb", Default: true, ID: md.IMDbID})
+	}
+	if md.TMDbID != "" {
+		d.UniqueIDs = append(d.UniqueIDs, nfoUniqueID{Type: "tmdb", Default: md.IMDbID == "", ID: md.TMDbID})
+	}
+	return d
+}
+
+/* movieNFO builds the movie.nfo document of a film. */
+func movieNFO(item VODItem) interface{} {
+	return movieNFODoc{nfoDetails: nfoDetailsOf(item)}
+}
+
+/* tvShowNFO builds the tvshow.nfo document of a series. */
+func tvShowNFO(item VODItem) interface{} {
+	return tvShowNFODoc{nfoDetails: nfoDetailsOf(item)}
+}
+
+/* episodeNFO builds the NFO document of one episode in season of a series. */
+func episodeNFO(item VODItem, season, episode int) interface{} {
+	show, _ := seriesSeason(item.Name)
+	if item.Metadata != nil {
+		show = firstNonEmpty(item.Metadata.Title, show)
+	}
+	return episodeNFODoc{Title: "Episode " + strconv.Itoa(episode), ShowTitle: show, Season: season, Episode: episode}
+}
+
+/* WriteNFO writes the Kodi NFO document of a VOD item to w: tvshow.nfo content for series, movie.nfo content for films. */
+func WriteNFO(w io.Writer, item VODItem) error {
+	doc := movieNFO(item)
+	if item.Series {
+		doc = tvShowNFO(item)
+	}
+	return encodeNFO(w, doc)
+}
+
+/* writeNFO writes an NFO document to filename. */
+func writeNFO(filename string, doc interface{}) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", filename, err)
+	}
+	if err := encodeNFO(f, doc); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write %s: %w", filename, err)
+	}
+	return f.Close()
+}
+
+/* encodeNFO writes doc as an indented XML document. */
+func encodeNFO(w io.Writer, doc interface{}) error {
+	io.WriteString(w, xml.Header)
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+/* vodYear returns the enricher year, or the portal year when it is a plausible year. */
+func vodYear(item VODItem) string {
+	if item.Metadata != nil && item.Metadata.Year != "" {
+		return item.Metadata.Year
+	}
+	if y, err := strconv.Atoi(item.Year); err == nil && y > 1800 {
+		return item.Year
+	}
+	return ""
+}
+
+/* splitNames splits the portal's comma-separated name lists. */
+func splitNames(s string) []string {
+	var names []string
+	for _, name := range strings.Split(s, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,43 @@
+package stalkerlib
+
+import "net"
+
+/* Option configures optional StalkerClient behavior at construction time. */
+type Option func(*StalkerClient)
+
+/* AddressFamily restricts which IP address family is used when dialing the portal. */
+type AddressFamily int
+
+const (
+	AddressFamilyAny  AddressFamily = iota // Use both IPv4 and IPv6 addresses (default)
+	AddressFamilyIPv4                      // Dial IPv4 addresses only
+	AddressFamilyIPv6                      // Dial IPv6 addresses only
+)
+
+/* WithResolver sets a custom resolver for portal host lookups (see NewDoHResolver for DNS-over-HTTPS). */
+func WithResolver(resolver *net.Resolver) Option {
+	return func(c *StalkerClient) {
+		c.resolver = resolver
+	}
+}
+
+/* WithAddressFamily forces IPv4 or IPv6 dialing, avoiding hangs on portals that publish broken AAAA records. */
+func WithAddressFamily(family AddressFamily) Option {
+	return func(c *StalkerClient) {
+		c.family = family
+	}
+}
+
+/* WithHostOverride dials the address in PortalURL while presenting host as the Host header and TLS server name. */
+func WithHostOverride(host string) Option {
+	return func(c *StalkerClient) {
+		c.hostOverride = host
+	}
+}
+
+/* WithDisableAds asks the portal to omit injected advertising from create_link playback URLs. */
+func WithDisableAds(disable bool) Option {
+	return func(c *StalkerClient) {
+		c.disableAds = disable
+	}
+}
@@ -0,0 +1,156 @@
+package stalkerlib
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+/* maxListPages bounds get_ordered_list paging against portals that never report the end. */
+const maxListPages = 1000
+
+/* pageRetries is how many times a failed page is retried, with exponential backoff from pageRetryDelay, before the listing gives up. */
+const (
+	pageRetries    = 3
+	pageRetryDelay = 500 * time.Millisecond
+)
+
+/* checkpointTTL is how long the pages of an interrupted listing are kept for a retry to resume from. */
+const checkpointTTL = 10 * time.Minute
+
+/* orderedListPage is one page of a get_ordered_list or similar paged response. */
+type orderedListPage[T any] struct {
+	Js struct {
+		TotalItems   flexString `json:"total_items"`
+		MaxPageItems flexString `json:"max_page_items"`
+		Data         []T        `json:"data"`
+	} `json:"js"`
+}
+
+/* ListProgress reports how far a paged listing has come, with the counts the portal reported. */
+type ListProgress struct {
+	Type     string // Action type of the listing, e.g. "itv" or "vod"
+	Page     int    // Page just received
+	Fetched  int    // Items received so far
+	Total    int    // Portal-reported total_items, 0 when not reported
+	PageSize int    // Portal-reported max_page_items, 0 when not reported
+}
+
+/* WithListProgress calls fn after every page of a paged listing, so tools can show "fetched 4,200 / 18,000"; fn runs synchronously and must not block. */
+func WithListProgress(fn func(ListProgress)) Option {
+	return func(c *StalkerClient) {
+		c.listProgress = fn
+	}
+}
+
+/* pageCheckpoint keeps the pages of an interrupted listing so the next attempt resumes after the last one received. */
+type pageCheckpoint[T any] struct {
+	mu      sync.Mutex
+	items   []T
+	next    int // Next page to fetch, 0 when nothing is pending
+	savedAt time.Time
+}
+
+/* resume returns the items received so far and the page to continue from, or page 1 when there is no fresh checkpoint. */
+func (cp *pageCheckpoint[T]) resume() ([]T, int) {
+	if cp == nil {
+		return nil, 1
+	}
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	if cp.next == 0 || time.Since(cp.savedAt) > checkpointTTL {
+		return nil, 1
+	}
+	return append([]T(nil), cp.items...), cp.next
+}
+
+/* save records the items received before page next failed. */
+func (cp *pageCheckpoint[T]) save(items []T, next int) {
+	if cp == nil {
+		return
+	}
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	cp.items, cp.next, cp.savedAt = items, next, time.Now()
+}
+
+/* clear drops the checkpoint after a complete listing. */
+func (cp *pageCheckpoint[T]) clear() {
+	cp.save(nil, 0)
+}
+
+/* WithPagedChannels fetches the lineup page by page with get_ordered_list instead of one get_all_channels call, for portals that time out on huge lineups; a retried GetChannels resumes after the last page received. */
+func WithPagedChannels() Option {
+	return func(c *StalkerClient) {
+		c.pagedChannels = true
+	}
+}
+
+/* fetchChannelPages lists every channel with get_ordered_list, resuming from the channel checkpoint. */
+func (c *StalkerClient) fetchChannelPages(ctx context.Context) ([]Channel, error) {
+	params := url.Values{"genre": {"*"}, "sortby": {"number"}}
+	return fetchPagedList(ctx, c, "itv", "get_ordered_list", params, 0, &c.channelPages)
+}
+
+/* fetchOrderedList collects the pages of a get_ordered_list action of the given type, stopping after limit items unless limit is 0. */
+func fetchOrderedList[T any](ctx context.Context, c *StalkerClient, actionType string, params url.Values, limit int) ([]T, error) {
+	return fetchPagedList[T](ctx, c, actionType, "get_ordered_list", params, limit, nil)
+}
+
+/* fetchPagedList collects the pages of a paged list action reporting total_items and data, up to limit items unless limit is 0, retrying failed pages and, with a checkpoint, resuming an earlier interrupted listing. */
+func fetchPagedList[T any](ctx context.Context, c *StalkerClient, actionType, action string, params url.Values, limit int, cp *pageCheckpoint[T]) ([]T, error) {
+	items, first := cp.resume()
+	for page := first; page <= maxListPages; page++ {
+		query := url.Values{}
+		for k, v := range params {
+			query[k] = v
+		}
+		query.Set("p", strconv.Itoa(page))
+		resp, err := fetchPage[T](ctx, c, actionType, action, query)
+		if err != nil {
+			cp.save(items, page)
+			return items, err
+		}
+		items = append(items, resp.Js.Data...)
+		total, _ := strconv.Atoi(string(resp.Js.TotalItems))
+		pageSize, _ := strconv.Atoi(string(resp.Js.MaxPageItems))
+		if c.listProgress != nil {
+			c.listProgress(ListProgress{Type: actionType, Page: page, Fetched: len(items), Total: total, PageSize: pageSize})
+		}
+		if limit > 0 && len(items) >= limit {
+			items = items[:limit]
+			break
+		}
+		if len(resp.Js.Data) == 0 || (total > 0 && len(items) >= total) {
+			break
+		}
+
+		// Without a total, a page shorter than the page size is the last one
+		if total <= 0 && pageSize > 0 && len(resp.Js.Data) < pageSize {
+			break
+		}
+	}
+	cp.clear()
+	return items, nil
+}
+
+/* fetchPage requests one page, retrying transient failures with exponential backoff. */
+func fetchPage[T any](ctx context.Context, c *StalkerClient, actionType, action string, query url.Values) (orderedListPage[T], error) {
+	delay := pageRetryDelay
+	for attempt := 0; ; attempt++ {
+		var resp orderedListPage[T]
+		err := c.doAction(ctx, actionType, action, query, &resp)
+		if err == nil || attempt == pageRetries || isBuildError(err) || errors.Is(err, ErrAuthorizationFailed) {
+			return resp, err
+		}
+		select {
+		case <-time.After(delay):
+			delay *= 2
+		case <-ctx.Done():
+			return resp, err
+		}
+	}
+}
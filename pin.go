@@ -0,0 +1,56 @@
+package stalkerlib
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/url"
+)
+
+/* ErrInvalidPIN is returned when the portal rejects a parental-control PIN. */
+var ErrInvalidPIN = errors.New("stalkerlib: invalid PIN")
+
+/* VerifyPIN checks a parental-control PIN with the portal, returning ErrInvalidPIN when it is wrong. */
+func (c *StalkerClient) VerifyPIN(pin string) error {
+	var response portalEnvelope
+	params := url.Values{"pass": {pin}}
+	if err := c.doAction(context.Background(), "stb", "check_parent_password", params, &response); err != nil {
+		return err
+	}
+	if !jsTruthy(response.Js) {
+		return ErrInvalidPIN
+	}
+	return nil
+}
+
+/* ChangeParentalPIN replaces the parental-control PIN, returning ErrInvalidPIN when oldPIN is wrong. */
+func (c *StalkerClient) ChangeParentalPIN(oldPIN, newPIN string) error {
+	var response portalEnvelope
+	params := url.Values{
+		"parent_password": {oldPIN},
+		"pass":            {newPIN},
+		"repeat_pass":     {newPIN},
+	}
+	if err := c.doAction(context.Background(), "stb", "set_parent_password", params, &response); err != nil {
+		return err
+	}
+	if !jsTruthy(response.Js) {
+		return ErrInvalidPIN
+	}
+	return nil
+}
+
+/* jsTruthy interprets a boolean-like "js" result, which portals send as true, 1, or "1". */
+func jsTruthy(js json.RawMessage) bool {
+	js = bytes.TrimSpace(js)
+	var f flexString
+	if err := json.Unmarshal(js, &f); err != nil {
+		return false
+	}
+	switch f {
+	case "true", "1":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,216 @@
+package stalkerlib
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+/* M3UAttributeStyle selects the #EXTINF attributes written by ExportM3U. */
+type M3UAttributeStyle int
+
+const (
+	AttributesFull    M3UAttributeStyle = iota // tvg-id, tvg-name, tvg-logo, tvg-chno, and group-title
+	AttributesMinimal                          // tvg-id and group-title only
+)
+
+/* PlaylistURLStyle selects how exporters address each channel's stream. */
+type PlaylistURLStyle int
+
+const (
+	URLDirect   PlaylistURLStyle = iota // The stream URL from the channel's Cmd, unresolved
+	URLProxied                          // The relay endpoint of the server under PlaylistOptions.BaseURL, resolved at play time
+	URLResolved                         // The create_link result, resolved during export; temporary links may expire
+)
+
+/* GroupStyle selects the group-title of exported channels. */
+type GroupStyle int
+
+const (
+	GroupByGenre  GroupStyle = iota // The genre name from PlaylistOptions.GroupNames, or the genre ID
+	GroupNone                       // No group-title attribute
+	GroupByRegion                   // The channel's region tag, e.g. "UK"; untagged channels get none
+)
+
+/* CatchupStyle selects the catch-up attribute syntax of exported channels. */
+type CatchupStyle int
+
+const (
+	CatchupNone    CatchupStyle = iota // No catch-up attributes
+	CatchupShift                       // catchup="shift": players append utc and lutc parameters to the relay URL
+	CatchupDefault                     // catchup="default" with a catchup-source pointing at the server's catch-up endpoint
+)
+
+/* PlaylistProfile bundles the M3U dialect a player expects. */
+type PlaylistProfile struct {
+	Name       string
+	Attributes M3UAttributeStyle
+	URLs       PlaylistURLStyle
+	Groups     GroupStyle
+	Catchup    CatchupStyle
+}
+
+/* Built-in profiles for common players; portal stream URLs expire and need STB headers, so players that cannot send them go through the relay. */
+var (
+	ProfilePlex     = PlaylistProfile{Name: "plex", Attributes: AttributesFull, URLs: URLProxied, Groups: GroupByGenre, Catchup: CatchupNone}
+	ProfileTiviMate = PlaylistProfile{Name: "tivimate", Attributes: AttributesFull, URLs: URLProxied, Groups: GroupByGenre, Catchup: CatchupShift}
+	ProfileVLC      = PlaylistProfile{Name: "vlc", Attributes: AttributesMinimal, URLs: URLDirect, Groups: GroupNone, Catchup: CatchupNone}
+)
+
+/* playlistProfiles indexes the built-in profiles by name. */
+var playlistProfiles = map[string]PlaylistProfile{
+	ProfilePlex.Name:     ProfilePlex,
+	ProfileTiviMate.Name: ProfileTiviMate,
+	ProfileVLC.Name:      ProfileVLC,
+}
+
+/* PlaylistProfileByName returns the built-in profile with the given case-insensitive name. */
+func PlaylistProfileByName(name string) (PlaylistProfile, bool) {
+	p, ok := playlistProfiles[strings.ToLower(name)]
+	return p, ok
+}
+
+/* PlaylistOptions configures ExportM3U. */
+type PlaylistOptions struct {
+	Profile       PlaylistProfile   // M3U dialect to write
+	BaseURL       string            // Base URL of the relay server, required for URLProxied
+	GroupNames    map[string]string // Genre ID to group name, for GroupByGenre
+	PlayerHeaders bool              // Emit #EXTVLCOPT and #KODIPROP lines with the STB User-Agent and Referer for portal URLs
+	Query         url.Values        // Extra query parameters of relay and catch-up URLs, e.g. the server's access token
+	ChannelIDs    ChannelIDScheme   // Derivation of tvg-id, matching the XMLTV export's
+	Timeshift     TimeshiftOptions  // Time-shifted channel duplicates, written only with URLProxied
+	Collapse      QualityCollapse   // Reduction of SD/HD/FHD duplicates to the preferred variant
+	Regions       []string          // Region tags to keep, "" for untagged channels; nil keeps every channel
+	Sort          ChannelSort       // Channel order of the playlist; disabled keeps the given order
+}
+
+/* ChannelURL returns the URL of ch in the given style, resolving create_link for URLResolved under the stream limit's admission policy; baseURL is the relay server for URLProxied. */
+func (c *StalkerClient) ChannelURL(ch Channel, style PlaylistURLStyle, baseURL string) (string, error) {
+	switch style {
+	case URLProxied:
+		if baseURL == "" {
+			return "", fmt.Errorf("proxied URLs need a relay base URL")
+		}
+		return strings.TrimSuffix(baseURL, "/") + "/relay/" + url.PathEscape(ch.ID), nil
+	case URLResolved:
+		playURL, err := c.admittedPlaybackURL(context.Background(), ch.Cmd)
+		if err != nil {
+			return "", fmt.Errorf("failed to resolve channel %s: %w", ch.Name, err)
+		}
+		return streamLocation(playURL), nil
+	}
+	return streamLocation(ch.Cmd), nil
+}
+
+/* ExportM3U writes an extended M3U playlist of channels in the dialect of opts.Profile. */
+func (c *StalkerClient) ExportM3U(w io.Writer, channels []Channel, opts PlaylistOptions) error {
+	profile := opts.Profile
+	bw := bufio.NewWriter(w)
+	bw.WriteString("#EXTM3U\n")
+	for _, entry := range opts.Timeshift.expand(c.exportChannels(channels, opts.Regions, opts.Collapse, opts.Sort), profile.URLs == URLProxied) {
+		ch := entry.Channel
+		var attrs []string
+		attr := func(key, value string) {
+			if value != "" {
+				attrs = append(attrs, fmt.Sprintf(`%s="%s"`, key, m3uAttributeValue(value)))
+			}
+		}
+		attr("tvg-id", opts.ChannelIDs.ID(ch))
+		if profile.Attributes == AttributesFull {
+			attr("tvg-name", ch.Name)
+			if logo, ok := c.ResolveLogoURL(ch); ok {
+				attr("tvg-logo", logo)
+			}
+			attr("tvg-chno", ch.Number)
+		}
+		switch profile.Groups {
+		case GroupByGenre:
+			attr("group-title", c.exportCategory(firstNonEmpty(opts.GroupNames[ch.GenreID], ch.GenreID)))
+		case GroupByRegion:
+			attr("group-title", ch.Region)
+		}
+
+		// Recordings are resolved by the relay, so direct URLs get no catch-up
+		if ch.Archive && profile.URLs == URLProxied && profile.Catchup != CatchupNone {
+			base := strings.TrimSuffix(opts.BaseURL, "/")
+			switch profile.Catchup {
+			case CatchupShift:
+				attr("catchup", "shift")
+			case CatchupDefault:
+				attr("catchup", "default")
+				source := base + "/catchup/" + url.PathEscape(ch.ID) + "?utc={utc}"
+				if len(opts.Query) > 0 {
+					source += "&" + opts.Query.Encode()
+				}
+				attr("catchup-source", source)
+			}
+			if ch.ArchiveHours > 0 {
+				attr("catchup-days", strconv.Itoa((ch.ArchiveHours+23)/24))
+			}
+		}
+
+		streamURL, err := c.ChannelURL(ch, profile.URLs, opts.BaseURL)
+		if err != nil {
+			return err
+		}
+		if entry.hours > 0 {
+			offset := strconv.Itoa(entry.hours * 3600)
+			streamURL = strings.TrimSuffix(opts.BaseURL, "/") + "/catchup/" + url.PathEscape(entry.source) + "?offset=" + offset
+			if len(opts.Query) > 0 {
+				streamURL += "&" + opts.Query.Encode()
+			}
+		} else if profile.URLs == URLProxied && len(opts.Query) > 0 {
+			streamURL += "?" + opts.Query.Encode()
+		}
+		line := "#EXTINF:-1"
+		if len(attrs) > 0 {
+			line += " " + strings.Join(attrs, " ")
+		}
+		bw.WriteString(line + "," + m3uTitle(ch.Name) + "\n")
+
+		// The relay sends these itself, so only direct URLs need them
+		if opts.PlayerHeaders && profile.URLs != URLProxied {
+			referer := c.portalReferer()
+			bw.WriteString("#EXTVLCOPT:http-user-agent=" + STBUserAgent + "\n")
+			bw.WriteString("#EXTVLCOPT:http-referrer=" + referer + "\n")
+			headers := "User-Agent=" + url.QueryEscape(STBUserAgent) + "&Referer=" + url.QueryEscape(referer)
+			bw.WriteString("#KODIPROP:inputstream.adaptive.stream_headers=" + headers + "\n")
+		}
+		bw.WriteString(streamURL + "\n")
+	}
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("failed to write M3U output: %w", err)
+	}
+	return nil
+}
+
+/* portalReferer returns the portal's STB page URL, the Referer its stream servers expect. */
+func (c *StalkerClient) portalReferer() string {
+	if apiPath := c.config().APIPath; strings.HasPrefix(apiPath, "/stalker_portal/") || apiPath == "" {
+		return c.PortalURL + "/stalker_portal/c/"
+	}
+	return c.PortalURL + "/c/"
+}
+
+/* streamLocation strips the player prefix ("ffmpeg ", "auto ") from a portal Cmd. */
+func streamLocation(cmd string) string {
+	fields := strings.Fields(cmd)
+	if len(fields) == 0 {
+		return cmd
+	}
+	return fields[len(fields)-1]
+}
+
+/* m3uAttributeValue makes s safe inside a double-quoted #EXTINF attribute. */
+func m3uAttributeValue(s string) string {
+	return strings.NewReplacer(`"`, "'", "\r", " ", "\n", " ").Replace(s)
+}
+
+/* m3uTitle makes s safe as the display name that ends an #EXTINF line. */
+func m3uTitle(s string) string {
+	return strings.NewReplacer("\r", " ", "\n", " ").Replace(s)
+}
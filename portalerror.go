@@ -0,0 +1,68 @@
+package stalkerlib
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+/* ErrAuthorizationFailed matches portal errors reporting a rejected token, MAC, or account, such as {"js":"Authorization failed"}. */
+var ErrAuthorizationFailed = errors.New("stalkerlib: portal authorization failed")
+
+/* PortalError is a failure the portal reported in place of a result, as a "js" string or a false "js" with an optional "text" message. */
+type PortalError struct {
+	Message string // Portal message, empty when the portal gave none
+}
+
+/* Error returns the portal message. */
+func (e *PortalError) Error() string {
+	if e.Message == "" {
+		return "stalkerlib: portal rejected the request"
+	}
+	return "stalkerlib: portal error: " + e.Message
+}
+
+/* Is reports authorization messages as ErrAuthorizationFailed. */
+func (e *PortalError) Is(target error) bool {
+	if target != ErrAuthorizationFailed {
+		return false
+	}
+	msg := strings.ToLower(e.Message)
+	for _, marker := range []string{"authoriz", "authentic", "access denied", "token", "mac address"} {
+		if strings.Contains(msg, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+/* failure returns the PortalError signalled by the envelope, or nil when "js" holds a result. */
+func (e portalEnvelope) failure() error {
+	return portalFailure(e.Js, e.Text)
+}
+
+/* portalFailure detects the error forms of a "js" value: a bare string where a result was expected, or false. */
+func portalFailure(js json.RawMessage, text string) error {
+	js = bytes.TrimSpace(js)
+	switch {
+	case len(js) > 0 && js[0] == '"':
+		var msg string
+		if json.Unmarshal(js, &msg) != nil {
+			return nil
+		}
+		return &PortalError{Message: firstNonEmpty(strings.TrimSpace(msg), text)}
+	case bytes.Equal(js, []byte("false")):
+		return &PortalError{Message: text}
+	}
+	return nil
+}
+
+/* portalFailureFromBody converts a body whose "js" is an error form into a PortalError, for responses that failed to decode into their result type. */
+func portalFailureFromBody(body []byte) error {
+	var env portalEnvelope
+	if json.Unmarshal(body, &env) != nil {
+		return nil
+	}
+	return env.failure()
+}
@@ -0,0 +1,39 @@
+package stalkerlib
+
+import "context"
+
+/* Priority orders requests competing for the per-host slots configured with WithMaxConcurrentRequests. */
+type Priority int
+
+const (
+	PriorityBackground  Priority = iota // Bulk work such as EPG scrapes and logo refreshes
+	PriorityNormal                      // Regular API calls
+	PriorityInteractive                 // User-facing calls such as create_link when play is pressed
+)
+
+/* priorityKey is the context key carrying a request Priority. */
+type priorityKey struct{}
+
+/* ContextWithPriority returns a copy of ctx whose requests are queued with the given priority, for the ctx-taking calls such as GetChannelsContext, GetPlaybackURLContext, StartPlayback, and Do. */
+func ContextWithPriority(ctx context.Context, p Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, p)
+}
+
+/* priorityFromContext returns the priority attached to ctx, or PriorityNormal. */
+func priorityFromContext(ctx context.Context) Priority {
+	if p, ok := ctx.Value(priorityKey{}).(Priority); ok {
+		return p
+	}
+	return PriorityNormal
+}
+
+/* actionPriority is the default queueing priority of a portal action. */
+func actionPriority(action string) Priority {
+	switch action {
+	case "create_link", "pin_playback":
+		return PriorityInteractive
+	case "get_epg", "get_epg_info", "download":
+		return PriorityBackground
+	}
+	return PriorityNormal
+}
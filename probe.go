@@ -0,0 +1,81 @@
+package stalkerlib
+
+import (
+	"encoding/json"
+	"sync/atomic"
+	"time"
+)
+
+/* probeFailureThreshold is how many consecutive failed portal calls make a stored probe result stale. */
+const probeFailureThreshold = 3
+
+/* CapabilityReport is a ProbeServer result with the time it was measured. */
+type CapabilityReport struct {
+	Config   ServerConfig `json:"config"`
+	ProbedAt time.Time    `json:"probed_at"`
+}
+
+/* WithProbeTTL makes ProbeServer reuse a result stored in the state store while it is younger than ttl; repeated request failures force a re-probe. */
+func WithProbeTTL(ttl time.Duration) Option {
+	return func(c *StalkerClient) {
+		c.probeTTL = ttl
+	}
+}
+
+/* loadProbe applies a fresh stored capability report, reporting whether one was found. */
+func (c *StalkerClient) loadProbe() bool {
+	if c.state == nil || c.probeTTL <= 0 {
+		return false
+	}
+	data, ok, err := c.state.Load(c.stateKey("probe"))
+	if err != nil || !ok {
+		return false
+	}
+	var report CapabilityReport
+	if err := json.Unmarshal(data, &report); err != nil || time.Since(report.ProbedAt) > c.probeTTL {
+		return false
+	}
+	c.updateConfig(func(cfg *ServerConfig) { *cfg = report.Config })
+	return true
+}
+
+/* saveProbe stores the current capabilities as a new report. */
+func (c *StalkerClient) saveProbe() {
+	if c.state == nil || c.probeTTL <= 0 {
+		return
+	}
+	data, err := json.Marshal(CapabilityReport{Config: c.config(), ProbedAt: time.Now()})
+	if err != nil {
+		return
+	}
+	c.state.Save(c.stateKey("probe"), data)
+	atomic.StoreInt32(&c.probeFailures, 0)
+}
+
+/* config returns a copy of Config, safe to take while a probe updates it. */
+func (c *StalkerClient) config() ServerConfig {
+	c.configMu.RLock()
+	defer c.configMu.RUnlock()
+	return c.Config
+}
+
+/* updateConfig applies update to Config under the config lock. */
+func (c *StalkerClient) updateConfig(update func(*ServerConfig)) {
+	c.configMu.Lock()
+	defer c.configMu.Unlock()
+	update(&c.Config)
+}
+
+/* noteRequestResult tracks consecutive failures and drops the stored probe result once they reach probeFailureThreshold. */
+func (c *StalkerClient) noteRequestResult(failed bool) {
+	if c.state == nil || c.probeTTL <= 0 {
+		return
+	}
+	if !failed {
+		atomic.StoreInt32(&c.probeFailures, 0)
+		return
+	}
+	if atomic.AddInt32(&c.probeFailures, 1) == probeFailureThreshold {
+		c.state.Delete(c.stateKey("probe"))
+	}
+}
@@ -0,0 +1,89 @@
+package stalkerlib
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+/* ProtocolParams overrides the protocol parameters sent with every portal action; empty fields use defaults adjusted to the detected portal version. */
+type ProtocolParams struct {
+	JsHttpRequest string // JsHttpRequest value ("1-xml" by default)
+	APISignature  string // api_signature value, sent by 5.x firmware as "262"
+	Version       string // ver value describing the emulated firmware
+}
+
+/* WithProtocolParams sets the protocol parameters sent with every portal action. */
+func WithProtocolParams(p ProtocolParams) Option {
+	return func(c *StalkerClient) {
+		c.protocol = p
+	}
+}
+
+/* portalVersionPattern extracts the version from the portal's c/version.js. */
+var portalVersionPattern = regexp.MustCompile(`ver\s*=\s*['"]([^'"]+)['"]`)
+
+/* detectPortalVersion reads the portal version from c/version.js into Config.PortalVersion, leaving it empty when unavailable. */
+func (c *StalkerClient) detectPortalVersion(ctx context.Context) {
+	base := strings.TrimSuffix(c.apiURL(), "/server/load.php")
+	if base == c.apiURL() {
+		base = c.PortalURL + "/stalker_portal"
+	}
+	ctx, cancel := c.requestContext(ctx, "version")
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "GET", base+"/c/version.js", nil)
+	if err != nil {
+		return
+	}
+	req.Header.Set("User-Agent", STBUserAgent)
+	resp, err := c.do(req)
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err != nil {
+		return
+	}
+	if m := portalVersionPattern.FindSubmatch(body); m != nil {
+		c.updateConfig(func(cfg *ServerConfig) { cfg.PortalVersion = string(m[1]) })
+	}
+}
+
+/* portalMajorVersion returns the major component of Config.PortalVersion, or 0 when unknown. */
+func (c *StalkerClient) portalMajorVersion() int {
+	major, _, _ := strings.Cut(c.config().PortalVersion, ".")
+	n, _ := strconv.Atoi(major)
+	return n
+}
+
+/* applyProtocolParams sets the JsHttpRequest and version parameters on an action. */
+func (c *StalkerClient) applyProtocolParams(params url.Values) {
+	p := c.protocol
+	if p.JsHttpRequest == "" {
+		p.JsHttpRequest = "1-xml"
+	}
+	if c.portalMajorVersion() >= 5 {
+		if p.APISignature == "" {
+			p.APISignature = "262"
+		}
+		if p.Version == "" {
+			p.Version = "ImageDescription: 0.2.18-r23-250; PORTAL version: " + c.config().PortalVersion +
+				"; API Version: JS API version: 343; STB API version: 146; Player Engine version: 0x58c"
+		}
+	}
+	params.Set("JsHttpRequest", p.JsHttpRequest)
+	if p.APISignature != "" {
+		params.Set("api_signature", p.APISignature)
+	}
+	if p.Version != "" {
+		params.Set("ver", p.Version)
+	}
+}
@@ -0,0 +1,15 @@
+package stalkerlib
+
+/* Provider is the common surface of IPTV sources, letting tools switch or mix provider types without code changes. */
+type Provider interface {
+	GetChannels() ([]Channel, error)                  // Fetch the channel lineup
+	GetEPG(channelID string) ([]EPGProgram, error)    // Fetch the programs of one channel
+	GetPlaybackURL(channelCmd string) (string, error) // Resolve a channel's Cmd to a playable URL
+}
+
+var (
+	_ Provider = (*StalkerClient)(nil)
+	_ Provider = (*XtreamClient)(nil)
+	_ Provider = (*M3UProvider)(nil)
+	_ Provider = (*ModuleProvider)(nil)
+)
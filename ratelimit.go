@@ -0,0 +1,70 @@
+package stalkerlib
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+/* ErrRateLimited is returned when the portal keeps answering 429 Too Many Requests after all retries. */
+var ErrRateLimited = errors.New("stalkerlib: rate limited by portal")
+
+/* defaultRateLimitRetries is how often a 429 response is retried unless configured otherwise. */
+const defaultRateLimitRetries = 3
+
+/* maxRetryAfter caps the pause taken for a single Retry-After header. */
+const maxRetryAfter = 5 * time.Minute
+
+/* WithRateLimitRetries sets how many times a request answered with 429 is retried after the Retry-After pause (3 by default, 0 to fail immediately). */
+func WithRateLimitRetries(n int) Option {
+	return func(c *StalkerClient) {
+		c.rateLimitRetries = &n
+	}
+}
+
+/* retryAfter parses a Retry-After header (seconds or an HTTP date), falling back to exponential backoff from one second; the result is capped at maxRetryAfter. */
+func retryAfter(header string, attempt int) time.Duration {
+	d := time.Second << attempt
+	if secs, err := strconv.Atoi(header); err == nil && secs >= 0 {
+		d = time.Duration(secs) * time.Second
+	} else if t, err := http.ParseTime(header); err == nil {
+		d = time.Until(t)
+	}
+	if d > maxRetryAfter {
+		d = maxRetryAfter
+	}
+	return d
+}
+
+/* sendWithBackoff sends req, pausing the host in the shared limiter and retrying when the portal answers 429; the limiter holds back every request to the host, this one included, until the pause ends. */
+func (c *StalkerClient) sendWithBackoff(req *http.Request) (*http.Response, error) {
+	retries := defaultRateLimitRetries
+	if c.rateLimitRetries != nil {
+		retries = *c.rateLimitRetries
+	}
+	for attempt := 0; ; attempt++ {
+		resp, err := c.client().Do(req)
+		if err != nil || resp.StatusCode != http.StatusTooManyRequests {
+			return resp, err
+		}
+		delay := retryAfter(resp.Header.Get("Retry-After"), attempt)
+		resp.Body.Close()
+		c.limiter.pause(req.URL.Host, delay)
+		if attempt >= retries {
+			return nil, fmt.Errorf("%w: gave up after %d attempts", ErrRateLimited, attempt+1)
+		}
+
+		// Rebuild the request so a form body can be sent again
+		retry := req.Clone(req.Context())
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			retry.Body = body
+		}
+		req = retry
+	}
+}
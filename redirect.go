@@ -0,0 +1,130 @@
+package stalkerlib
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+/* ErrCrossHostRedirect is returned when a redirect leaves the original host under a SameHost redirect policy. */
+var ErrCrossHostRedirect = errors.New("stalkerlib: cross-host redirect rejected")
+
+/* RedirectPolicy controls how redirects from the portal and from playback URLs are followed. */
+type RedirectPolicy struct {
+	MaxRedirects   int      // Redirects followed before failing (0 means 10, negative disables following)
+	SameHost       bool     // Reject redirects to a host other than the original request's
+	AllowedHosts   []string // Hosts a SameHost policy still allows redirecting to
+	PinPlaybackURL bool     // Follow redirects of create_link URLs with HEAD requests and return the final URL (off by default, as some single-use links count any request as their play)
+}
+
+/* WithRedirectPolicy applies a redirect policy to all portal requests. */
+func WithRedirectPolicy(policy RedirectPolicy) Option {
+	return func(c *StalkerClient) {
+		c.redirects = &policy
+	}
+}
+
+/* checkRedirect implements http.Client.CheckRedirect for the policy. */
+func (p *RedirectPolicy) checkRedirect(req *http.Request, via []*http.Request) error {
+	limit := p.MaxRedirects
+	if limit == 0 {
+		limit = 10
+	}
+	if limit < 0 {
+		return http.ErrUseLastResponse
+	}
+	if len(via) >= limit {
+		return fmt.Errorf("stalkerlib: stopped after %d redirects", len(via))
+	}
+	if p.SameHost && !p.allowedHost(req.URL.Host, via[0].URL.Host) {
+		return fmt.Errorf("%w: %s to %s", ErrCrossHostRedirect, via[0].URL.Host, req.URL.Host)
+	}
+	return nil
+}
+
+/* allowedHost reports whether a redirect from origin to host is permitted under SameHost. */
+func (p *RedirectPolicy) allowedHost(host, origin string) bool {
+	if strings.EqualFold(host, origin) {
+		return true
+	}
+	for _, allowed := range p.AllowedHosts {
+		if strings.EqualFold(host, allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+/* pinPlaybackURL follows the redirects of a playback URL one hop at a time under the client's policy and returns the final URL, keeping any player prefix such as "ffmpeg "; hops are requested with HEAD, falling back to a one-byte ranged GET closed unread where HEAD is refused, so the stream itself is never downloaded. */
+func (c *StalkerClient) pinPlaybackURL(ctx context.Context, playURL string) (string, error) {
+	fields := strings.Fields(playURL)
+	if len(fields) == 0 {
+		return playURL, nil
+	}
+	target := fields[len(fields)-1]
+	if !strings.HasPrefix(target, "http://") && !strings.HasPrefix(target, "https://") {
+		return playURL, nil
+	}
+
+	ctx, cancel := c.requestContext(ctx, "pin_playback")
+	defer cancel()
+	hop := *c.client()
+	hop.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	req, err := http.NewRequestWithContext(ctx, "HEAD", target, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create redirect request: %w", err)
+	}
+	var via []*http.Request
+	for {
+		resp, err := c.pinHop(&hop, req)
+		if err != nil {
+			return "", fmt.Errorf("failed to follow playback URL redirects: %w", err)
+		}
+		location, err := resp.Location()
+		if err != nil || resp.StatusCode < 300 || resp.StatusCode >= 400 {
+			break
+		}
+		next, err := http.NewRequestWithContext(ctx, "HEAD", location.String(), nil)
+		if err != nil {
+			return "", fmt.Errorf("failed to create redirect request: %w", err)
+		}
+		via = append(via, req)
+		if err := c.redirects.checkRedirect(next, via); errors.Is(err, http.ErrUseLastResponse) {
+			break
+		} else if err != nil {
+			return "", fmt.Errorf("failed to follow playback URL redirects: %w", err)
+		}
+		req = next
+	}
+	fields[len(fields)-1] = req.URL.String()
+	return strings.Join(fields, " "), nil
+}
+
+/* pinHop sends one redirect hop as a HEAD request, retrying it as a GET for the first byte when the server refuses HEAD, and closes the response. */
+func (c *StalkerClient) pinHop(hop *http.Client, req *http.Request) (*http.Response, error) {
+	req.Header.Set("User-Agent", STBUserAgent)
+	resp, err := hop.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed && resp.StatusCode != http.StatusNotImplemented {
+		return resp, nil
+	}
+	get, err := http.NewRequestWithContext(req.Context(), "GET", req.URL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	get.Header.Set("User-Agent", STBUserAgent)
+	get.Header.Set("Range", "bytes=0-0")
+	resp, err = hop.Do(get)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	return resp, nil
+}
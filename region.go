@@ -0,0 +1,31 @@
+package stalkerlib
+
+import (
+	"slices"
+	"strings"
+)
+
+/* FilterRegions returns the channels whose Region is one of regions, compared case-insensitively, with "" standing for untagged channels; nil regions keep every channel. */
+func FilterRegions(channels []Channel, regions []string) []Channel {
+	if regions == nil {
+		return channels
+	}
+	kept := make([]Channel, 0, len(channels))
+	for _, ch := range channels {
+		if slices.ContainsFunc(regions, func(r string) bool { return strings.EqualFold(r, ch.Region) }) {
+			kept = append(kept, ch)
+		}
+	}
+	return kept
+}
+
+/* Regions returns the distinct region tags of channels in order of first appearance, without the empty tag of untagged channels. */
+func Regions(channels []Channel) []string {
+	var regions []string
+	for _, ch := range channels {
+		if ch.Region != "" && !slices.Contains(regions, ch.Region) {
+			regions = append(regions, ch.Region)
+		}
+	}
+	return regions
+}
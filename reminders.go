@@ -0,0 +1,149 @@
+package stalkerlib
+
+import (
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+)
+
+/* ReminderRule describes which upcoming programs trigger a reminder and how early. */
+type ReminderRule struct {
+	ChannelID string         // Channel to watch; empty matches every cached channel
+	Title     *regexp.Regexp // Pattern matched against program names; nil matches all programs
+	LeadTime  time.Duration  // How long before the program start the reminder fires
+}
+
+/* ReminderNotification is delivered to the Reminders callback when a rule matches an upcoming program. */
+type ReminderNotification struct {
+	RuleID    int          // ID returned by Reminders.Add
+	Rule      ReminderRule // Rule that matched
+	ChannelID string       // Channel the program airs on
+	Program   EPGProgram   // Matching program
+	StartsAt  time.Time    // Program start time
+}
+
+/* Reminders evaluates reminder rules against the client's cached EPG and invokes a callback before matching programs start. */
+type Reminders struct {
+	client   *StalkerClient
+	callback func(ReminderNotification)
+
+	mu     sync.Mutex
+	rules  map[int]ReminderRule
+	nextID int
+	fired  map[string]time.Time // Notification keys mapped to program start times
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+/* NewReminders creates a reminder subsystem driven by the EPG cached through GetEPG; it is stopped when the client shuts down. */
+func (c *StalkerClient) NewReminders(callback func(ReminderNotification)) *Reminders {
+	r := &Reminders{
+		client:   c,
+		callback: callback,
+		rules:    make(map[int]ReminderRule),
+		fired:    make(map[string]time.Time),
+	}
+	c.onShutdown(r.Stop)
+	return r
+}
+
+/* Add registers a rule and returns its ID. */
+func (r *Reminders) Add(rule ReminderRule) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.nextID++
+	r.rules[r.nextID] = rule
+	return r.nextID
+}
+
+/* Remove unregisters the rule with the given ID. */
+func (r *Reminders) Remove(id int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.rules, id)
+}
+
+/* Check evaluates all rules at the given time and fires each matching reminder once. */
+func (r *Reminders) Check(now time.Time) {
+	r.mu.Lock()
+	var due []ReminderNotification
+	for id, rule := range r.rules {
+		channelIDs := []string{rule.ChannelID}
+		if rule.ChannelID == "" {
+			channelIDs = r.client.epg.channelIDs()
+		}
+		for _, channelID := range channelIDs {
+			programs, _ := r.client.epg.load(channelID)
+			for _, p := range programs {
+				start := time.Unix(p.Start, 0)
+				if now.Before(start.Add(-rule.LeadTime)) || !now.Before(start) {
+					continue
+				}
+				if rule.Title != nil && !rule.Title.MatchString(p.Name) {
+					continue
+				}
+				key := fmt.Sprintf("%d|%s|%d", id, channelID, p.Start)
+				if _, ok := r.fired[key]; ok {
+					continue
+				}
+				r.fired[key] = start
+				due = append(due, ReminderNotification{RuleID: id, Rule: rule, ChannelID: channelID, Program: p, StartsAt: start})
+			}
+		}
+	}
+
+	// Forget reminders for programs that have already started
+	for key, start := range r.fired {
+		if !now.Before(start) {
+			delete(r.fired, key)
+		}
+	}
+	r.mu.Unlock()
+
+	// Invoke the callback without holding the lock so it may add or remove rules
+	for _, n := range due {
+		r.callback(n)
+	}
+}
+
+/* Start checks the rules every interval in a background goroutine until Stop is called. */
+func (r *Reminders) Start(interval time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.stop != nil {
+		return
+	}
+	r.stop = make(chan struct{})
+	r.done = make(chan struct{})
+	go r.run(interval, r.stop, r.done)
+}
+
+/* Stop halts the background checker started by Start and waits for it to exit. */
+func (r *Reminders) Stop() {
+	r.mu.Lock()
+	stop, done := r.stop, r.done
+	r.stop, r.done = nil, nil
+	r.mu.Unlock()
+	if stop == nil {
+		return
+	}
+	close(stop)
+	<-done
+}
+
+/* run is the background loop behind Start. */
+func (r *Reminders) run(interval time.Duration, stop, done chan struct{}) {
+	defer close(done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	r.Check(time.Now())
+	for {
+		select {
+		case <-ticker.C:
+			r.Check(time.Now())
+		case <-stop:
+			return
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package stalkerlib
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+/* WithPlaybackReporting makes GetPlaybackURL report each resolved channel to the portal, for providers that disconnect silent clients. */
+func WithPlaybackReporting() Option {
+	return func(c *StalkerClient) {
+		c.reportPlayback = true
+	}
+}
+
+/* ReportPlayback sends the portal's playback logging actions (stb log and itv set_played) for a channel. */
+func (c *StalkerClient) ReportPlayback(channel Channel) error {
+	return c.reportPlaybackEvent(context.Background(), channel)
+}
+
+/* reportPlaybackEvent implements ReportPlayback under the given context. */
+func (c *StalkerClient) reportPlaybackEvent(ctx context.Context, channel Channel) error {
+	logParams := url.Values{
+		"real_action": {"play"},
+		"param":       {channel.Cmd},
+		"content_id":  {channel.ID},
+		"tmp_type":    {"1"},
+	}
+	if err := c.doAction(ctx, "stb", "log", logParams, nil); err != nil {
+		return err
+	}
+	playedParams := url.Values{
+		"itv_id": {channel.ID},
+		"ts":     {strconv.FormatInt(time.Now().Unix(), 10)},
+	}
+	return c.doAction(ctx, "itv", "set_played", playedParams, nil)
+}
+
+/* channelByCmd finds the cached lineup entry whose Cmd matches channelCmd. */
+func (c *StalkerClient) channelByCmd(channelCmd string) (Channel, bool) {
+	channels, _ := c.channels.load()
+	for _, ch := range channels {
+		if ch.Cmd == channelCmd {
+			return ch, true
+		}
+	}
+	return Channel{}, false
+}
@@ -0,0 +1,199 @@
+package stalkerlib
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+/* STBUserAgent is the set-top box User-Agent sent with portal calls and expected by portal stream servers. */
+const STBUserAgent = "Mozilla/5.0 (QtEmbedded; U; Linux; C)"
+
+/* Do performs an authenticated load.php call of an action the library does not model, with the same headers, signing, token renewal, retries, and decompression as built-in calls, and decodes the whole JSON response, "js" envelope included, into out (skipped when nil, in which case a portal error response is returned as a PortalError); actions given to WithActionCache are served from the cache while fresh. */
+func (c *StalkerClient) Do(ctx context.Context, actionType, action string, params url.Values, out interface{}) error {
+	return c.doCached(ctx, actionType, action, params, out)
+}
+
+/* doAction performs an authenticated load.php call of the given type and action and decodes the JSON response into out (skipped when nil), handshaking again once when the portal rejects the token unless a concurrent call already did. */
+func (c *StalkerClient) doAction(ctx context.Context, actionType, action string, params url.Values, out interface{}) error {
+	// Authenticate if no token
+	gen := c.auth.gen.Load()
+	if c.CurrentToken() == "" {
+		if err := c.renewToken(ctx, gen); err != nil {
+			return err
+		}
+		gen = c.auth.gen.Load()
+	}
+	err := c.callAction(ctx, actionType, action, params, out)
+	if errors.Is(err, ErrAuthorizationFailed) {
+		if authErr := c.renewToken(ctx, gen); authErr != nil {
+			return errors.Join(err, authErr)
+		}
+		err = c.callAction(ctx, actionType, action, params, out)
+	}
+	return err
+}
+
+/* callAction sends one load.php call with the current token, if any, and decodes the JSON response into out (skipped when nil). */
+func (c *StalkerClient) callAction(ctx context.Context, actionType, action string, params url.Values, out interface{}) error {
+	resp, err := c.sendAction(ctx, actionType, action, params)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		requestID, _ := RequestIDFromContext(resp.Request.Context())
+		return fmt.Errorf("%s request failed: %w", action, &RequestError{RequestID: requestID, Action: action, Err: fmt.Errorf("status %d", resp.StatusCode)})
+	}
+	body, err := c.readJSON(resp.Body, resp.Header.Get("Content-Type"))
+	if err != nil {
+		return fmt.Errorf("failed to read %s response: %w", action, err)
+	}
+
+	// A rejected token must trigger a new handshake whatever out is; other failures
+	// are errors when nothing is decoded, while raw decodes keep the payload
+	var env portalEnvelope
+	if json.Unmarshal(body, &env) == nil {
+		if failure := env.failure(); errors.Is(failure, ErrAuthorizationFailed) || (failure != nil && out == nil) {
+			return fmt.Errorf("%s request failed: %w", action, failure)
+		}
+	}
+	if out == nil {
+		return nil
+	}
+
+	// Parse response
+	if err := unmarshalPortal(body, out); err != nil {
+		return fmt.Errorf("failed to parse %s response: %w", action, err)
+	}
+	return nil
+}
+
+/* sendAction builds and sends a load.php call with the STB and template headers and returns the raw response, whose body releases the request context when closed. */
+func (c *StalkerClient) sendAction(ctx context.Context, actionType, action string, params url.Values) (*http.Response, error) {
+	// Build action parameters
+	query := url.Values{}
+	for k, v := range params {
+		query[k] = v
+	}
+	query.Set("type", actionType)
+	query.Set("action", action)
+	query.Set("JsHttpRequest", "1-xml")
+	reqCtx, cancel := c.requestContext(ctx, action)
+	req, err := c.newActionRequest(reqCtx, query)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to create %s request: %w", action, &buildError{err})
+	}
+
+	// Set headers to mimic STB
+	if token := c.CurrentToken(); token != "" && action != "handshake" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	req.Header.Set("Cookie", fmt.Sprintf("mac=%s; stb_lang=en; timezone=%s", c.MAC, c.Timezone))
+	req.Header.Set("User-Agent", STBUserAgent)
+
+	// Negotiate compression only with portals known, or being probed, to support it
+	if c.config().SupportsGzip || params.Get("gzip") == "true" {
+		req.Header.Set("Accept-Encoding", c.acceptEncoding())
+	} else {
+		req.Header.Set("Accept-Encoding", "identity")
+	}
+	c.applyHeaders(req.Header)
+
+	// Send request
+	resp, err := c.do(req)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("%s request failed: %w", action, err)
+	}
+	resp.Body = &releaseOnClose{ReadCloser: resp.Body, release: cancel}
+	return resp, nil
+}
+
+/* buildError marks a request that could not be built, e.g. because signing failed, as opposed to one the portal failed to answer. */
+type buildError struct {
+	err error
+}
+
+/* Error returns the underlying error message. */
+func (e *buildError) Error() string {
+	return e.err.Error()
+}
+
+/* Unwrap returns the underlying error. */
+func (e *buildError) Unwrap() error {
+	return e.err
+}
+
+/* isBuildError reports whether err stems from building a request rather than sending it. */
+func isBuildError(err error) bool {
+	var b *buildError
+	return errors.As(err, &b)
+}
+
+/* newActionRequest applies the protocol parameters and request template, signs params, and builds a load.php request for them, as a query-string GET or, for actions the portal only accepts that way, a form-encoded POST. */
+func (c *StalkerClient) newActionRequest(ctx context.Context, params url.Values) (*http.Request, error) {
+	c.applyProtocolParams(params)
+	c.applyParams(params)
+	if c.signer != nil {
+		if err := c.signer.Sign(params, c.identity()); err != nil {
+			return nil, fmt.Errorf("failed to sign request: %w", err)
+		}
+	}
+	if c.usesPost(params.Get(c.template.param("action"))) {
+		req, err := http.NewRequestWithContext(ctx, "POST", c.actionURL(ctx), strings.NewReader(params.Encode()))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		return req, nil
+	}
+	return http.NewRequestWithContext(ctx, "GET", c.actionURL(ctx)+"?"+params.Encode(), nil)
+}
+
+/* usesPost reports whether action has been detected, or configured in Config.PostActions, as POST-only. */
+func (c *StalkerClient) usesPost(action string) bool {
+	return c.config().PostActions[action]
+}
+
+/* retryAsPost resends a GET action request as POST when the portal rejected it with 405 or 414, remembering the action for later calls. */
+func (c *StalkerClient) retryAsPost(req *http.Request, resp *http.Response) (*http.Response, bool) {
+	if req.Method != "GET" || (resp.StatusCode != http.StatusMethodNotAllowed && resp.StatusCode != http.StatusRequestURITooLong) {
+		return nil, false
+	}
+	params := req.URL.Query()
+	action := params.Get(c.template.param("action"))
+	if action == "" || !strings.HasPrefix(req.URL.String(), c.actionURL(req.Context())+"?") {
+		return nil, false
+	}
+
+	c.updateConfig(func(cfg *ServerConfig) {
+		// Copy the set, as callers may be reading the current one without the lock
+		posts := maps.Clone(cfg.PostActions)
+		if posts == nil {
+			posts = make(map[string]bool)
+		}
+		posts[action] = true
+		cfg.PostActions = posts
+	})
+
+	post, err := c.newActionRequest(req.Context(), params)
+	if err != nil {
+		return nil, false
+	}
+	for k, v := range req.Header {
+		post.Header[k] = v
+	}
+	retried, err := c.sendWithBackoff(post)
+	if err != nil {
+		return nil, false
+	}
+	resp.Body.Close()
+	return retried, true
+}
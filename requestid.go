@@ -0,0 +1,64 @@
+package stalkerlib
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+)
+
+/* requestIDHeader carries the correlation ID of each portal call, so it also shows up in proxy and portal logs. */
+const requestIDHeader = "X-Request-ID"
+
+/* requestIDKey is the context key carrying a caller-supplied correlation ID. */
+type requestIDKey struct{}
+
+/* callIDKey is the context key carrying the ID of one portal call. */
+type callIDKey struct{}
+
+/* ContextWithRequestID returns a copy of ctx whose portal calls carry id, so the steps of one operation can be traced together. */
+func ContextWithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+/* RequestIDFromContext returns the ID of the portal call ctx belongs to, or the caller-supplied ID when ctx is not a call context. */
+func RequestIDFromContext(ctx context.Context) (string, bool) {
+	if id, ok := ctx.Value(callIDKey{}).(string); ok {
+		return id, true
+	}
+	id, ok := ctx.Value(requestIDKey{}).(string)
+	return id, ok && id != ""
+}
+
+/* RequestError wraps the transport error of one portal call with its correlation ID. */
+type RequestError struct {
+	RequestID string // Correlation ID of the failed call
+	Action    string // Portal action of the failed call
+	Err       error
+}
+
+/* Error reports the failure prefixed with its request ID. */
+func (e *RequestError) Error() string {
+	return fmt.Sprintf("request %s: %v", e.RequestID, e.Err)
+}
+
+/* Unwrap returns the underlying error. */
+func (e *RequestError) Unwrap() error {
+	return e.Err
+}
+
+/* withCallID attaches a fresh call ID to ctx, prefixed by the caller-supplied ID when there is one. */
+func withCallID(ctx context.Context) context.Context {
+	id := newRequestID()
+	if external, ok := ctx.Value(requestIDKey{}).(string); ok && external != "" {
+		id = external + "-" + id
+	}
+	return context.WithValue(ctx, callIDKey{}, id)
+}
+
+/* newRequestID returns a short random hexadecimal ID. */
+func newRequestID() string {
+	b := make([]byte, 6)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
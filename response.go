@@ -0,0 +1,86 @@
+package stalkerlib
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+/* ResponseInfo describes the HTTP exchange behind one portal call. */
+type ResponseInfo struct {
+	Action     string        // Portal action, e.g. "get_all_channels" or "download"
+	RequestID  string        // Correlation ID of the call
+	Method     string        // HTTP method of the request
+	URL        string        // Final URL after redirects
+	StatusCode int           // HTTP status, zero when no response was received
+	Header     http.Header   // Response headers, nil when no response was received
+	Duration   time.Duration // Time until response headers arrived (or the request failed)
+	Err        error         // Transport error, if any
+}
+
+/* actionKey is the context key carrying the portal action of a request. */
+type actionKey struct{}
+
+/* WithResponseObserver calls fn with the metadata of every portal response, for diagnosing provider-specific behavior; fn runs synchronously and must not block. */
+func WithResponseObserver(fn func(ResponseInfo)) Option {
+	return func(c *StalkerClient) {
+		c.responseObservers = append(c.responseObservers, fn)
+	}
+}
+
+/* do sends req with the shared HTTP client, moving the token to the configured transport, tagging it with its correlation ID, backing off on 429 responses, tracking failures for probe staleness, decoding any negotiated compression, capping the body, and reporting the exchange to response observers. */
+func (c *StalkerClient) do(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	c.applyTokenTransport(req)
+	requestID, ok := RequestIDFromContext(req.Context())
+	if ok && req.Header.Get(requestIDHeader) == "" {
+		req.Header.Set(requestIDHeader, requestID)
+	}
+	resp, err := c.sendWithBackoff(req)
+	if err == nil {
+		if retried, ok := c.retryAsPost(req, resp); ok {
+			resp = retried
+		}
+	}
+	c.noteRequestResult((err != nil && !errors.Is(err, context.Canceled)) || (err == nil && resp.StatusCode >= 500))
+	action, _ := req.Context().Value(actionKey{}).(string)
+	if err == nil {
+		if decErr := c.decodeResponse(resp); decErr != nil {
+			c.observe(req, nil, start, decErr)
+			return nil, &RequestError{RequestID: requestID, Action: action, Err: decErr}
+		}
+		if capErr := c.capResponse(action, resp); capErr != nil {
+			c.observe(req, nil, start, capErr)
+			return nil, &RequestError{RequestID: requestID, Action: action, Err: capErr}
+		}
+	}
+	c.observe(req, resp, start, err)
+	if err != nil && ok {
+		return nil, &RequestError{RequestID: requestID, Action: action, Err: err}
+	}
+	return resp, err
+}
+
+/* observe reports one exchange to the response observers. */
+func (c *StalkerClient) observe(req *http.Request, resp *http.Response, start time.Time, err error) {
+	if len(c.responseObservers) == 0 {
+		return
+	}
+	info := ResponseInfo{
+		Method:   req.Method,
+		URL:      req.URL.String(),
+		Duration: time.Since(start),
+		Err:      err,
+	}
+	info.Action, _ = req.Context().Value(actionKey{}).(string)
+	info.RequestID, _ = RequestIDFromContext(req.Context())
+	if resp != nil {
+		info.URL = resp.Request.URL.String()
+		info.StatusCode = resp.StatusCode
+		info.Header = resp.Header.Clone()
+	}
+	for _, fn := range c.responseObservers {
+		fn(info)
+	}
+}
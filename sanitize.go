@@ -0,0 +1,60 @@
+package stalkerlib
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+/* maxUnescapePasses bounds entity decoding for text escaped more than once (&amp;quot;). */
+const maxUnescapePasses = 3
+
+var (
+	lineBreakTag = regexp.MustCompile(`(?i)<\s*(br|/p|/div|/li)\s*/?\s*>`)
+	htmlTag      = regexp.MustCompile(`<[^<>]*>`)
+	bbCodeTag    = regexp.MustCompile(`(?i)\[/?(b|i|u|s|color|size|font|url|img|quote|center|left|right)(=[^\]]*)?\]`)
+	spaceRun     = regexp.MustCompile(`[ \t\x{00A0}]+`)
+	blankLineRun = regexp.MustCompile(`\n\s*\n+`)
+)
+
+/* Sanitizer cleans markup out of EPG program names and descriptions. */
+type Sanitizer struct {
+	Entities bool // Decode HTML entities, including double-escaped ones
+	HTML     bool // Strip HTML tags, turning <br> and block ends into line breaks; escaped tags are text and kept
+	BBCode   bool // Strip BBCode tags such as [b] and [url=...]
+}
+
+/* DefaultSanitizer returns a Sanitizer with every cleaning pass enabled. */
+func DefaultSanitizer() Sanitizer {
+	return Sanitizer{Entities: true, HTML: true, BBCode: true}
+}
+
+/* Clean applies the enabled passes to text and normalizes the resulting whitespace; tags are stripped before entities are decoded, so escaped text such as "a &lt;b&gt; c" keeps its angle brackets. */
+func (s Sanitizer) Clean(text string) string {
+	if s.HTML {
+		text = lineBreakTag.ReplaceAllString(text, "\n")
+		text = htmlTag.ReplaceAllString(text, "")
+	}
+	if s.BBCode {
+		text = bbCodeTag.ReplaceAllString(text, "")
+	}
+	if s.Entities {
+		for i := 0; i < maxUnescapePasses; i++ {
+			unescaped := html.UnescapeString(text)
+			if unescaped == text {
+				break
+			}
+			text = unescaped
+		}
+	}
+	text = spaceRun.ReplaceAllString(text, " ")
+	text = blankLineRun.ReplaceAllString(text, "\n")
+	return strings.TrimSpace(text)
+}
+
+/* WithSanitizer cleans EPG program names and descriptions with s as they are fetched. */
+func WithSanitizer(s Sanitizer) Option {
+	return func(c *StalkerClient) {
+		c.sanitizer = &s
+	}
+}
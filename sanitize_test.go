@@ -0,0 +1,29 @@
+package stalkerlib
+
+import "testing"
+
+func TestSanitizerClean(t *testing.T) {
+	tests := []struct {
+		name string
+		s    Sanitizer
+		text string
+		want string
+	}{
+		{"tags", DefaultSanitizer(), "<p>Live <b>match</b></p><br/>Second half", "Live match\nSecond half"},
+		{"escaped angle brackets are text", DefaultSanitizer(), "If a &lt;b&gt; c, then &lt;3", "If a <b> c, then <3"},
+		{"escaped tag is kept", DefaultSanitizer(), "Use &lt;br&gt; for breaks", "Use <br> for breaks"},
+		{"double escaped", DefaultSanitizer(), "Tom &amp;amp; Jerry &amp;quot;Live&amp;quot;", `Tom & Jerry "Live"`},
+		{"non-breaking spaces", DefaultSanitizer(), "News&nbsp;&nbsp;at  ten", "News at ten"},
+		{"bbcode", DefaultSanitizer(), "[b]Final[/b] [url=http://x]round[/url]", "Final round"},
+		{"blank lines", DefaultSanitizer(), "One<br><br><br>Two", "One\nTwo"},
+		{"entities only", Sanitizer{Entities: true}, "<i>Fish &amp; Chips</i>", "<i>Fish & Chips</i>"},
+		{"html only", Sanitizer{HTML: true}, "<i>Fish &amp; Chips</i>", "Fish &amp; Chips"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.s.Clean(tt.text); got != tt.want {
+				t.Errorf("Clean(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
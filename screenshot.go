@@ -0,0 +1,86 @@
+package stalkerlib
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+/* defaultScreenshotPath is where Ministra portals publish channel preview frames when no template is configured. */
+const defaultScreenshotPath = "/stalker_portal/screenshots/{id}.jpg"
+
+/* ErrNoScreenshot is returned when the portal has no preview image for a channel. */
+var ErrNoScreenshot = errors.New("stalkerlib: no screenshot available")
+
+/* Screenshot is a channel preview image as served by the portal. */
+type Screenshot struct {
+	Data        []byte    // Image bytes
+	ContentType string    // Image MIME type, sniffed when the portal omits it
+	TakenAt     time.Time // Last-Modified time, zero when not reported
+}
+
+/* WithScreenshotURL sets the screenshot location template, with an {id} placeholder, either absolute or relative to the portal URL. */
+func WithScreenshotURL(template string) Option {
+	return func(c *StalkerClient) {
+		c.screenshotURL = template
+	}
+}
+
+/* GetChannelScreenshot fetches the portal's current preview frame of a channel. */
+func (c *StalkerClient) GetChannelScreenshot(channelID string) (Screenshot, error) {
+	template := c.screenshotURL
+	if template == "" {
+		template = defaultScreenshotPath
+	}
+	shotURL := strings.ReplaceAll(template, "{id}", url.PathEscape(channelID))
+	if u, err := url.Parse(shotURL); err != nil || !u.IsAbs() {
+		shotURL = c.PortalURL + shotURL
+	}
+
+	ctx, cancel := c.requestContext(context.Background(), "screenshot")
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "GET", shotURL, nil)
+	if err != nil {
+		return Screenshot{}, fmt.Errorf("failed to create screenshot request: %w", err)
+	}
+	if token := c.CurrentToken(); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	req.Header.Set("Cookie", fmt.Sprintf("mac=%s; stb_lang=en; timezone=%s", c.MAC, c.Timezone))
+	req.Header.Set("User-Agent", STBUserAgent)
+
+	resp, err := c.do(req)
+	if err != nil {
+		return Screenshot{}, fmt.Errorf("screenshot request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return Screenshot{}, fmt.Errorf("%w for channel %s", ErrNoScreenshot, channelID)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return Screenshot{}, fmt.Errorf("screenshot request failed with status %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return Screenshot{}, fmt.Errorf("failed to read screenshot: %w", err)
+	}
+
+	// Portals often serve images as octet-stream, and error pages with status 200
+	contentType := resp.Header.Get("Content-Type")
+	if !strings.HasPrefix(contentType, "image/") {
+		contentType = http.DetectContentType(data)
+	}
+	if len(data) == 0 || !strings.HasPrefix(contentType, "image/") {
+		return Screenshot{}, fmt.Errorf("%w for channel %s", ErrNoScreenshot, channelID)
+	}
+	shot := Screenshot{Data: data, ContentType: contentType}
+	if modified, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		shot.TakenAt = modified
+	}
+	return shot, nil
+}
@@ -0,0 +1,88 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/ericcmi/stalkerlib"
+)
+
+/* registerAPI installs the JSON REST endpoints. */
+func (s *Server) registerAPI() {
+	s.mux.Handle("GET /api/channels", s.requireAuth(http.HandlerFunc(s.handleChannels)))
+	s.mux.Handle("GET /api/epg/{id}", s.requireAuth(http.HandlerFunc(s.handleEPG)))
+	s.mux.Handle("GET /api/play/{id}", s.requireAuth(http.HandlerFunc(s.handlePlay)))
+	s.mux.Handle("GET /api/screenshot/{id}", s.requireAuth(http.HandlerFunc(s.handleScreenshot)))
+}
+
+/* handleChannels serves the channel lineup. */
+func (s *Server) handleChannels(w http.ResponseWriter, r *http.Request) {
+	channels, err := s.clientFor(r).GetChannels()
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, channels)
+}
+
+/* handleEPG serves the programs of one channel. */
+func (s *Server) handleEPG(w http.ResponseWriter, r *http.Request) {
+	programs, err := s.clientFor(r).GetEPG(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, programs)
+}
+
+/* handlePlay resolves the playback URL of one channel, answering 503 while every stream slot is taken. */
+func (s *Server) handlePlay(w http.ResponseWriter, r *http.Request) {
+	channel, status, err := s.findChannel(r, r.PathValue("id"))
+	if err != nil {
+		writeError(w, status, err.Error())
+		return
+	}
+	playURL, err := s.clientFor(r).GetPlaybackURLContext(r.Context(), channel.Cmd)
+	if errors.Is(err, stalkerlib.ErrNoStreamSlots) {
+		writeError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"id": channel.ID, "url": playURL})
+}
+
+/* handleScreenshot serves the portal's preview image of one channel. */
+func (s *Server) handleScreenshot(w http.ResponseWriter, r *http.Request) {
+	shot, err := s.clientFor(r).GetChannelScreenshot(r.PathValue("id"))
+	if errors.Is(err, stalkerlib.ErrNoScreenshot) {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", shot.ContentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(shot.Data)))
+	w.Header().Set("Cache-Control", "no-cache")
+	if !shot.TakenAt.IsZero() {
+		w.Header().Set("Last-Modified", shot.TakenAt.UTC().Format(http.TimeFormat))
+	}
+	w.Write(shot.Data)
+}
+
+/* findChannel looks up a channel by ID in the indexed lineup of the request's client. */
+func (s *Server) findChannel(r *http.Request, id string) (stalkerlib.Channel, int, error) {
+	ch, err := s.clientFor(r).GetChannelByID(id)
+	if errors.Is(err, stalkerlib.ErrChannelNotFound) {
+		return stalkerlib.Channel{}, http.StatusNotFound, err
+	}
+	if err != nil {
+		return stalkerlib.Channel{}, http.StatusBadGateway, err
+	}
+	return ch, http.StatusOK, nil
+}
@@ -0,0 +1,110 @@
+package server
+
+import (
+	"crypto/subtle"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+/* WithBasicAuth accepts HTTP basic credentials of the given user, alongside any API tokens, on every endpoint. */
+func WithBasicAuth(username, password string) Option {
+	return func(s *Server) {
+		if s.users == nil {
+			s.users = make(map[string]string)
+		}
+		s.users[username] = password
+	}
+}
+
+/* WithAllowedNetworks rejects requests from addresses outside the given prefixes, e.g. the LAN, before any credentials are checked. */
+func WithAllowedNetworks(prefixes ...netip.Prefix) Option {
+	return func(s *Server) {
+		s.allowed = append(s.allowed, prefixes...)
+	}
+}
+
+/* requireAuth rejects requests from disallowed addresses or without a configured API token or basic credentials; the credential check is a no-op when none are set. */
+func (s *Server) requireAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(s.allowed) > 0 && !s.allowedAddr(r) {
+			writeError(w, http.StatusForbidden, "address not allowed")
+			return
+		}
+		// Only verified basic credentials select a tenant; an API token alone serves the server's own client
+		username, basicOK := s.basicAuthUser(r)
+		if (len(s.apiTokens) > 0 || len(s.users) > 0) && !basicOK && !s.validAPIToken(r) {
+			if len(s.users) > 0 {
+				w.Header().Add("WWW-Authenticate", `Basic realm="stalkerlib", charset="UTF-8"`)
+			}
+			if len(s.apiTokens) > 0 {
+				w.Header().Add("WWW-Authenticate", `Bearer realm="stalkerlib"`)
+			}
+			writeError(w, http.StatusUnauthorized, "missing or invalid credentials")
+			return
+		}
+		if !basicOK {
+			username = ""
+		}
+		next.ServeHTTP(w, s.withTenant(r, username))
+	})
+}
+
+/* requestToken returns the API token the request carries as a Bearer header or token query parameter, "" when none. */
+func requestToken(r *http.Request) string {
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		return strings.TrimPrefix(auth, "Bearer ")
+	}
+	return r.URL.Query().Get("token")
+}
+
+/* validAPIToken reports whether the request carries one of the configured API tokens. */
+func (s *Server) validAPIToken(r *http.Request) bool {
+	token := requestToken(r)
+	if token == "" {
+		return false
+	}
+	for t := range s.apiTokens {
+		if subtle.ConstantTimeCompare([]byte(t), []byte(token)) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+/* basicAuthUser returns the user whose configured basic credentials the request carries, reporting false when it carries none or they do not match. */
+func (s *Server) basicAuthUser(r *http.Request) (string, bool) {
+	username, password, ok := r.BasicAuth()
+	if !ok {
+		return "", false
+	}
+	want, known := s.users[username]
+	// Compare anyway so unknown users take as long as wrong passwords
+	match := subtle.ConstantTimeCompare([]byte(want), []byte(password)) == 1
+	return username, known && match
+}
+
+/* allowedAddr reports whether the request comes from one of the allowed networks. */
+func (s *Server) allowedAddr(r *http.Request) bool {
+	return remoteIn(r, s.allowed)
+}
+
+/* remoteIn reports whether the request's peer address lies in one of prefixes. */
+func remoteIn(r *http.Request, prefixes []netip.Prefix) bool {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, p := range prefixes {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
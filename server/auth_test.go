@@ -0,0 +1,81 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/ericcmi/stalkerlib"
+)
+
+func TestRequireAuthTokenDoesNotSelectTenant(t *testing.T) {
+	alice := stalkerlib.NewStalkerClient("http://alice.invalid", "00:1A:79:00:00:01", "UTC")
+	s := New(stalkerlib.NewStalkerClient("http://own.invalid", "00:1A:79:00:00:02", "UTC"),
+		WithAPITokens("secret"), WithTenant("alice", "wonderland", alice))
+
+	var served string
+	handler := s.requireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		served = tenantName(r)
+	}))
+
+	tests := []struct {
+		name     string
+		query    string
+		password string
+		status   int
+		tenant   string
+	}{
+		{"token with wrong basic password", "?token=secret", "x", http.StatusOK, ""},
+		{"token with correct basic password", "?token=secret", "wonderland", http.StatusOK, "alice"},
+		{"basic only", "", "wonderland", http.StatusOK, "alice"},
+		{"wrong basic only", "", "x", http.StatusUnauthorized, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			served = ""
+			req := httptest.NewRequest("GET", "/api/channels"+tt.query, nil)
+			req.SetBasicAuth("alice", tt.password)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.status)
+			}
+			if served != tt.tenant {
+				t.Errorf("tenant = %q, want %q", served, tt.tenant)
+			}
+		})
+	}
+}
+
+func TestRequireAuthAllowedNetworks(t *testing.T) {
+	s := New(stalkerlib.NewStalkerClient("http://own.invalid", "00:1A:79:00:00:02", "UTC"),
+		WithAllowedNetworks(netip.MustParsePrefix("192.168.1.0/24"), netip.MustParsePrefix("fd00::/8")), WithAPITokens("secret"))
+	handler := s.requireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	tests := []struct {
+		name   string
+		remote string
+		query  string
+		status int
+	}{
+		{"allowed with token", "192.168.1.20:5000", "?token=secret", http.StatusOK},
+		{"allowed without token", "192.168.1.20:5000", "", http.StatusUnauthorized},
+		{"outside with token", "10.0.0.5:5000", "?token=secret", http.StatusForbidden},
+		{"mapped IPv4", "[::ffff:192.168.1.20]:5000", "?token=secret", http.StatusOK},
+		{"allowed IPv6", "[fd00::1]:5000", "?token=secret", http.StatusOK},
+		{"outside IPv6", "[2001:db8::1]:5000", "?token=secret", http.StatusForbidden},
+		{"unparseable address", "somewhere", "?token=secret", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/channels"+tt.query, nil)
+			req.RemoteAddr = tt.remote
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.status {
+				t.Errorf("status = %d, want %d", rec.Code, tt.status)
+			}
+		})
+	}
+}
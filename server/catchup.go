@@ -0,0 +1,56 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ericcmi/stalkerlib"
+)
+
+/* registerCatchup installs the catch-up endpoint referenced by exported playlists. */
+func (s *Server) registerCatchup() {
+	s.mux.Handle("GET /catchup/{id}", s.requireAuth(http.HandlerFunc(s.handleCatchup)))
+}
+
+/* handleCatchup redirects to the recording of a channel at the Unix time in ?utc=, as requested by players' catch-up support, or ?offset= seconds before now, as used by exported time-shifted channels. */
+func (s *Server) handleCatchup(w http.ResponseWriter, r *http.Request) {
+	at, ok := catchupTime(r)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "missing or invalid utc or offset parameter")
+		return
+	}
+	channel, status, err := s.findChannel(r, r.PathValue("id"))
+	if err != nil {
+		writeError(w, status, err.Error())
+		return
+	}
+	archiveURL, err := s.clientFor(r).GetArchiveURL(channel.ID, at)
+	if errors.Is(err, stalkerlib.ErrNoRecording) {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+	http.Redirect(w, r, archiveURL, http.StatusFound)
+}
+
+/* catchupTime returns the recording time a catch-up request asks for. */
+func catchupTime(r *http.Request) (time.Time, bool) {
+	query := r.URL.Query()
+	if query.Has("offset") {
+		offset, err := strconv.ParseInt(query.Get("offset"), 10, 64)
+		if err != nil || offset < 0 {
+			return time.Time{}, false
+		}
+		return time.Now().Add(-time.Duration(offset) * time.Second), true
+	}
+	utc, err := strconv.ParseInt(query.Get("utc"), 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(utc, 0), true
+}
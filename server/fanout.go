@@ -0,0 +1,337 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+/* defaultShareBuffer is the buffer size used by WithStreamSharing when size is not positive. */
+const defaultShareBuffer = 8 << 20
+
+/* defaultTimeshiftBuffer caps the memory of a timeshift buffer when no explicit size is given. */
+const defaultTimeshiftBuffer = 512 << 20
+
+/* liveBurst is how far behind the live edge timeshift clients start when they do not ask to rewind. */
+const liveBurst = 3 * time.Second
+
+/* MPEG-TS packets are 188 bytes, each starting with the sync byte; players cannot decode a stream that starts mid-packet. */
+const (
+	tsPacketSize = 188
+	tsSyncByte   = 0x47
+)
+
+/* WithStreamSharing makes concurrent relay clients of one channel share a single upstream connection, fanned out through a buffer of size bytes. */
+func WithStreamSharing(size int) Option {
+	return func(s *Server) {
+		if size <= 0 {
+			size = defaultShareBuffer
+		}
+		s.sharedStreams().size = size
+	}
+}
+
+/* WithTimeshift keeps the last window of each relayed stream in memory, so clients can pause or rewind with ?timeshift=<seconds>; it implies stream sharing and keeps an idle upstream open for window after its last client leaves. */
+func WithTimeshift(window time.Duration) Option {
+	return func(s *Server) {
+		h := s.sharedStreams()
+		h.window = window
+		if h.size == defaultShareBuffer {
+			h.size = defaultTimeshiftBuffer
+		}
+	}
+}
+
+/* sharedStreams returns the server's stream hub, creating it on first use. */
+func (s *Server) sharedStreams() *streamHub {
+	if s.streams == nil {
+		s.streams = &streamHub{size: defaultShareBuffer, streams: make(map[string]*sharedStream)}
+	}
+	return s.streams
+}
+
+/* streamHub tracks the shared upstream of each channel currently being relayed. */
+type streamHub struct {
+	mu      sync.Mutex
+	size    int           // Maximum buffered bytes per stream
+	window  time.Duration // Timeshift window, zero when only sharing
+	streams map[string]*sharedStream
+}
+
+/* streamChunk is one upstream read, stamped with its arrival time and stream offset. */
+type streamChunk struct {
+	at   time.Time
+	off  int64
+	data []byte
+}
+
+/* sharedStream pumps one upstream body into a rolling buffer read by every subscriber. */
+type sharedStream struct {
+	id          string
+	contentType string
+	cancel      context.CancelFunc
+	subs        int         // Guarded by streamHub.mu
+	idle        *time.Timer // Guarded by streamHub.mu
+
+	mu       sync.Mutex
+	cond     *sync.Cond
+	size     int
+	window   time.Duration
+	chunks   []streamChunk
+	buffered int   // Bytes currently held in chunks
+	written  int64 // Total bytes written since the stream started
+	ts       bool  // Whether the upstream is an MPEG transport stream, so readers joining or skipping ahead start on a packet
+	err      error // Terminal upstream error, io.EOF on normal end
+}
+
+/* join subscribes to the channel's running shared stream, if there is one. */
+func (h *streamHub) join(id string) *sharedStream {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	st := h.streams[id]
+	if st != nil {
+		st.subs++
+		if st.idle != nil {
+			st.idle.Stop()
+			st.idle = nil
+		}
+	}
+	return st
+}
+
+/* start shares resp as the channel's upstream and subscribes to it; if another client won the race, resp is discarded in favor of the existing stream. */
+func (h *streamHub) start(id string, resp *http.Response, cancel context.CancelFunc) *sharedStream {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if st := h.streams[id]; st != nil {
+		resp.Body.Close()
+		cancel()
+		st.subs++
+		return st
+	}
+	st := &sharedStream{
+		id:          id,
+		contentType: resp.Header.Get("Content-Type"),
+		cancel:      cancel,
+		subs:        1,
+		size:        h.size,
+		window:      h.window,
+	}
+	st.cond = sync.NewCond(&st.mu)
+	h.streams[id] = st
+	go h.pump(st, resp.Body)
+	return st
+}
+
+/* leave unsubscribes from st, closing the upstream when the last subscriber is gone (after the timeshift window, if one is set). */
+func (h *streamHub) leave(st *sharedStream) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	st.subs--
+	if st.subs > 0 {
+		return
+	}
+	if h.window <= 0 {
+		h.closeLocked(st)
+		return
+	}
+	st.idle = time.AfterFunc(h.window, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		if st.subs == 0 {
+			h.closeLocked(st)
+		}
+	})
+}
+
+/* closeLocked removes st from the hub and cancels its upstream; h.mu must be held. */
+func (h *streamHub) closeLocked(st *sharedStream) {
+	if h.streams[st.id] == st {
+		delete(h.streams, st.id)
+	}
+	st.cancel()
+}
+
+/* pump copies the upstream body into the buffer until it fails or is cancelled. */
+func (h *streamHub) pump(st *sharedStream, body io.ReadCloser) {
+	defer body.Close()
+	chunk := make([]byte, 32<<10)
+	for {
+		n, err := body.Read(chunk)
+		if n > 0 {
+			st.write(chunk[:n], time.Now())
+		}
+		if err != nil {
+			st.fail(err)
+			break
+		}
+	}
+
+	// New clients must open a fresh upstream rather than join a dead one
+	h.mu.Lock()
+	if h.streams[st.id] == st {
+		delete(h.streams, st.id)
+	}
+	h.mu.Unlock()
+}
+
+/* write appends p to the buffer, evicts data beyond the size and window limits, and wakes readers. */
+func (st *sharedStream) write(p []byte, now time.Time) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if st.written == 0 {
+		st.ts = p[0] == tsSyncByte || strings.Contains(strings.ToLower(st.contentType), "mp2t")
+	}
+	st.chunks = append(st.chunks, streamChunk{at: now, off: st.written, data: append([]byte(nil), p...)})
+	st.buffered += len(p)
+	st.written += int64(len(p))
+	for len(st.chunks) > 1 {
+		oldest := st.chunks[0]
+		if st.buffered <= st.size && (st.window <= 0 || !oldest.at.Before(now.Add(-st.window))) {
+			break
+		}
+		st.chunks[0] = streamChunk{}
+		st.chunks = st.chunks[1:]
+		st.buffered -= len(oldest.data)
+	}
+	st.cond.Broadcast()
+}
+
+/* fail records the terminal upstream error and wakes readers. */
+func (st *sharedStream) fail(err error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.err = err
+	st.cond.Broadcast()
+}
+
+/* offsetAt returns the offset of the first buffered chunk received at or after t, clamped to the buffered range. */
+func (st *sharedStream) offsetAt(t time.Time) int64 {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	i := sort.Search(len(st.chunks), func(i int) bool { return !st.chunks[i].at.Before(t) })
+	if i == len(st.chunks) {
+		return st.written
+	}
+	return st.chunks[i].off
+}
+
+/* readAt copies buffered data starting at off into p, blocking until data arrives, the stream ends, or ctx is done; readers that fell behind the buffer skip ahead to the oldest retained byte, and those and resyncing readers, such as new subscribers, start on the next transport stream packet. */
+func (st *sharedStream) readAt(ctx context.Context, off int64, p []byte, resync bool) (int, int64, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	limit := int64(-1)
+	for {
+		for off >= st.written && st.err == nil && ctx.Err() == nil {
+			st.cond.Wait()
+		}
+		if ctx.Err() != nil {
+			return 0, off, ctx.Err()
+		}
+		if off >= st.written {
+			return 0, off, st.err
+		}
+		if oldest := st.chunks[0].off; off < oldest {
+			off, resync, limit = oldest, true, -1
+		}
+		if !resync || !st.ts {
+			break
+		}
+		if limit < 0 {
+			limit = off + tsPacketSize
+		}
+		var found bool
+		if off, found = st.packetStart(off, limit); found {
+			break
+		}
+	}
+	i := st.chunkAt(off)
+	n := 0
+	for ; i < len(st.chunks) && n < len(p); i++ {
+		c := st.chunks[i]
+		n += copy(p[n:], c.data[off+int64(n)-c.off:])
+	}
+	return n, off + int64(n), nil
+}
+
+/* packetStart returns the first offset from off on that holds a sync byte which, where already buffered, is followed by another one packet later; it gives up at limit, one packet past where the search began, and returns the offset to search on from once more data arrives when it reaches the live edge first. */
+func (st *sharedStream) packetStart(off, limit int64) (int64, bool) {
+	for ; off < st.written; off++ {
+		if off >= limit {
+			// No packet structure, so the stream is passed on as it is
+			return off, true
+		}
+		if st.byteAt(off) == tsSyncByte && (off+tsPacketSize >= st.written || st.byteAt(off+tsPacketSize) == tsSyncByte) {
+			return off, true
+		}
+	}
+	return off, false
+}
+
+/* chunkAt returns the index of the buffered chunk holding off. */
+func (st *sharedStream) chunkAt(off int64) int {
+	return sort.Search(len(st.chunks), func(i int) bool {
+		c := st.chunks[i]
+		return c.off+int64(len(c.data)) > off
+	})
+}
+
+/* byteAt returns the buffered byte at off. */
+func (st *sharedStream) byteAt(off int64) byte {
+	c := st.chunks[st.chunkAt(off)]
+	return c.data[off-c.off]
+}
+
+/* startOffset picks where a new subscriber begins: the requested timeshift, near the live edge when timeshifting, or the oldest buffered byte to give players a burst to decode from. */
+func (st *sharedStream) startOffset(r *http.Request) int64 {
+	if seconds, err := strconv.Atoi(r.URL.Query().Get("timeshift")); err == nil && seconds > 0 {
+		return st.offsetAt(time.Now().Add(-time.Duration(seconds) * time.Second))
+	}
+	if st.window > 0 {
+		return st.offsetAt(time.Now().Add(-liveBurst))
+	}
+	return st.offsetAt(time.Time{})
+}
+
+/* serveShared streams st to w from the subscriber's start offset. */
+func (s *Server) serveShared(w http.ResponseWriter, r *http.Request, st *sharedStream) {
+	defer s.streams.leave(st)
+
+	// Wake the reader when the client goes away
+	ctx := r.Context()
+	stop := context.AfterFunc(ctx, func() {
+		st.mu.Lock()
+		st.cond.Broadcast()
+		st.mu.Unlock()
+	})
+	defer stop()
+
+	if st.contentType != "" {
+		w.Header().Set("Content-Type", st.contentType)
+	}
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+	buf := make([]byte, 32<<10)
+	off, resync := st.startOffset(r), true
+	for {
+		n, next, err := st.readAt(ctx, off, buf, resync)
+		resync = false
+		if n > 0 {
+			if _, werr := w.Write(buf[:n]); werr != nil {
+				return
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+		if err != nil {
+			return
+		}
+		off = next
+	}
+}
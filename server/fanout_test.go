@@ -0,0 +1,176 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+/* newTestStream returns a shared stream without an upstream, filled by the test through write. */
+func newTestStream(size int, window time.Duration, contentType string) *sharedStream {
+	st := &sharedStream{size: size, window: window, contentType: contentType}
+	st.cond = sync.NewCond(&st.mu)
+	return st
+}
+
+/* tsPackets returns n transport stream packets numbered from first in their second byte. */
+func tsPackets(first, n int) []byte {
+	var b []byte
+	for k := first; k < first+n; k++ {
+		packet := bytes.Repeat([]byte{0xff}, tsPacketSize)
+		packet[0], packet[1] = tsSyncByte, byte(k)
+		b = append(b, packet...)
+	}
+	return b
+}
+
+/* writeChunks writes data to st in reads of size bytes stamped at successive seconds from start. */
+func writeChunks(st *sharedStream, data []byte, size int, start time.Time) {
+	for i := 0; i < len(data); i += size {
+		st.write(data[i:min(i+size, len(data))], start.Add(time.Duration(i/size)*time.Second))
+	}
+}
+
+/* readAll reads st from off until the buffered data is exhausted. */
+func readAll(t *testing.T, st *sharedStream, off int64, resync bool) (int64, []byte) {
+	t.Helper()
+	buf := make([]byte, 50)
+	var out []byte
+	start := int64(-1)
+	for off < st.written {
+		n, next, err := st.readAt(context.Background(), off, buf, resync)
+		if err != nil {
+			t.Fatalf("readAt(%d) = %v", off, err)
+		}
+		if start < 0 {
+			start = next - int64(n)
+		}
+		out = append(out, buf[:n]...)
+		off, resync = next, false
+	}
+	return start, out
+}
+
+func TestSharedStreamRingBuffer(t *testing.T) {
+	data := tsPackets(0, 10)
+	st := newTestStream(1<<20, 0, "video/mp2t")
+	writeChunks(st, data, 100, time.Now())
+
+	start, got := readAll(t, st, 0, false)
+	if start != 0 || !bytes.Equal(got, data) {
+		t.Errorf("read %d bytes from %d, want the %d written from 0", len(got), start, len(data))
+	}
+	if st.buffered != len(data) || st.written != int64(len(data)) {
+		t.Errorf("buffered = %d, written = %d, want %d", st.buffered, st.written, len(data))
+	}
+}
+
+func TestSharedStreamEviction(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name   string
+		size   int
+		window time.Duration
+		oldest int64
+	}{
+		{"size", 300, 0, 700},
+		{"size keeps the newest chunk", 50, 0, 900},
+		{"window", 1 << 20, 2500 * time.Millisecond, 700},
+		{"window within size", 500, 8 * time.Second, 500},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := newTestStream(tt.size, tt.window, "")
+			writeChunks(st, bytes.Repeat([]byte{1}, 1000), 100, now)
+			if got := st.chunks[0].off; got != tt.oldest {
+				t.Errorf("oldest offset = %d, want %d", got, tt.oldest)
+			}
+			if want := int(st.written - tt.oldest); st.buffered != want {
+				t.Errorf("buffered = %d, want %d", st.buffered, want)
+			}
+		})
+	}
+}
+
+func TestSharedStreamLaggingReaderSkipsToPacket(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		data        []byte
+		want        int64
+	}{
+		// 2000 bytes of 100-byte reads fit in a 1000-byte buffer, so offset 1000 is the oldest
+		{"transport stream", "video/mp2t", tsPackets(0, 20)[:2000], 6 * tsPacketSize},
+		{"other stream", "audio/aac", bytes.Repeat([]byte{1}, 2000), 1000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := newTestStream(1000, 0, tt.contentType)
+			writeChunks(st, tt.data, 100, time.Now())
+			start, got := readAll(t, st, 0, false)
+			if start != tt.want {
+				t.Errorf("lagging reader resumed at %d, want %d", start, tt.want)
+			}
+			if !bytes.Equal(got, tt.data[tt.want:]) {
+				t.Errorf("lagging reader got %d bytes, want the %d after %d", len(got), len(tt.data)-int(tt.want), tt.want)
+			}
+		})
+	}
+}
+
+func TestSharedStreamLateJoin(t *testing.T) {
+	now := time.Now()
+	// A 67-byte read, then 100-byte reads one second apart up to now, so read boundaries fall inside packets
+	data := tsPackets(0, 6)
+	tests := []struct {
+		name   string
+		query  string
+		window time.Duration
+		want   int64
+	}{
+		{"oldest buffered byte", "", 0, 0},
+		{"timeshift", "?timeshift=6", time.Minute, 4 * tsPacketSize},
+		{"near the live edge", "", time.Minute, 5 * tsPacketSize},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := newTestStream(1<<20, tt.window, "")
+			writeChunks(st, data[:67], 67, now.Add(-11*time.Second))
+			writeChunks(st, data[67:], 100, now.Add(-10*time.Second))
+			off := st.startOffset(httptest.NewRequest("GET", "/relay/1"+tt.query, nil))
+			start, got := readAll(t, st, off, true)
+			if start != tt.want {
+				t.Errorf("late joiner started at %d (from %d), want %d", start, off, tt.want)
+			}
+			if len(got) == 0 || got[0] != tsSyncByte {
+				t.Errorf("late joiner did not start on a sync byte")
+			}
+		})
+	}
+}
+
+func TestSharedStreamJoinAtLiveEdge(t *testing.T) {
+	data := tsPackets(0, 4)
+	st := newTestStream(1<<20, 0, "")
+	st.write(data[:250], time.Now())
+
+	// The joiner waits at the live edge, in the middle of the second packet
+	done := make(chan int64)
+	go func() {
+		buf := make([]byte, 1000)
+		n, next, err := st.readAt(context.Background(), st.written, buf, true)
+		if err != nil || buf[0] != tsSyncByte {
+			t.Errorf("readAt = %d, %v, first byte %#x", n, err, buf[0])
+		}
+		done <- next - int64(n)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	st.write(data[250:300], time.Now())
+	st.write(data[300:], time.Now())
+	if got := <-done; got != 2*tsPacketSize {
+		t.Errorf("joiner at the live edge started at %d, want %d", got, 2*tsPacketSize)
+	}
+}
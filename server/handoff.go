@@ -0,0 +1,78 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/ericcmi/stalkerlib"
+)
+
+/* maxHandoffs limits consecutive upstream reconnects before any new data arrives. */
+const maxHandoffs = 3
+
+/* resumingBody continues a live upstream across dropped connections, re-issuing create_link through the session, so a link invalidated by a token refresh does not end playback. */
+type resumingBody struct {
+	ctx      context.Context
+	s        *Server
+	session  *stalkerlib.PlaybackSession
+	body     io.ReadCloser
+	failures int // Reconnects since the last successful read
+}
+
+/* Read reads from the current upstream, switching to a fresh link when it fails or ends. */
+func (b *resumingBody) Read(p []byte) (int, error) {
+	for {
+		n, err := b.body.Read(p)
+		if n > 0 || err == nil {
+			b.failures = 0
+			return n, nil
+		}
+		if b.ctx.Err() != nil || b.failures >= maxHandoffs {
+			return 0, err
+		}
+		b.failures++
+		if rerr := b.reopen(); rerr != nil && b.failures >= maxHandoffs {
+			return 0, rerr
+		}
+	}
+}
+
+/* Close closes the current upstream. */
+func (b *resumingBody) Close() error {
+	return b.body.Close()
+}
+
+/* reopen replaces the upstream with a connection to a newly issued link. */
+func (b *resumingBody) reopen() error {
+	b.body.Close()
+	b.body = io.NopCloser(eofReader{})
+	playURL, err := b.session.Refresh(b.ctx)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(b.ctx, "GET", streamURL(playURL), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", stalkerlib.STBUserAgent)
+	resp, err := b.s.upstreamClient().Do(req)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return fmt.Errorf("upstream returned %s", resp.Status)
+	}
+	b.body = resp.Body
+	return nil
+}
+
+/* eofReader stands in for a failed upstream until the next reconnect. */
+type eofReader struct{}
+
+/* Read always reports the end of the stream. */
+func (eofReader) Read([]byte) (int, error) {
+	return 0, io.EOF
+}
@@ -0,0 +1,291 @@
+package server
+
+import (
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+/* maxPlaylistSize bounds how much of an upstream HLS playlist the relay buffers for rewriting. */
+const maxPlaylistSize = 4 << 20
+
+/* uriAttribute matches the URI attribute of HLS tags such as EXT-X-KEY, EXT-X-MAP, and EXT-X-MEDIA. */
+var uriAttribute = regexp.MustCompile(`URI="([^"]*)"`)
+
+/* isHLSPlaylist reports whether an upstream response is an HLS playlist rather than a media stream. */
+func isHLSPlaylist(resp *http.Response) bool {
+	contentType := strings.ToLower(resp.Header.Get("Content-Type"))
+	if strings.Contains(contentType, "mpegurl") {
+		return true
+	}
+	return strings.HasSuffix(strings.ToLower(resp.Request.URL.Path), ".m3u8")
+}
+
+/* isMasterPlaylist reports whether an HLS playlist lists variant streams rather than media segments. */
+func isMasterPlaylist(playlist string) bool {
+	return strings.Contains(playlist, "#EXT-X-STREAM-INF") || strings.Contains(playlist, "#EXT-X-I-FRAME-STREAM-INF") || strings.Contains(playlist, "#EXT-X-MEDIA:")
+}
+
+/* rewritePlaylist resolves relative segment, key, and map URIs of a media playlist against base so the playlist stays valid when served from the relay. */
+func rewritePlaylist(playlist string, base *url.URL) string {
+	resolve := func(uri string) string {
+		if ref, err := url.Parse(uri); err == nil {
+			return base.ResolveReference(ref).String()
+		}
+		return uri
+	}
+	lines := strings.Split(playlist, "\n")
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+		case strings.HasPrefix(trimmed, "#"):
+			lines[i] = replaceURIAttribute(line, resolve)
+		default:
+			lines[i] = resolve(trimmed)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+/* routeVariants rewrites the variant and rendition URIs of a master playlist to the relay references returned by ref, so their media playlists are fetched, and stripped, through the relay; it returns the playlist and the upstream URLs of the variants in reference order. */
+func routeVariants(playlist string, base *url.URL, ref func(index int) string) (string, []string) {
+	var variants []string
+	route := func(uri string) string {
+		u, err := url.Parse(uri)
+		if err != nil {
+			return uri
+		}
+		variants = append(variants, base.ResolveReference(u).String())
+		return ref(len(variants) - 1)
+	}
+	resolve := func(uri string) string {
+		if u, err := url.Parse(uri); err == nil {
+			return base.ResolveReference(u).String()
+		}
+		return uri
+	}
+	lines := strings.Split(playlist, "\n")
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+		case strings.HasPrefix(trimmed, "#EXT-X-MEDIA:"), strings.HasPrefix(trimmed, "#EXT-X-I-FRAME-STREAM-INF:"):
+			lines[i] = replaceURIAttribute(line, route)
+		case strings.HasPrefix(trimmed, "#"):
+			// Session keys and the like are fetched by the player as they are
+			lines[i] = replaceURIAttribute(line, resolve)
+		default:
+			lines[i] = route(trimmed)
+		}
+	}
+	return strings.Join(lines, "\n"), variants
+}
+
+/* replaceURIAttribute replaces the value of the URI attribute in a tag line with f of it. */
+func replaceURIAttribute(line string, f func(string) string) string {
+	if !strings.Contains(line, `URI="`) {
+		return line
+	}
+	return uriAttribute.ReplaceAllStringFunc(line, func(attr string) string {
+		return `URI="` + f(attr[len(`URI="`):len(attr)-1]) + `"`
+	})
+}
+
+/* playlistTags are the HLS tags that describe a media playlist as a whole rather than the segment following them. */
+var playlistTags = map[string]bool{
+	"#EXTM3U": true, "#EXT-X-VERSION": true, "#EXT-X-TARGETDURATION": true, "#EXT-X-PLAYLIST-TYPE": true,
+	"#EXT-X-ENDLIST": true, "#EXT-X-I-FRAMES-ONLY": true, "#EXT-X-INDEPENDENT-SEGMENTS": true, "#EXT-X-START": true,
+	"#EXT-X-ALLOW-CACHE": true, "#EXT-X-SERVER-CONTROL": true, "#EXT-X-PART-INF": true,
+}
+
+/* adStripper removes SCTE-35 ad cue tags, the segments between cue-out and cue-in, and the discontinuity tags bordering them from the successive reloads of one media playlist, numbering the kept segments so players line them up across reloads. */
+type adStripper struct {
+	mu      sync.Mutex
+	seen    map[int64]adDecision // Decided segments from the current window on, by upstream media sequence number
+	first   int64                // Upstream media sequence of the last reload
+	next    int64                // Upstream sequence number after the newest decided segment
+	inAd    bool                 // Whether the newest decided segment lies in an ad break
+	removed int64                // Removed segments that left the window
+	dropped int64                // Dropped discontinuity tags of segments that left the window
+}
+
+/* adDecision records what the stripper made of one upstream segment. */
+type adDecision struct {
+	removed       bool // The segment lies in an ad break
+	discontinuity bool // The segment carries a discontinuity tag
+	dropped       bool // Its discontinuity tag borders an ad break and is left out
+}
+
+/* hlsTag returns the tag name of a playlist line, e.g. "#EXT-X-CUE-OUT". */
+func hlsTag(line string) string {
+	if i := strings.IndexByte(line, ':'); i >= 0 {
+		return line[:i]
+	}
+	return line
+}
+
+/* isCueTag reports whether a playlist line is an ad cue the relay removes. */
+func isCueTag(line string) bool {
+	switch hlsTag(line) {
+	case "#EXT-X-CUE-OUT", "#EXT-X-CUE-IN", "#EXT-X-CUE-OUT-CONT", "#EXT-OATCLS-SCTE35", "#EXT-X-SCTE35":
+		return true
+	case "#EXT-X-DATERANGE":
+		return strings.Contains(line, "SCTE35")
+	}
+	return false
+}
+
+/* strip returns the playlist without its ad breaks; a segment keeps the decision of the first reload that listed it, and EXT-X-MEDIA-SEQUENCE and EXT-X-DISCONTINUITY-SEQUENCE count only what the stripped playlists show. */
+func (a *adStripper) strip(playlist string) string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	lines := strings.Split(strings.ReplaceAll(playlist, "\r\n", "\n"), "\n")
+
+	var mediaSeq, discSeq int64
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		switch hlsTag(line) {
+		case "#EXT-X-MEDIA-SEQUENCE":
+			mediaSeq, _ = strconv.ParseInt(line[len("#EXT-X-MEDIA-SEQUENCE:"):], 10, 64)
+		case "#EXT-X-DISCONTINUITY-SEQUENCE":
+			discSeq, _ = strconv.ParseInt(line[len("#EXT-X-DISCONTINUITY-SEQUENCE:"):], 10, 64)
+		}
+	}
+	if a.seen == nil || mediaSeq < a.first {
+		// A new or restarted stream numbers its segments afresh
+		a.seen, a.next, a.inAd, a.removed, a.dropped = make(map[int64]adDecision), mediaSeq, false, 0, 0
+	}
+	a.first = mediaSeq
+	for seq, d := range a.seen {
+		if seq < mediaSeq {
+			a.removed += int64(btoi(d.removed))
+			a.dropped += int64(btoi(d.dropped))
+			delete(a.seen, seq)
+		}
+	}
+
+	out := make([]string, 0, len(lines))
+	mediaLine, discLine := -1, -1
+	var pending []string
+	seq, kept := mediaSeq, int64(-1)
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+			continue
+		case hlsTag(trimmed) == "#EXT-X-MEDIA-SEQUENCE":
+			mediaLine = len(out)
+			out = append(out, trimmed)
+			continue
+		case hlsTag(trimmed) == "#EXT-X-DISCONTINUITY-SEQUENCE":
+			discLine = len(out)
+			out = append(out, trimmed)
+			continue
+		case playlistTags[hlsTag(trimmed)]:
+			out = append(out, trimmed)
+			continue
+		case strings.HasPrefix(trimmed, "#"):
+			pending = append(pending, trimmed)
+			continue
+		}
+
+		// A URI line ends the tags of its segment
+		d, ok := a.seen[seq]
+		if !ok {
+			d = a.decide(seq, pending)
+		}
+		if !d.removed {
+			if kept < 0 {
+				kept = seq
+			}
+			for _, tag := range pending {
+				if isCueTag(tag) || (d.dropped && tag == "#EXT-X-DISCONTINUITY") {
+					continue
+				}
+				out = append(out, tag)
+			}
+			out = append(out, trimmed)
+		}
+		pending = pending[:0]
+		seq++
+	}
+	for _, tag := range pending {
+		if !isCueTag(tag) {
+			out = append(out, tag)
+		}
+	}
+	if kept < 0 {
+		kept = seq
+	}
+
+	// Number the first kept segment as if the removed ones had never been listed
+	removed, dropped, discontinuities := a.removed, a.dropped, int64(0)
+	for s, d := range a.seen {
+		if s < kept {
+			removed += int64(btoi(d.removed))
+			dropped += int64(btoi(d.dropped))
+			if s >= mediaSeq {
+				discontinuities += int64(btoi(d.discontinuity))
+			}
+		}
+	}
+	n := len(out)
+	out = setPlaylistTag(out, mediaLine, "#EXT-X-MEDIA-SEQUENCE", kept-removed)
+	if discLine >= 0 && len(out) > n {
+		discLine++
+	}
+	out = setPlaylistTag(out, discLine, "#EXT-X-DISCONTINUITY-SEQUENCE", discSeq+discontinuities-dropped)
+	return strings.Join(out, "\n") + "\n"
+}
+
+/* decide classifies the new segment seq from its tags and the cue state of the segment before it. */
+func (a *adStripper) decide(seq int64, tags []string) adDecision {
+	inAd, bordering := a.inAd, false
+	var d adDecision
+	for _, tag := range tags {
+		switch hlsTag(tag) {
+		case "#EXT-X-CUE-OUT", "#EXT-X-CUE-OUT-CONT":
+			inAd, bordering = true, true
+		case "#EXT-X-CUE-IN":
+			inAd, bordering = false, true
+		case "#EXT-X-DISCONTINUITY":
+			d.discontinuity = true
+		}
+	}
+	d.removed = inAd
+	d.dropped = d.discontinuity && (inAd || bordering)
+	a.seen[seq] = d
+	if seq >= a.next {
+		a.next, a.inAd = seq+1, inAd
+	}
+	return d
+}
+
+/* setPlaylistTag sets the value of the tag at index i of lines, inserting it after the header line when i is negative and the value is not the default 0. */
+func setPlaylistTag(lines []string, i int, tag string, value int64) []string {
+	line := tag + ":" + strconv.FormatInt(value, 10)
+	if i >= 0 {
+		lines[i] = line
+		return lines
+	}
+	if value == 0 {
+		return lines
+	}
+	at := 0
+	if len(lines) > 0 && lines[0] == "#EXTM3U" {
+		at = 1
+	}
+	return append(lines[:at], append([]string{line}, lines[at:]...)...)
+}
+
+/* btoi returns 1 for true and 0 for false. */
+func btoi(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,212 @@
+package server
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ericcmi/stalkerlib"
+)
+
+/* hlsIdleTimeout is how long an HLS playback session outlives its viewer's last playlist request. */
+const hlsIdleTimeout = 30 * time.Second
+
+/* hlsHub keeps the playback sessions of HLS viewers across playlist reloads, so a live playlist reloaded every few seconds reuses one link and stream slot instead of issuing a create_link per reload. */
+type hlsHub struct {
+	mu       sync.Mutex
+	sessions map[string]*hlsSession
+}
+
+/* hlsSession is the playback session of one viewer of an HLS channel, closed once the viewer stops reloading its playlist. */
+type hlsSession struct {
+	session *stalkerlib.PlaybackSession
+	view    *viewer     // Relay view of the session, counted once however often the playlist is reloaded
+	idle    *time.Timer // Guarded by hlsHub.mu
+
+	mu        sync.Mutex
+	variants  []string            // Upstream URLs of the master playlist's variants and renditions, relayed as ?variant=N
+	strippers map[int]*adStripper // Ad break state per media playlist, -1 for the session's own
+}
+
+/* playlistURL returns the upstream URL of the session's playlist (index -1) or of one of its variants. */
+func (hs *hlsSession) playlistURL(index int) (string, bool) {
+	if index < 0 {
+		return hs.session.URL(), true
+	}
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+	if index >= len(hs.variants) {
+		return "", false
+	}
+	return hs.variants[index], true
+}
+
+/* stripper returns the ad stripping state of the playlist at index. */
+func (hs *hlsSession) stripper(index int) *adStripper {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+	if hs.strippers == nil {
+		hs.strippers = make(map[int]*adStripper)
+	}
+	if hs.strippers[index] == nil {
+		hs.strippers[index] = &adStripper{}
+	}
+	return hs.strippers[index]
+}
+
+/* variantIndex returns the variant a relay request asks for with ?variant=, or -1 for the channel's own playlist. */
+func variantIndex(r *http.Request) int {
+	index, err := strconv.Atoi(r.URL.Query().Get("variant"))
+	if err != nil || index < 0 {
+		return -1
+	}
+	return index
+}
+
+/* variantRef returns the reference, relative to the relay request r, of variant index of its master playlist; it keeps r's query, so a token the player authenticated with is carried along. */
+func variantRef(r *http.Request, index int) string {
+	query := r.URL.Query()
+	query.Set("variant", strconv.Itoa(index))
+	return "./" + url.PathEscape(r.PathValue("id")) + "?" + query.Encode()
+}
+
+/* hlsKey identifies the HLS session of a viewer by stream key and peer address. */
+func hlsKey(r *http.Request, key string) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return key + "|" + host
+}
+
+/* find returns the live session under key, postponing its expiry, or nil when there is none. */
+func (h *hlsHub) find(key string) *hlsSession {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	hs := h.sessions[key]
+	if hs == nil || !hs.idle.Stop() {
+		// A session whose timer already fired is being closed
+		return nil
+	}
+	hs.idle.Reset(hlsIdleTimeout)
+	return hs
+}
+
+/* add keeps session and its view under key until it goes idle, then closes the session and calls end. */
+func (h *hlsHub) add(key string, session *stalkerlib.PlaybackSession, view *viewer, end func()) *hlsSession {
+	hs := &hlsSession{session: session, view: view}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	hs.idle = time.AfterFunc(hlsIdleTimeout, func() {
+		h.mu.Lock()
+		if h.sessions[key] == hs {
+			delete(h.sessions, key)
+		}
+		h.mu.Unlock()
+		session.Close()
+		end()
+	})
+	if h.sessions == nil {
+		h.sessions = make(map[string]*hlsSession)
+	}
+	h.sessions[key] = hs
+	return hs
+}
+
+/* serveHLS answers a playlist request from the viewer's session, fetching the playlist or the requested variant again from the session's link and renewing the link once when the upstream rejects it. */
+func (s *Server) serveHLS(w http.ResponseWriter, r *http.Request, hs *hlsSession) {
+	index := variantIndex(r)
+	playURL, ok := hs.playlistURL(index)
+	if !ok {
+		writeError(w, http.StatusNotFound, "unknown variant")
+		return
+	}
+	resp, err := s.fetchPlaylist(r, playURL)
+	if err == nil && (resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone) {
+		resp.Body.Close()
+		resp, err = s.refetchPlaylist(r, hs, index)
+	}
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		writeError(w, http.StatusBadGateway, "upstream returned "+resp.Status)
+		return
+	}
+	s.writePlaylist(w, r, hs, index, resp)
+}
+
+/* refetchPlaylist renews the session's link and fetches the playlist at index from it, reading the variants of a renewed master playlist first. */
+func (s *Server) refetchPlaylist(r *http.Request, hs *hlsSession, index int) (*http.Response, error) {
+	playURL, err := hs.session.Refresh(r.Context())
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.fetchPlaylist(r, playURL)
+	if err != nil || index < 0 {
+		return resp, err
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxPlaylistSize))
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	s.renderPlaylist(r, hs, -1, string(body), resp.Request.URL)
+	variantURL, ok := hs.playlistURL(index)
+	if !ok {
+		return nil, errors.New("variant no longer listed")
+	}
+	return s.fetchPlaylist(r, variantURL)
+}
+
+/* fetchPlaylist requests an upstream playlist for the duration of r. */
+func (s *Server) fetchPlaylist(r *http.Request, playURL string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(r.Context(), "GET", streamURL(playURL), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", stalkerlib.STBUserAgent)
+	return s.upstreamClient().Do(req)
+}
+
+/* writePlaylist renders the upstream playlist at index of the session and writes it to w; a request for a variant answered with the master playlist, as after the session expired, is served the variant instead. */
+func (s *Server) writePlaylist(w http.ResponseWriter, r *http.Request, hs *hlsSession, index int, resp *http.Response) {
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxPlaylistSize))
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+	playlist := s.renderPlaylist(r, hs, index, string(body), resp.Request.URL)
+	if index < 0 && variantIndex(r) >= 0 {
+		s.serveHLS(w, r, hs)
+		return
+	}
+	w.Header().Set("Content-Type", "application/vnd.apple.mpegurl")
+	w.Header().Set("Cache-Control", "no-cache")
+	io.WriteString(w, playlist)
+}
+
+/* renderPlaylist rewrites an upstream playlist fetched from base for the relay: a master playlist's variants are routed back through the relay and remembered by the session, while a media playlist's URIs are made absolute and its ad breaks stripped when configured. */
+func (s *Server) renderPlaylist(r *http.Request, hs *hlsSession, index int, playlist string, base *url.URL) string {
+	if isMasterPlaylist(playlist) {
+		playlist, variants := routeVariants(playlist, base, func(i int) string { return variantRef(r, i) })
+		if index < 0 {
+			hs.mu.Lock()
+			hs.variants = variants
+			hs.mu.Unlock()
+		}
+		return playlist
+	}
+	playlist = rewritePlaylist(playlist, base)
+	if s.stripAds {
+		playlist = hs.stripper(index).strip(playlist)
+	}
+	return playlist
+}
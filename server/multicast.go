@@ -0,0 +1,106 @@
+package server
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"net/http"
+
+	"github.com/ericcmi/stalkerlib"
+)
+
+/* WithMulticastInterface joins multicast groups on the named network interface instead of the system default. */
+func WithMulticastInterface(name string) Option {
+	return func(s *Server) {
+		s.multicastIface = name
+	}
+}
+
+/* registerMulticast installs udpxy-compatible /udp/{addr} and /rtp/{addr} endpoints. */
+func (s *Server) registerMulticast() {
+	s.mux.Handle("GET /udp/{addr}", s.requireAuth(http.HandlerFunc(s.handleMulticast)))
+	s.mux.Handle("GET /rtp/{addr}", s.requireAuth(http.HandlerFunc(s.handleMulticast)))
+}
+
+/* handleMulticast relays the multicast group named in the path, udpxy style. */
+func (s *Server) handleMulticast(w http.ResponseWriter, r *http.Request) {
+	protocol := "udp"
+	if r.Pattern == "GET /rtp/{addr}" {
+		protocol = "rtp"
+	}
+	_, addr, ok := stalkerlib.ParseMulticastCmd(protocol + "://@" + r.PathValue("addr"))
+	if !ok {
+		writeError(w, http.StatusBadRequest, "not a multicast group address")
+		return
+	}
+	s.serveMulticast(w, r, protocol, addr)
+}
+
+/* serveMulticast joins a multicast group and writes its MPEG-TS payload to w until the client disconnects. */
+func (s *Server) serveMulticast(w http.ResponseWriter, r *http.Request, protocol, addr string) {
+	group, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	var iface *net.Interface
+	if s.multicastIface != "" {
+		if iface, err = net.InterfaceByName(s.multicastIface); err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+	conn, err := net.ListenMulticastUDP("udp", iface, group)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(r.Context(), func() { conn.Close() })
+	defer stop()
+
+	w.Header().Set("Content-Type", "video/mp2t")
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+	packet := make([]byte, 65536)
+	for {
+		n, err := conn.Read(packet)
+		if err != nil {
+			return
+		}
+		payload := packet[:n]
+		if protocol == "rtp" {
+			if payload = rtpPayload(payload); payload == nil {
+				continue
+			}
+		}
+		if _, err := w.Write(payload); err != nil {
+			return
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+}
+
+/* rtpPayload strips the RTP header, CSRC list, extension, and padding from a packet, returning nil for malformed packets. */
+func rtpPayload(packet []byte) []byte {
+	if len(packet) < 12 || packet[0]>>6 != 2 {
+		return nil
+	}
+	header := 12 + 4*int(packet[0]&0x0f)
+	if packet[0]&0x10 != 0 {
+		if len(packet) < header+4 {
+			return nil
+		}
+		header += 4 + 4*int(binary.BigEndian.Uint16(packet[header+2:]))
+	}
+	end := len(packet)
+	if packet[0]&0x20 != 0 && end > 0 {
+		end -= int(packet[end-1])
+	}
+	if header > end {
+		return nil
+	}
+	return packet[header:end]
+}
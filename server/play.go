@@ -0,0 +1,48 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/ericcmi/stalkerlib"
+)
+
+/* WithPlayRelaying makes /play/{id} relay the stream like /relay/{id} instead of redirecting to the resolved URL, for players that cannot send the STB headers. */
+func WithPlayRelaying() Option {
+	return func(s *Server) {
+		s.playRelay = true
+	}
+}
+
+/* registerPlay installs the lazily resolving playback endpoint for playlists. */
+func (s *Server) registerPlay() {
+	s.mux.Handle("GET /play/{id}", s.requireAuth(http.HandlerFunc(s.handlePlayRedirect)))
+}
+
+/* handlePlayRedirect resolves create_link when the player opens the channel and redirects to the fresh URL, so playlists never hold expired temporary links; multicast URLs are always relayed. Redirected streams are refused at the stream limit but hold no slot, so enable relaying to count them. */
+func (s *Server) handlePlayRedirect(w http.ResponseWriter, r *http.Request) {
+	if s.playRelay || r.URL.Query().Has("utc") {
+		s.handleRelay(w, r)
+		return
+	}
+	channel, status, err := s.findChannel(r, r.PathValue("id"))
+	if err != nil {
+		writeError(w, status, err.Error())
+		return
+	}
+	playURL, err := s.clientFor(r).GetPlaybackURLContext(r.Context(), channel.Cmd)
+	if errors.Is(err, stalkerlib.ErrNoStreamSlots) {
+		writeError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+	if protocol, addr, ok := stalkerlib.ParseMulticastCmd(playURL); ok {
+		s.serveMulticast(w, r, protocol, addr)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	http.Redirect(w, r, streamURL(playURL), http.StatusFound)
+}
@@ -0,0 +1,176 @@
+package server
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ericcmi/stalkerlib"
+)
+
+/* LineupProfile is a curated view of the lineup, e.g. "kids" or "livingroom", served with its own playlist and guide under /profiles/{name}/. */
+type LineupProfile struct {
+	Name      string                        // URL segment of the profile
+	Filter    func(stalkerlib.Channel) bool // Reports whether a channel belongs to the profile; nil keeps every channel
+	Playlist  stalkerlib.PlaylistProfile    // M3U dialect of the profile's playlist
+	IDs       stalkerlib.ChannelIDScheme    // Channel IDs shared by the playlist and the guide
+	Timeshift stalkerlib.TimeshiftOptions   // Time-shifted duplicates added to the playlist and the guide
+	Collapse  stalkerlib.QualityCollapse    // Quality variants reduced to one in the playlist and the guide
+	Regions   []string                      // Region tags kept in the playlist and the guide; nil keeps every region
+	Sort      stalkerlib.ChannelSort        // Channel order of the playlist and the guide
+}
+
+/* GenreFilter keeps channels in one of the given genre IDs. */
+func GenreFilter(genreIDs ...string) func(stalkerlib.Channel) bool {
+	return func(ch stalkerlib.Channel) bool {
+		return slices.Contains(genreIDs, ch.GenreID)
+	}
+}
+
+/* ExcludeGenreFilter drops channels in any of the given genre IDs. */
+func ExcludeGenreFilter(genreIDs ...string) func(stalkerlib.Channel) bool {
+	return func(ch stalkerlib.Channel) bool {
+		return !slices.Contains(genreIDs, ch.GenreID)
+	}
+}
+
+/* ChannelFilter keeps only the channels with the given IDs. */
+func ChannelFilter(ids ...string) func(stalkerlib.Channel) bool {
+	return func(ch stalkerlib.Channel) bool {
+		return slices.Contains(ids, ch.ID)
+	}
+}
+
+/* WithLineupProfiles serves each profile's playlist at /profiles/{name}/playlist.m3u and its guide at /profiles/{name}/guide.xml (or guide.xml.gz). */
+func WithLineupProfiles(profiles ...LineupProfile) Option {
+	return func(s *Server) {
+		if s.profiles == nil {
+			s.profiles = make(map[string]LineupProfile)
+		}
+		for _, p := range profiles {
+			s.profiles[p.Name] = p
+		}
+	}
+}
+
+/* registerProfiles installs the per-profile playlist and guide exports. */
+func (s *Server) registerProfiles() {
+	s.mux.Handle("GET /profiles/{name}/playlist.m3u", s.requireAuth(http.HandlerFunc(s.handleProfilePlaylist)))
+	s.mux.Handle("GET /profiles/{name}/guide.xml", s.requireAuth(http.HandlerFunc(s.handleProfileGuide)))
+	s.mux.Handle("GET /profiles/{name}/guide.xml.gz", s.requireAuth(http.HandlerFunc(s.handleProfileGuide)))
+}
+
+/* handleProfilePlaylist serves the M3U playlist of one profile, with relay URLs pointing back at this server. */
+func (s *Server) handleProfilePlaylist(w http.ResponseWriter, r *http.Request) {
+	profile, channels, ok := s.profileChannels(w, r)
+	if !ok {
+		return
+	}
+	// Buffer the playlist so a failing create_link still yields a clean error response
+	var buf bytes.Buffer
+	opts := stalkerlib.PlaylistOptions{Profile: profile.Playlist, BaseURL: s.requestBaseURL(r), ChannelIDs: profile.IDs, Timeshift: profile.Timeshift, Collapse: profile.Collapse, Regions: profile.Regions, Sort: profile.Sort}
+	if s.validAPIToken(r) {
+		// Players cannot send headers, so relay URLs carry the token the playlist was fetched with
+		opts.Query = url.Values{"token": {requestToken(r)}}
+	}
+	if err := s.clientFor(r).ExportM3U(&buf, channels, opts); err != nil {
+		writeError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "audio/x-mpegurl")
+	buf.WriteTo(w)
+}
+
+/* handleProfileGuide serves the XMLTV guide of one profile's channels, gzipped for guide.xml.gz, with times in the zone of an optional ?tz= parameter (e.g. "UTC"). */
+func (s *Server) handleProfileGuide(w http.ResponseWriter, r *http.Request) {
+	profile, channels, ok := s.profileChannels(w, r)
+	if !ok {
+		return
+	}
+
+	opts := stalkerlib.XMLTVOptions{ChannelIDs: profile.IDs, Timeshift: profile.Timeshift, URLs: profile.Playlist.URLs, Collapse: profile.Collapse, Regions: profile.Regions, Sort: profile.Sort}
+	if tz := r.URL.Query().Get("tz"); tz != "" {
+		loc, err := time.LoadLocation(tz)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid tz parameter")
+			return
+		}
+		opts.Location = loc
+	}
+
+	// Channels without programs are still listed, so one failing EPG does not lose the guide
+	programs, _ := s.clientFor(r).GetAllEPG(channels)
+	var buf bytes.Buffer
+	if err := s.clientFor(r).ExportXMLTV(&buf, channels, programs, opts); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if strings.HasSuffix(r.URL.Path, ".gz") {
+		w.Header().Set("Content-Type", "application/gzip")
+		zw := gzip.NewWriter(w)
+		buf.WriteTo(zw)
+		zw.Close()
+		return
+	}
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	buf.WriteTo(w)
+}
+
+/* profileChannels looks up the profile named in the request and its filtered lineup, writing the error response when either fails. */
+func (s *Server) profileChannels(w http.ResponseWriter, r *http.Request) (LineupProfile, []stalkerlib.Channel, bool) {
+	profile, ok := s.profiles[r.PathValue("name")]
+	if !ok {
+		writeError(w, http.StatusNotFound, "unknown profile")
+		return LineupProfile{}, nil, false
+	}
+	channels, err := s.clientFor(r).GetChannels()
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err.Error())
+		return LineupProfile{}, nil, false
+	}
+	if profile.Filter != nil {
+		channels = slices.DeleteFunc(slices.Clone(channels), func(ch stalkerlib.Channel) bool {
+			return !profile.Filter(ch)
+		})
+	}
+	return profile, channels, true
+}
+
+/* WithPublicURL makes playlists point relay URLs at base, e.g. "https://tv.example.com", instead of the host each request names. */
+func WithPublicURL(base string) Option {
+	return func(s *Server) {
+		s.publicURL = strings.TrimSuffix(base, "/")
+	}
+}
+
+/* WithTrustedProxies honors X-Forwarded-Proto and X-Forwarded-Host from reverse proxies in the given prefixes when building relay URLs. */
+func WithTrustedProxies(prefixes ...netip.Prefix) Option {
+	return func(s *Server) {
+		s.proxies = append(s.proxies, prefixes...)
+	}
+}
+
+/* requestBaseURL returns the scheme and host the client reached this server with, as forwarded by a trusted proxy, or the configured public URL; credentials are never embedded, so relay URLs authenticate with the API token query parameter instead. */
+func (s *Server) requestBaseURL(r *http.Request) string {
+	if s.publicURL != "" {
+		return s.publicURL
+	}
+	scheme, host := "http", r.Host
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if len(s.proxies) > 0 && remoteIn(r, s.proxies) {
+		if proto := r.Header.Get("X-Forwarded-Proto"); proto == "http" || proto == "https" {
+			scheme = proto
+		}
+		if forwarded := r.Header.Get("X-Forwarded-Host"); forwarded != "" {
+			host = forwarded
+		}
+	}
+	return scheme + "://" + host
+}
@@ -0,0 +1,101 @@
+package server
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+
+	"github.com/ericcmi/stalkerlib"
+)
+
+func TestRequestBaseURL(t *testing.T) {
+	client := stalkerlib.NewStalkerClient("http://own.invalid", "00:1A:79:00:00:02", "UTC")
+	proxy := WithTrustedProxies(netip.MustParsePrefix("10.0.0.0/8"))
+
+	tests := []struct {
+		name    string
+		opts    []Option
+		remote  string
+		tls     bool
+		headers map[string]string
+		want    string
+	}{
+		{"direct", nil, "192.168.1.20:5000", false, nil, "http://tv.local:8080"},
+		{"direct TLS", nil, "192.168.1.20:5000", true, nil, "https://tv.local:8080"},
+		{"forwarded without trusted proxies", nil, "10.0.0.2:5000", false,
+			map[string]string{"X-Forwarded-Proto": "https", "X-Forwarded-Host": "evil.example"}, "http://tv.local:8080"},
+		{"forwarded by untrusted peer", []Option{proxy}, "192.168.1.20:5000", false,
+			map[string]string{"X-Forwarded-Proto": "https", "X-Forwarded-Host": "evil.example"}, "http://tv.local:8080"},
+		{"forwarded by trusted proxy", []Option{proxy}, "10.0.0.2:5000", false,
+			map[string]string{"X-Forwarded-Proto": "https", "X-Forwarded-Host": "tv.example.com"}, "https://tv.example.com"},
+		{"invalid forwarded proto", []Option{proxy}, "10.0.0.2:5000", false,
+			map[string]string{"X-Forwarded-Proto": "javascript"}, "http://tv.local:8080"},
+		{"public URL wins", []Option{proxy, WithPublicURL("https://tv.example.com/")}, "10.0.0.2:5000", false,
+			map[string]string{"X-Forwarded-Host": "other.example"}, "https://tv.example.com"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New(client, tt.opts...)
+			req := httptest.NewRequest("GET", "http://tv.local:8080/profiles/all/playlist.m3u", nil)
+			req.RemoteAddr = tt.remote
+			if tt.tls {
+				req.TLS = &tls.ConnectionState{}
+			}
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			if got := s.requestBaseURL(req); got != tt.want {
+				t.Errorf("requestBaseURL = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProfilePlaylistCarriesAPIToken(t *testing.T) {
+	portal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("action") {
+		case "handshake":
+			w.Write([]byte(`{"js":{"token":"portal-token"}}`))
+		case "get_all_channels":
+			w.Write([]byte(`{"js":{"data":[{"id":"1","name":"One","cmd":"ffrt http://stream.invalid/1"}]}}`))
+		default:
+			w.Write([]byte(`{"js":{}}`))
+		}
+	}))
+	defer portal.Close()
+	client := stalkerlib.NewStalkerClient(portal.URL, "00:1A:79:00:00:02", "UTC")
+	profile := LineupProfile{Name: "all", Playlist: stalkerlib.ProfilePlex}
+
+	tests := []struct {
+		name     string
+		opts     []Option
+		query    string
+		password string
+		want     string
+	}{
+		{"token in query", []Option{WithAPITokens("secret")}, "?token=secret", "", "/relay/1?token=secret"},
+		{"no credentials configured", nil, "", "", "/relay/1\n"},
+		{"basic credentials only", []Option{WithAPITokens("secret"), WithBasicAuth("alice", "wonderland")}, "", "wonderland", "/relay/1\n"},
+		{"wrong token with basic credentials", []Option{WithAPITokens("secret"), WithBasicAuth("alice", "wonderland")}, "?token=guess", "wonderland", "/relay/1\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New(client, append(tt.opts, WithLineupProfiles(profile))...)
+			req := httptest.NewRequest("GET", "http://tv.local/profiles/all/playlist.m3u"+tt.query, nil)
+			if tt.password != "" {
+				req.SetBasicAuth("alice", tt.password)
+			}
+			rec := httptest.NewRecorder()
+			s.ServeHTTP(rec, req)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+			}
+			if body := rec.Body.String(); !strings.Contains(body, "http://tv.local"+tt.want) {
+				t.Errorf("playlist lacks %q:\n%s", tt.want, body)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ericcmi/stalkerlib"
+)
+
+/* pushMessage is the JSON document sent to WebSocket subscribers for each lineup or guide change. */
+type pushMessage struct {
+	Type      string                  `json:"type"`
+	Time      time.Time               `json:"time"`
+	ChannelID string                  `json:"channel_id,omitempty"`
+	Channels  []stalkerlib.Channel    `json:"channels,omitempty"`
+	Programs  []stalkerlib.EPGProgram `json:"programs,omitempty"`
+}
+
+/* pushQueueSize is how many undelivered messages a subscriber may lag behind before it is disconnected. */
+const pushQueueSize = 32
+
+/* pushHub fans client events out to connected WebSocket subscribers. */
+type pushHub struct {
+	mu   sync.Mutex
+	subs map[*wsConn]chan []byte
+}
+
+/* registerPush installs the WebSocket endpoint and subscribes to the update events of the server and tenant clients. */
+func (s *Server) registerPush() {
+	s.push = newPushHub(s.client)
+	for _, t := range s.tenants {
+		t.push = newPushHub(t.client)
+	}
+	s.mux.Handle("GET /api/ws", s.requireAuth(http.HandlerFunc(s.handleWebSocket)))
+}
+
+/* newPushHub creates a hub broadcasting the update events of client. */
+func newPushHub(client *stalkerlib.StalkerClient) *pushHub {
+	h := &pushHub{subs: make(map[*wsConn]chan []byte)}
+	client.Subscribe(func(e stalkerlib.Event) {
+		h.publishEvent(client, e)
+	})
+	return h
+}
+
+/* publishEvent converts lineup and guide events of client into push messages. */
+func (h *pushHub) publishEvent(client *stalkerlib.StalkerClient, e stalkerlib.Event) {
+	msg := pushMessage{Type: e.Type.String(), Time: e.Time, ChannelID: e.ChannelID}
+	switch e.Type {
+	case stalkerlib.EventChannelsUpdated:
+		msg.Channels, _ = client.CachedChannels()
+	case stalkerlib.EventEPGUpdated:
+		msg.Programs, _ = client.CachedEPG(e.ChannelID)
+	default:
+		return
+	}
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return
+	}
+	h.broadcast(data)
+}
+
+/* broadcast queues data for every subscriber, dropping subscribers that have fallen too far behind. */
+func (h *pushHub) broadcast(data []byte) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for conn, queue := range h.subs {
+		select {
+		case queue <- data:
+		default:
+			delete(h.subs, conn)
+			close(queue)
+		}
+	}
+}
+
+/* handleWebSocket upgrades the request and streams push messages until the client disconnects. */
+func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	conn, err := upgradeWebSocket(w, r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	defer conn.Close()
+
+	hub := s.pushFor(r)
+	queue := make(chan []byte, pushQueueSize)
+	hub.mu.Lock()
+	hub.subs[conn] = queue
+	hub.mu.Unlock()
+	defer func() {
+		hub.mu.Lock()
+		if _, ok := hub.subs[conn]; ok {
+			delete(hub.subs, conn)
+			close(queue)
+		}
+		hub.mu.Unlock()
+	}()
+
+	closed := make(chan struct{})
+	go func() {
+		conn.readLoop()
+		close(closed)
+	}()
+	for {
+		select {
+		case data, ok := <-queue:
+			if !ok {
+				return
+			}
+			if err := conn.writeFrame(opText, data); err != nil {
+				return
+			}
+		case <-closed:
+			return
+		}
+	}
+}
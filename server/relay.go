@@ -0,0 +1,189 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ericcmi/stalkerlib"
+)
+
+/* WithAdMarkerStripping removes ad-insertion cues and the ad segments they delimit from HLS playlists served by the relay, renumbering the remaining segments; the variants of master playlists are relayed as well, so their media playlists are stripped too. */
+func WithAdMarkerStripping() Option {
+	return func(s *Server) {
+		s.stripAds = true
+	}
+}
+
+/* WithRelayClient sets the HTTP client used to fetch upstream streams (http.DefaultClient by default). */
+func WithRelayClient(client *http.Client) Option {
+	return func(s *Server) {
+		s.relayClient = client
+	}
+}
+
+/* WithRelayDNSCache fetches upstream streams with a client that resolves stream hosts through cache, sparing the relay a lookup per segment request. */
+func WithRelayDNSCache(cache *stalkerlib.DNSCache) Option {
+	return func(s *Server) {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+		transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return cache.DialContext(ctx, dialer, network, addr)
+		}
+		s.relayClient = &http.Client{Transport: transport}
+	}
+}
+
+/* registerRelay installs the stream relay endpoint. */
+func (s *Server) registerRelay() {
+	s.mux.Handle("GET /relay/{id}", s.requireAuth(http.HandlerFunc(s.handleRelay)))
+}
+
+/* handleRelay resolves a channel's playback URL and proxies the upstream stream to the caller, joining an already shared upstream or the caller's HLS session when possible; requests with ?utc= from shift-style catch-up are answered with the recording. */
+func (s *Server) handleRelay(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Has("utc") {
+		s.handleCatchup(w, r)
+		return
+	}
+	channel, status, err := s.findChannel(r, r.PathValue("id"))
+	if err != nil {
+		writeError(w, status, err.Error())
+		return
+	}
+	if hs := s.hls.find(hlsKey(r, streamKey(r, channel.ID))); hs != nil {
+		s.serveHLS(hs.view.writer(w), r, hs)
+		return
+	}
+	if s.streams != nil {
+		if st := s.streams.join(streamKey(r, channel.ID)); st != nil {
+			w, done := s.stats.track(w, r, channel.ID)
+			defer done()
+			s.serveShared(w, r, st)
+			return
+		}
+	}
+	ctx := r.Context()
+	if id := r.Header.Get("X-Request-ID"); id != "" {
+		ctx = stalkerlib.ContextWithRequestID(ctx, id)
+	}
+	session, err := s.clientFor(r).StartPlayback(ctx, channel.Cmd)
+	if errors.Is(err, stalkerlib.ErrNoStreamSlots) {
+		writeError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+	view := s.stats.begin(r, channel.ID)
+	if protocol, addr, ok := stalkerlib.ParseMulticastCmd(session.URL()); ok {
+		defer s.stats.end(view)
+		defer session.Close()
+		s.serveMulticast(view.writer(w), r, protocol, addr)
+		return
+	}
+	s.proxyStream(view.writer(w), r, streamKey(r, channel.ID), session, view)
+}
+
+/* proxyStream copies the session's upstream stream to w, sharing it under key when stream sharing is on; the session is closed, and view ended, with the upstream, except that an HLS playlist is rewritten so its URIs resolve from the relay and the session and view kept for the caller's reloads. */
+func (s *Server) proxyStream(w http.ResponseWriter, r *http.Request, key string, session *stalkerlib.PlaybackSession, view *viewer) {
+	// A shared upstream must outlive the request that opened it
+	ctx, cancelCtx := r.Context(), context.CancelFunc(func() {})
+	if s.streams != nil {
+		ctx, cancelCtx = context.WithCancel(context.Background())
+	}
+	cancel := func() {
+		cancelCtx()
+		session.Close()
+	}
+	kept := false
+	defer func() {
+		if !kept {
+			s.stats.end(view)
+		}
+	}()
+	req, err := http.NewRequestWithContext(ctx, "GET", streamURL(session.URL()), nil)
+	if err != nil {
+		cancel()
+		writeError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+	req.Header.Set("User-Agent", stalkerlib.STBUserAgent)
+	resp, err := s.upstreamClient().Do(req)
+	if err != nil {
+		cancel()
+		writeError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+	if resp.StatusCode == http.StatusOK && isHLSPlaylist(resp) {
+		defer cancelCtx()
+		defer resp.Body.Close()
+		kept = true
+		s.writePlaylist(w, r, s.hls.add(hlsKey(r, key), session, view, func() { s.stats.end(view) }), -1, resp)
+		return
+	}
+	if resp.StatusCode == http.StatusOK && resp.ContentLength < 0 {
+		// Live streams survive link expiry, e.g. after the token is refreshed
+		resp.Body = &resumingBody{ctx: ctx, s: s, session: session, body: resp.Body}
+	}
+	if resp.StatusCode == http.StatusOK && s.streams != nil {
+		s.serveShared(w, r, s.streams.start(key, resp, cancel))
+		return
+	}
+	defer cancel()
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		writeError(w, http.StatusBadGateway, "upstream returned "+resp.Status)
+		return
+	}
+
+	if contentType := resp.Header.Get("Content-Type"); contentType != "" {
+		w.Header().Set("Content-Type", contentType)
+	}
+	w.WriteHeader(http.StatusOK)
+	copyFlushing(w, resp.Body)
+}
+
+/* upstreamClient returns the configured relay client, or http.DefaultClient. */
+func (s *Server) upstreamClient() *http.Client {
+	if s.relayClient != nil {
+		return s.relayClient
+	}
+	return http.DefaultClient
+}
+
+/* streamURL strips the player prefix ("ffmpeg ", "auto ") that portals put in front of playback URLs. */
+func streamURL(playURL string) string {
+	fields := strings.Fields(playURL)
+	if len(fields) == 0 {
+		return playURL
+	}
+	return fields[len(fields)-1]
+}
+
+/* copyFlushing copies src to w, flushing after each chunk so live streams are not held in response buffers. */
+func copyFlushing(w http.ResponseWriter, src io.Reader) error {
+	flusher, _ := w.(http.Flusher)
+	buf := make([]byte, 32<<10)
+	for {
+		n, err := src.Read(buf)
+		if n > 0 {
+			if _, werr := w.Write(buf[:n]); werr != nil {
+				return werr
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
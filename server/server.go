@@ -0,0 +1,112 @@
+/* Package server exposes a StalkerClient over HTTP for players, web UIs, and other local consumers. */
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/netip"
+	"sync"
+
+	"github.com/ericcmi/stalkerlib"
+)
+
+/* Server serves portal data from a StalkerClient over HTTP. */
+type Server struct {
+	client    *stalkerlib.StalkerClient
+	apiTokens map[string]bool
+	users     map[string]string
+	allowed   []netip.Prefix
+	proxies   []netip.Prefix
+	publicURL string
+	tenants   map[string]*tenant
+	mux       *http.ServeMux
+	push      *pushHub
+
+	stripAds       bool
+	relayClient    *http.Client
+	streams        *streamHub
+	hls            hlsHub
+	multicastIface string
+	playRelay      bool
+
+	profiles map[string]LineupProfile
+	stats    *relayStats
+
+	mu         sync.Mutex
+	httpServer *http.Server
+}
+
+/* Option configures optional Server behavior at construction time. */
+type Option func(*Server)
+
+/* WithAPITokens requires one of the given tokens, sent as "Authorization: Bearer <token>" or a token query parameter, on every endpoint. */
+func WithAPITokens(tokens ...string) Option {
+	return func(s *Server) {
+		if s.apiTokens == nil {
+			s.apiTokens = make(map[string]bool)
+		}
+		for _, t := range tokens {
+			s.apiTokens[t] = true
+		}
+	}
+}
+
+/* New creates a Server backed by client. */
+func New(client *stalkerlib.StalkerClient, opts ...Option) *Server {
+	s := &Server{client: client, mux: http.NewServeMux()}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.registerAPI()
+	s.registerPush()
+	s.registerRelay()
+	s.registerMulticast()
+	s.registerCatchup()
+	s.registerPlay()
+	s.registerProfiles()
+	s.registerStatus()
+	return s
+}
+
+/* ServeHTTP dispatches a request to the server's routes. */
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+/* ListenAndServe listens on addr and serves requests until Shutdown is called. */
+func (s *Server) ListenAndServe(addr string) error {
+	s.mu.Lock()
+	s.httpServer = &http.Server{Addr: addr, Handler: s}
+	httpServer := s.httpServer
+	s.mu.Unlock()
+
+	err := httpServer.ListenAndServe()
+	if err == http.ErrServerClosed {
+		return nil
+	}
+	return err
+}
+
+/* Shutdown gracefully stops a server started with ListenAndServe. */
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	httpServer := s.httpServer
+	s.mu.Unlock()
+	if httpServer == nil {
+		return nil
+	}
+	return httpServer.Shutdown(ctx)
+}
+
+/* writeJSON writes v as a JSON response with the given status code. */
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+/* writeError writes a JSON error response. */
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
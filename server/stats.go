@@ -0,0 +1,158 @@
+package server
+
+import (
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ericcmi/stalkerlib"
+)
+
+/* relayStats counts relay viewers, views, and bytes sent for the /status endpoint. */
+type relayStats struct {
+	mu     sync.Mutex
+	active map[*viewer]bool
+	views  map[string]map[string]int64 // View count by tenant, then channel ID
+	sent   map[string]int64            // Bytes of finished viewers by tenant
+}
+
+/* viewer is one relay client currently being streamed to. */
+type viewer struct {
+	tenant    string
+	channelID string
+	remote    string
+	started   time.Time
+	bytes     atomic.Int64
+}
+
+/* countingWriter counts the bytes written to a relay client. */
+type countingWriter struct {
+	http.ResponseWriter
+	v *viewer
+}
+
+/* Write forwards p and counts the bytes written. */
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(p)
+	w.v.bytes.Add(int64(n))
+	return n, err
+}
+
+/* Flush forwards to the underlying writer, so live streams are still flushed. */
+func (w *countingWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+/* StreamStatus describes one active relay stream. */
+type StreamStatus struct {
+	Tenant     string    `json:"tenant,omitempty"`
+	ChannelID  string    `json:"channel_id"`
+	RemoteAddr string    `json:"remote_addr"`
+	Started    time.Time `json:"started"`
+	BytesSent  int64     `json:"bytes_sent"`
+	Bitrate    int64     `json:"bitrate"` // Average bits per second since the stream started
+}
+
+/* AccountStatus describes the stream slot use of one upstream account. */
+type AccountStatus struct {
+	Tenant         string `json:"tenant,omitempty"`
+	ActiveSessions int    `json:"active_sessions"`
+	StreamLimit    int    `json:"stream_limit"` // 0 when unlimited or unknown
+}
+
+/* Status is the document served by /status. */
+type Status struct {
+	Streams   []StreamStatus   `json:"streams"`
+	Views     map[string]int64 `json:"views"` // Relay views by channel ID since the server started
+	BytesSent int64            `json:"bytes_sent"`
+	Bandwidth int64            `json:"bandwidth"` // Sum of the active streams' bitrates
+	Accounts  []AccountStatus  `json:"accounts"`
+}
+
+/* registerStatus installs the playback statistics endpoint. */
+func (s *Server) registerStatus() {
+	s.stats = &relayStats{
+		active: make(map[*viewer]bool),
+		views:  make(map[string]map[string]int64),
+		sent:   make(map[string]int64),
+	}
+	s.mux.Handle("GET /status", s.requireAuth(http.HandlerFunc(s.handleStatus)))
+}
+
+/* track counts a new view of channelID and returns a writer counting its bytes and a function ending the view. */
+func (st *relayStats) track(w http.ResponseWriter, r *http.Request, channelID string) (http.ResponseWriter, func()) {
+	v := st.begin(r, channelID)
+	return v.writer(w), func() { st.end(v) }
+}
+
+/* begin counts a new view of channelID, active until end is called, e.g. once per HLS playback session rather than per playlist request. */
+func (st *relayStats) begin(r *http.Request, channelID string) *viewer {
+	v := &viewer{tenant: tenantName(r), channelID: channelID, remote: r.RemoteAddr, started: time.Now()}
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.active[v] = true
+	if st.views[v.tenant] == nil {
+		st.views[v.tenant] = make(map[string]int64)
+	}
+	st.views[v.tenant][channelID]++
+	return v
+}
+
+/* end finishes the view v, adding its bytes to the tenant's total. */
+func (st *relayStats) end(v *viewer) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	delete(st.active, v)
+	st.sent[v.tenant] += v.bytes.Load()
+}
+
+/* writer returns w counting the bytes written to it as sent to v. */
+func (v *viewer) writer(w http.ResponseWriter) http.ResponseWriter {
+	return &countingWriter{ResponseWriter: w, v: v}
+}
+
+/* handleStatus serves the relay statistics; tenants only see their own streams and account. */
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	status := Status{Streams: []StreamStatus{}, Views: make(map[string]int64)}
+	tenants := map[string]*stalkerlib.StalkerClient{"": s.client}
+	if t := tenantOf(r); t != nil {
+		tenants = map[string]*stalkerlib.StalkerClient{t.name: t.client}
+	} else {
+		for name, t := range s.tenants {
+			tenants[name] = t.client
+		}
+	}
+
+	now := time.Now()
+	s.stats.mu.Lock()
+	for v := range s.stats.active {
+		if _, ok := tenants[v.tenant]; !ok {
+			continue
+		}
+		stream := StreamStatus{Tenant: v.tenant, ChannelID: v.channelID, RemoteAddr: v.remote, Started: v.started, BytesSent: v.bytes.Load()}
+		if elapsed := now.Sub(v.started).Seconds(); elapsed > 0 {
+			stream.Bitrate = int64(float64(stream.BytesSent*8) / elapsed)
+		}
+		status.Streams = append(status.Streams, stream)
+		status.BytesSent += stream.BytesSent
+		status.Bandwidth += stream.Bitrate
+	}
+	for name := range tenants {
+		for id, n := range s.stats.views[name] {
+			status.Views[id] += n
+		}
+		status.BytesSent += s.stats.sent[name]
+	}
+	s.stats.mu.Unlock()
+
+	for name, client := range tenants {
+		status.Accounts = append(status.Accounts, AccountStatus{Tenant: name, ActiveSessions: len(client.ActiveSessions()), StreamLimit: client.StreamLimit()})
+	}
+	sort.Slice(status.Streams, func(i, j int) bool { return status.Streams[i].Started.Before(status.Streams[j].Started) })
+	sort.Slice(status.Accounts, func(i, j int) bool { return status.Accounts[i].Tenant < status.Accounts[j].Tenant })
+	writeJSON(w, http.StatusOK, status)
+}
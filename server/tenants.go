@@ -0,0 +1,76 @@
+package server
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/ericcmi/stalkerlib"
+)
+
+/* tenant is a local user bound to its own upstream account, with separate tokens, rate limits, stream slots, and push subscribers. */
+type tenant struct {
+	name   string
+	client *stalkerlib.StalkerClient
+	push   *pushHub
+}
+
+/* tenantKey is the request context key of the authenticated tenant. */
+type tenantKey struct{}
+
+/* WithTenant serves client to the local user authenticating with the given basic credentials, so one server can host several upstream accounts; every other caller is served the server's own client. */
+func WithTenant(username, password string, client *stalkerlib.StalkerClient) Option {
+	return func(s *Server) {
+		WithBasicAuth(username, password)(s)
+		if s.tenants == nil {
+			s.tenants = make(map[string]*tenant)
+		}
+		s.tenants[username] = &tenant{name: username, client: client}
+	}
+}
+
+/* withTenant returns r with the tenant of username in its context; requireAuth passes the user only when its basic credentials matched. */
+func (s *Server) withTenant(r *http.Request, username string) *http.Request {
+	t, ok := s.tenants[username]
+	if !ok || username == "" {
+		return r
+	}
+	return r.WithContext(context.WithValue(r.Context(), tenantKey{}, t))
+}
+
+/* tenantOf returns the tenant a request was authenticated as, or nil for the server's own client. */
+func tenantOf(r *http.Request) *tenant {
+	t, _ := r.Context().Value(tenantKey{}).(*tenant)
+	return t
+}
+
+/* tenantName returns the name of the request's tenant, empty for the server's own client. */
+func tenantName(r *http.Request) string {
+	if t := tenantOf(r); t != nil {
+		return t.name
+	}
+	return ""
+}
+
+/* clientFor returns the upstream client serving the request. */
+func (s *Server) clientFor(r *http.Request) *stalkerlib.StalkerClient {
+	if t := tenantOf(r); t != nil {
+		return t.client
+	}
+	return s.client
+}
+
+/* pushFor returns the push hub of the request's upstream client. */
+func (s *Server) pushFor(r *http.Request) *pushHub {
+	if t := tenantOf(r); t != nil {
+		return t.push
+	}
+	return s.push
+}
+
+/* streamKey returns the shared stream key of a channel, so tenants never join each other's upstream connections. */
+func streamKey(r *http.Request, channelID string) string {
+	if name := tenantName(r); name != "" {
+		return name + "/" + channelID
+	}
+	return channelID
+}
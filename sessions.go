@@ -0,0 +1,201 @@
+package stalkerlib
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+)
+
+/* ErrNoStreamSlots is returned by StartPlayback and GetPlaybackURL when every stream slot of the account is in use under AdmissionReject. */
+var ErrNoStreamSlots = errors.New("stalkerlib: all stream slots in use")
+
+/* AdmissionPolicy decides what StartPlayback does when the account is at its stream limit. */
+type AdmissionPolicy int
+
+const (
+	AdmissionReject AdmissionPolicy = iota // Fail with ErrNoStreamSlots
+	AdmissionQueue                         // Wait for a session to close, admitting waiters in arrival order
+)
+
+/* WithStreamLimit caps simultaneous PlaybackSessions at limit, or at the account's reported playback limit when limit is 0, applying policy when at capacity; GetPlaybackURL and URLResolved exports are admitted the same way but do not keep a slot once the URL is handed out. */
+func WithStreamLimit(limit int, policy AdmissionPolicy) Option {
+	return func(c *StalkerClient) {
+		c.slots.limit = limit
+		c.slots.auto = limit == 0
+		c.slots.policy = policy
+		c.slots.enabled = true
+	}
+}
+
+/* PlaybackSession is an open stream occupying one of the account's slots until closed. */
+type PlaybackSession struct {
+	ChannelCmd string    // Cmd the session was started for
+	Started    time.Time // When the session was admitted
+
+	client  *StalkerClient
+	once    sync.Once
+	mu      sync.Mutex
+	playURL string // Resolved playback URL, replaced by Refresh
+}
+
+/* URL returns the session's current playback URL. */
+func (s *PlaybackSession) URL() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.playURL
+}
+
+/* Close releases the session's stream slot; it is safe to call more than once. */
+func (s *PlaybackSession) Close() error {
+	s.once.Do(func() {
+		s.client.slots.release(s)
+	})
+	return nil
+}
+
+/* streamSlots tracks active sessions against the account's stream limit. */
+type streamSlots struct {
+	mu       sync.Mutex
+	enabled  bool
+	auto     bool // Limit is taken from the account info on first use
+	resolved bool
+	limit    int
+	policy   AdmissionPolicy
+	active   map[*PlaybackSession]bool
+	waiters  []*slotWaiter // Queued sessions under AdmissionQueue, admitted first come, first served
+}
+
+/* slotWaiter is a session queued for a stream slot; ready is closed once the slot is its own. */
+type slotWaiter struct {
+	session *PlaybackSession
+	ready   chan struct{}
+}
+
+/* StartPlayback admits a new stream under the configured stream limit, resolves its playback URL, and returns a session that must be closed when playback ends. */
+func (c *StalkerClient) StartPlayback(ctx context.Context, channelCmd string) (*PlaybackSession, error) {
+	if err := c.resolveStreamLimit(ctx); err != nil {
+		return nil, err
+	}
+	s := &PlaybackSession{ChannelCmd: channelCmd, client: c}
+	if err := c.slots.acquire(ctx, s); err != nil {
+		return nil, err
+	}
+	playURL, err := c.getPlaybackURL(ctx, channelCmd)
+	if err != nil {
+		s.Close()
+		return nil, err
+	}
+	s.playURL, s.Started = playURL, time.Now()
+	return s, nil
+}
+
+/* admittedPlaybackURL resolves a playback URL outside a session, applying the admission policy first so no create_link is issued while every slot is taken. */
+func (c *StalkerClient) admittedPlaybackURL(ctx context.Context, channelCmd string) (string, error) {
+	if err := c.resolveStreamLimit(ctx); err != nil {
+		return "", err
+	}
+	s := &PlaybackSession{ChannelCmd: channelCmd, client: c}
+	if err := c.slots.acquire(ctx, s); err != nil {
+		return "", err
+	}
+	defer s.Close()
+	return c.getPlaybackURL(ctx, channelCmd)
+}
+
+/* ActiveSessions returns the sessions currently holding stream slots. */
+func (c *StalkerClient) ActiveSessions() []*PlaybackSession {
+	c.slots.mu.Lock()
+	defer c.slots.mu.Unlock()
+	sessions := make([]*PlaybackSession, 0, len(c.slots.active))
+	for s := range c.slots.active {
+		sessions = append(sessions, s)
+	}
+	return sessions
+}
+
+/* StreamLimit returns the enforced number of simultaneous sessions, 0 when unlimited or not yet known. */
+func (c *StalkerClient) StreamLimit() int {
+	c.slots.mu.Lock()
+	defer c.slots.mu.Unlock()
+	if !c.slots.enabled {
+		return 0
+	}
+	return c.slots.limit
+}
+
+/* resolveStreamLimit reads the account's playback limit once when WithStreamLimit(0, ...) asked for it. */
+func (c *StalkerClient) resolveStreamLimit(ctx context.Context) error {
+	c.slots.mu.Lock()
+	needed := c.slots.auto && !c.slots.resolved
+	c.slots.mu.Unlock()
+	if !needed {
+		return nil
+	}
+	info, err := c.getAccountInfo(ctx)
+	if err != nil {
+		return err
+	}
+	c.slots.mu.Lock()
+	c.slots.limit, c.slots.resolved = info.PlaybackLimit, true
+	c.slots.mu.Unlock()
+	return nil
+}
+
+/* acquire registers s, rejecting or queueing it per the policy when all slots are taken; a limit of 0 is unlimited. */
+func (sl *streamSlots) acquire(ctx context.Context, s *PlaybackSession) error {
+	sl.mu.Lock()
+	if sl.active == nil {
+		sl.active = make(map[*PlaybackSession]bool)
+	}
+	// Newcomers never overtake sessions already queued
+	if !sl.enabled || sl.limit <= 0 || (len(sl.active) < sl.limit && len(sl.waiters) == 0) {
+		sl.active[s] = true
+		sl.mu.Unlock()
+		return nil
+	}
+	if sl.policy == AdmissionReject {
+		sl.mu.Unlock()
+		return ErrNoStreamSlots
+	}
+	w := &slotWaiter{session: s, ready: make(chan struct{})}
+	sl.waiters = append(sl.waiters, w)
+	sl.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+	}
+	sl.mu.Lock()
+	defer sl.mu.Unlock()
+	select {
+	case <-w.ready:
+		// Admitted while giving up, so the slot goes to the next in line
+		delete(sl.active, s)
+		sl.admitLocked()
+	default:
+		sl.waiters = slices.DeleteFunc(sl.waiters, func(q *slotWaiter) bool { return q == w })
+	}
+	return ctx.Err()
+}
+
+/* release frees the slot of s and hands it to the longest-waiting session. */
+func (sl *streamSlots) release(s *PlaybackSession) {
+	sl.mu.Lock()
+	defer sl.mu.Unlock()
+	delete(sl.active, s)
+	sl.admitLocked()
+}
+
+/* admitLocked admits queued sessions in arrival order while slots are free; sl.mu must be held. */
+func (sl *streamSlots) admitLocked() {
+	for len(sl.waiters) > 0 && (sl.limit <= 0 || len(sl.active) < sl.limit) {
+		w := sl.waiters[0]
+		sl.waiters[0] = nil
+		sl.waiters = sl.waiters[1:]
+		sl.active[w.session] = true
+		close(w.ready)
+	}
+}
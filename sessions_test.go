@@ -0,0 +1,147 @@
+package stalkerlib
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+/* queued reports the number of sessions waiting for a slot. */
+func queued(sl *streamSlots) int {
+	sl.mu.Lock()
+	defer sl.mu.Unlock()
+	return len(sl.waiters)
+}
+
+/* waitQueued polls until n sessions wait for a slot. */
+func waitQueued(t *testing.T, sl *streamSlots, n int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for queued(sl) != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("queued = %d, want %d", queued(sl), n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestStreamSlotsReject(t *testing.T) {
+	tests := []struct {
+		name     string
+		enabled  bool
+		limit    int
+		admitted int
+	}{
+		{"at the limit", true, 2, 2},
+		{"unlimited", true, 0, 5},
+		{"disabled", false, 1, 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sl := &streamSlots{enabled: tt.enabled, limit: tt.limit, policy: AdmissionReject}
+			var sessions []*PlaybackSession
+			for i := 0; i < 5; i++ {
+				s := &PlaybackSession{}
+				err := sl.acquire(context.Background(), s)
+				if i < tt.admitted && err != nil {
+					t.Fatalf("acquire %d = %v, want nil", i, err)
+				}
+				if i >= tt.admitted && !errors.Is(err, ErrNoStreamSlots) {
+					t.Fatalf("acquire %d = %v, want ErrNoStreamSlots", i, err)
+				}
+				if err == nil {
+					sessions = append(sessions, s)
+				}
+			}
+			sl.release(sessions[0])
+			if err := sl.acquire(context.Background(), &PlaybackSession{}); err != nil {
+				t.Errorf("acquire after release = %v, want nil", err)
+			}
+		})
+	}
+}
+
+func TestStreamSlotsQueueFIFO(t *testing.T) {
+	sl := &streamSlots{enabled: true, limit: 1, policy: AdmissionQueue}
+	first := &PlaybackSession{}
+	if err := sl.acquire(context.Background(), first); err != nil {
+		t.Fatal(err)
+	}
+
+	sessions := make([]*PlaybackSession, 4)
+	admitted := make(chan int, len(sessions))
+	for i := range sessions {
+		sessions[i] = &PlaybackSession{}
+		go func() {
+			if err := sl.acquire(context.Background(), sessions[i]); err != nil {
+				t.Errorf("acquire %d = %v", i, err)
+			}
+			admitted <- i
+		}()
+		// Queue the sessions one by one so their order is known
+		waitQueued(t, sl, i+1)
+	}
+
+	sl.release(first)
+	for want := range sessions {
+		got := <-admitted
+		if got != want {
+			t.Fatalf("admitted session %d, want %d", got, want)
+		}
+		select {
+		case extra := <-admitted:
+			t.Fatalf("session %d admitted while %d holds the only slot", extra, got)
+		case <-time.After(10 * time.Millisecond):
+		}
+		sl.release(sessions[got])
+	}
+}
+
+func TestStreamSlotsQueueCancel(t *testing.T) {
+	sl := &streamSlots{enabled: true, limit: 1, policy: AdmissionQueue}
+	first := &PlaybackSession{}
+	if err := sl.acquire(context.Background(), first); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancelled := make(chan error)
+	go func() { cancelled <- sl.acquire(ctx, &PlaybackSession{}) }()
+	waitQueued(t, sl, 1)
+	next := &PlaybackSession{}
+	admitted := make(chan error)
+	go func() { admitted <- sl.acquire(context.Background(), next) }()
+	waitQueued(t, sl, 2)
+
+	cancel()
+	if err := <-cancelled; !errors.Is(err, context.Canceled) {
+		t.Fatalf("cancelled acquire = %v, want context.Canceled", err)
+	}
+	waitQueued(t, sl, 1)
+
+	sl.release(first)
+	if err := <-admitted; err != nil {
+		t.Fatalf("acquire after cancelled waiter = %v, want nil", err)
+	}
+	sl.mu.Lock()
+	defer sl.mu.Unlock()
+	if len(sl.active) != 1 || !sl.active[next] {
+		t.Errorf("active = %v, want only the session behind the cancelled one", sl.active)
+	}
+}
+
+func TestStreamSlotsQueueDeadline(t *testing.T) {
+	sl := &streamSlots{enabled: true, limit: 1, policy: AdmissionQueue}
+	if err := sl.acquire(context.Background(), &PlaybackSession{}); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := sl.acquire(ctx, &PlaybackSession{}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("acquire = %v, want context.DeadlineExceeded", err)
+	}
+	if n := queued(sl); n != 0 {
+		t.Errorf("queued = %d after the deadline, want 0", n)
+	}
+}
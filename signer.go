@@ -0,0 +1,23 @@
+package stalkerlib
+
+import "net/url"
+
+/* RequestSigner adds signature parameters to a portal action before it is sent, for portals that verify requests with an HMAC or similar; Sign should use Set so re-signing reused parameters replaces earlier values. */
+type RequestSigner interface {
+	Sign(params url.Values, identity DeviceIdentity) error
+}
+
+/* RequestSignerFunc adapts a function to the RequestSigner interface. */
+type RequestSignerFunc func(params url.Values, identity DeviceIdentity) error
+
+/* Sign calls f(params, identity). */
+func (f RequestSignerFunc) Sign(params url.Values, identity DeviceIdentity) error {
+	return f(params, identity)
+}
+
+/* WithRequestSigner invokes signer on the parameters of every portal action. */
+func WithRequestSigner(signer RequestSigner) Option {
+	return func(c *StalkerClient) {
+		c.signer = signer
+	}
+}
@@ -0,0 +1,225 @@
+package stalkerlib
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+/* snapshotVersion is the archive layout version written by SnapshotExport. */
+const snapshotVersion = 1
+
+/* snapshotManifest is the manifest.json entry of a snapshot archive. */
+type snapshotManifest struct {
+	Version   int          `json:"version"`
+	CreatedAt time.Time    `json:"created_at"`
+	PortalURL string       `json:"portal_url"`
+	MAC       string       `json:"mac"`
+	Timezone  string       `json:"timezone"`
+	Config    ServerConfig `json:"config"`
+}
+
+/* snapshotChannels is the channels.json entry of a snapshot archive. */
+type snapshotChannels struct {
+	FetchedAt time.Time `json:"fetched_at"`
+	Channels  []Channel `json:"channels"`
+}
+
+/* snapshotEPG is one epg/<channel>.json entry of a snapshot archive. */
+type snapshotEPG struct {
+	ChannelID string       `json:"channel_id"`
+	FetchedAt time.Time    `json:"fetched_at"`
+	Programs  []EPGProgram `json:"programs"`
+}
+
+/* SnapshotExport writes a gzipped tar archive with the client configuration, cached channels and EPG, and the logo files in logoDir (skipped when empty). */
+func (c *StalkerClient) SnapshotExport(w io.Writer, logoDir string) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	manifest := snapshotManifest{
+		Version:   snapshotVersion,
+		CreatedAt: time.Now().UTC(),
+		PortalURL: c.PortalURL,
+		MAC:       c.MAC,
+		Timezone:  c.Timezone,
+		Config:    c.config(),
+	}
+	if err := writeSnapshotJSON(tw, "manifest.json", manifest); err != nil {
+		return err
+	}
+
+	// Cached portal data
+	if channels, ok := c.channels.load(); ok {
+		info, _ := c.channels.info(0)
+		if err := writeSnapshotJSON(tw, "channels.json", snapshotChannels{FetchedAt: info.FetchedAt, Channels: channels}); err != nil {
+			return err
+		}
+	}
+	for i, id := range c.epg.channelIDs() {
+		programs, _ := c.epg.load(id)
+		info, _ := c.epg.slot(id).info(0)
+		entry := snapshotEPG{ChannelID: id, FetchedAt: info.FetchedAt, Programs: programs}
+		if err := writeSnapshotJSON(tw, fmt.Sprintf("epg/%d.json", i), entry); err != nil {
+			return err
+		}
+	}
+
+	// Logo files
+	if logoDir != "" {
+		entries, err := os.ReadDir(logoDir)
+		if err != nil {
+			return fmt.Errorf("failed to read logo directory %s: %w", logoDir, err)
+		}
+		for _, entry := range entries {
+			if !entry.Type().IsRegular() || strings.HasSuffix(entry.Name(), ".part") {
+				continue
+			}
+			if err := writeSnapshotFile(tw, "logos/"+entry.Name(), filepath.Join(logoDir, entry.Name())); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("failed to finalize snapshot: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return fmt.Errorf("failed to finalize snapshot: %w", err)
+	}
+	return nil
+}
+
+/* SnapshotImport restores configuration and cached data from a SnapshotExport archive, extracting logos into logoDir (skipped when empty); a client that already holds a token only accepts snapshots of its own portal, MAC, and timezone. */
+func (c *StalkerClient) SnapshotImport(r io.Reader, logoDir string) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return fmt.Errorf("failed to open snapshot: %w", err)
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read snapshot: %w", err)
+		}
+		name := path.Clean(hdr.Name)
+
+		switch {
+		case name == "manifest.json":
+			var manifest snapshotManifest
+			if err := json.NewDecoder(tr).Decode(&manifest); err != nil {
+				return fmt.Errorf("failed to parse snapshot manifest: %w", err)
+			}
+			if manifest.Version > snapshotVersion {
+				return fmt.Errorf("unsupported snapshot version %d", manifest.Version)
+			}
+			if err := c.importManifest(manifest); err != nil {
+				return err
+			}
+
+		case name == "channels.json":
+			var entry snapshotChannels
+			if err := json.NewDecoder(tr).Decode(&entry); err != nil {
+				return fmt.Errorf("failed to parse snapshot channels: %w", err)
+			}
+			c.channels.storeAt(entry.Channels, entry.FetchedAt)
+
+		case strings.HasPrefix(name, "epg/"):
+			var entry snapshotEPG
+			if err := json.NewDecoder(tr).Decode(&entry); err != nil {
+				return fmt.Errorf("failed to parse snapshot EPG %s: %w", name, err)
+			}
+			c.epg.slot(entry.ChannelID).storeAt(entry.Programs, entry.FetchedAt)
+
+		case strings.HasPrefix(name, "logos/") && logoDir != "":
+			// Only plain file names are extracted, never paths from the archive
+			base := path.Base(name)
+			if base == "." || base == ".." || hdr.Typeflag != tar.TypeReg {
+				continue
+			}
+			if err := extractSnapshotFile(tr, logoDir, base); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+/* importManifest applies the identity and configuration of a snapshot, changing the identity only while no handshake is running and no token was issued for the current one. */
+func (c *StalkerClient) importManifest(m snapshotManifest) error {
+	c.auth.mu.Lock()
+	defer c.auth.mu.Unlock()
+	if m.PortalURL != c.PortalURL || m.MAC != c.MAC || m.Timezone != c.Timezone {
+		if c.CurrentToken() != "" {
+			return fmt.Errorf("snapshot of %s (%s) cannot be imported into a client already authenticated as %s (%s)", m.PortalURL, m.MAC, c.PortalURL, c.MAC)
+		}
+		c.PortalURL, c.MAC, c.Timezone = m.PortalURL, m.MAC, m.Timezone
+	}
+	c.updateConfig(func(cfg *ServerConfig) { *cfg = m.Config })
+	return nil
+}
+
+/* writeSnapshotJSON adds a JSON-encoded entry to the archive. */
+func writeSnapshotJSON(tw *tar.Writer, name string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode snapshot entry %s: %w", name, err)
+	}
+	hdr := &tar.Header{Name: name, Mode: 0644, Size: int64(len(data)), ModTime: time.Now()}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return fmt.Errorf("failed to write snapshot entry %s: %w", name, err)
+	}
+	if _, err := tw.Write(data); err != nil {
+		return fmt.Errorf("failed to write snapshot entry %s: %w", name, err)
+	}
+	return nil
+}
+
+/* writeSnapshotFile adds a file from disk to the archive. */
+func writeSnapshotFile(tw *tar.Writer, name, filename string) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", filename, err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat %s: %w", filename, err)
+	}
+	hdr := &tar.Header{Name: name, Mode: 0644, Size: info.Size(), ModTime: info.ModTime()}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return fmt.Errorf("failed to write snapshot entry %s: %w", name, err)
+	}
+	if _, err := io.Copy(tw, f); err != nil {
+		return fmt.Errorf("failed to write snapshot entry %s: %w", name, err)
+	}
+	return nil
+}
+
+/* extractSnapshotFile writes the current archive entry to dir/name. */
+func extractSnapshotFile(r io.Reader, dir, name string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory %s: %w", dir, err)
+	}
+	filename := filepath.Join(dir, name)
+	out, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("failed to create file %s: %w", filename, err)
+	}
+	if _, err := io.Copy(out, r); err != nil {
+		out.Close()
+		return fmt.Errorf("failed to extract %s: %w", filename, err)
+	}
+	return out.Close()
+}
@@ -0,0 +1,68 @@
+package stalkerlib
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestSnapshotImport(t *testing.T) {
+	source := NewStalkerClient("http://portal.invalid", "00:1A:79:00:00:01", "Europe/Berlin")
+	source.Config = ServerConfig{SupportsGzip: true, APIPath: "/portal.php", TokenTransport: TokenInCookie}
+	source.channels.store([]Channel{{ID: "1", Name: "One"}})
+	var snapshot bytes.Buffer
+	if err := source.SnapshotExport(&snapshot, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		portal   string
+		mac      string
+		token    string
+		wantErr  string
+		wantURL  string
+		imported bool
+	}{
+		{"fresh client", "http://other.invalid", "00:1A:79:00:00:02", "", "", "http://portal.invalid", true},
+		{"authenticated with the same identity", "http://portal.invalid", "00:1A:79:00:00:01", "tok", "", "http://portal.invalid", true},
+		{"authenticated with another identity", "http://other.invalid", "00:1A:79:00:00:02", "tok", "already authenticated", "http://other.invalid", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewStalkerClient(tt.portal, tt.mac, "Europe/Berlin")
+			if tt.token != "" {
+				c.setToken(tt.token)
+			}
+
+			// Requests reading the configuration may run while the snapshot is imported
+			var wg sync.WaitGroup
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := 0; i < 100; i++ {
+					_ = c.config().APIPath
+				}
+			}()
+			err := c.SnapshotImport(bytes.NewReader(snapshot.Bytes()), "")
+			wg.Wait()
+
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("SnapshotImport = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("SnapshotImport = %v, want an error containing %q", err, tt.wantErr)
+			}
+			if c.PortalURL != tt.wantURL {
+				t.Errorf("PortalURL = %q, want %q", c.PortalURL, tt.wantURL)
+			}
+			if got := c.config(); (got.APIPath == "/portal.php" && got.TokenTransport == TokenInCookie) != tt.imported {
+				t.Errorf("config = %+v, imported = %v", got, tt.imported)
+			}
+			if channels, ok := c.channels.load(); ok != tt.imported || (ok && channels[0].Name != "One") {
+				t.Errorf("channels = %v, %v, imported = %v", channels, ok, tt.imported)
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package stalkerlib
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+/* SortStrategy selects how SortChannels orders a lineup. */
+type SortStrategy int
+
+const (
+	SortByNumber          SortStrategy = iota // Portal channel number, unnumbered channels last
+	SortByName                                // Natural name order, so "Channel 2" sorts before "Channel 10"
+	SortByGenreThenNumber                     // Genre ID, then portal channel number
+)
+
+/* ChannelSort makes exporters order the lineup with SortChannels instead of keeping the given order. */
+type ChannelSort struct {
+	Enabled  bool         // Sort the exported channels
+	Strategy SortStrategy // Order applied when enabled
+}
+
+/* SortChannels sorts channels in place with the given strategy, breaking ties by natural name order. */
+func SortChannels(channels []Channel, strategy SortStrategy) {
+	sort.SliceStable(channels, func(i, j int) bool {
+		a, b := channels[i], channels[j]
+		switch strategy {
+		case SortByNumber:
+			if c := compareChannelNumbers(a.Number, b.Number); c != 0 {
+				return c < 0
+			}
+		case SortByGenreThenNumber:
+			if a.GenreID != b.GenreID {
+				return NaturalLess(a.GenreID, b.GenreID)
+			}
+			if c := compareChannelNumbers(a.Number, b.Number); c != 0 {
+				return c < 0
+			}
+		}
+		return NaturalLess(a.Name, b.Name)
+	})
+}
+
+/* compareChannelNumbers orders numeric channel numbers ascending, placing missing or non-numeric ones last. */
+func compareChannelNumbers(a, b string) int {
+	na, errA := strconv.Atoi(strings.TrimSpace(a))
+	nb, errB := strconv.Atoi(strings.TrimSpace(b))
+	switch {
+	case errA != nil && errB != nil:
+		return 0
+	case errA != nil:
+		return 1
+	case errB != nil:
+		return -1
+	case na < nb:
+		return -1
+	case na > nb:
+		return 1
+	}
+	return 0
+}
+
+/* NaturalLess compares strings case-insensitively, treating digit runs as numbers. */
+func NaturalLess(a, b string) bool {
+	for a != "" && b != "" {
+		ra, _ := utf8.DecodeRuneInString(a)
+		rb, _ := utf8.DecodeRuneInString(b)
+		if isDigit(ra) && isDigit(rb) {
+			da, restA := splitDigits(a)
+			db, restB := splitDigits(b)
+			ta, tb := strings.TrimLeft(da, "0"), strings.TrimLeft(db, "0")
+			if len(ta) != len(tb) {
+				return len(ta) < len(tb)
+			}
+			if ta != tb {
+				return ta < tb
+			}
+			a, b = restA, restB
+			continue
+		}
+		la, lb := unicode.ToLower(ra), unicode.ToLower(rb)
+		if la != lb {
+			return la < lb
+		}
+		a, b = a[utf8.RuneLen(ra):], b[utf8.RuneLen(rb):]
+	}
+	return len(a) < len(b)
+}
+
+/* isDigit reports whether r is an ASCII digit. */
+func isDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
+
+/* splitDigits splits s into its leading run of ASCII digits and the remainder. */
+func splitDigits(s string) (string, string) {
+	i := 0
+	for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+		i++
+	}
+	return s[:i], s[i:]
+}
@@ -1,16 +1,22 @@
 package stalkerlib
 
 import (
-	"compress/gzip"
+	"context"
 	"encoding/json"
 	"encoding/xml"
+	"errors"
 	"fmt"
-	"io"
+	"net"
 	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/ericcmi/stalkerlib/matching"
 )
 
 /* StalkerClient represents a client for interacting with Stalker Middleware APIs.
@@ -19,14 +25,71 @@ type StalkerClient struct {
 	PortalURL string // Stalker portal base URL (e.g., http://example.com)
 	MAC       string // MAC address for authentication
 	Timezone  string // Timezone for EPG (e.g., UTC, America/New_York)
-	Token     string // Authentication token
 	Config    ServerConfig // Server-specific capabilities
+	Token     string // Deprecated: read CurrentToken instead; mirrors it, written under the handshake lock, and a token set here before the first call is used
+
+	httpClient   *http.Client         // Shared HTTP client built from the client options
+	resolver     *net.Resolver        // Custom resolver used when dialing the portal
+	family       AddressFamily        // Address family restriction for dialing
+	hostOverride string               // Host header and TLS server name presented to the portal
+	epg          epgCache             // Programs from the most recent GetEPG call per channel
+	channels     cacheSlot[[]Channel] // Channel list from the most recent GetChannels call
+	genres       *GenreMap            // Category normalization applied to guide and playlist exports
+	charset      string               // Forced response charset; detected when empty
+	sanitizer    *Sanitizer           // Markup cleanup applied to fetched EPG text
+
+	timeouts          Timeouts                 // Connection and request time limits
+	operationTimeouts map[string]time.Duration // Per-action overrides of the request limit
+	maxPerHost        int                      // Maximum in-flight requests per host, 0 for unlimited
+	life              lifecycle                // In-flight requests and background goroutines
+	offlineTTL        time.Duration            // Freshness window of the offline cache, 0 when disabled
+	events            eventBus                 // Subscribers to client lifecycle events
+	logoResolvers     []LogoResolver           // Fallback logo sources tried after the portal logo
+	history           HistoryStore             // Watch history of resolved playback URLs
+	reportPlayback    bool                     // Whether playback is reported to the portal
+	disableAds        bool                     // Value sent as disable_ad in create_link
+	redirects         *RedirectPolicy          // Redirect handling, nil for net/http defaults
+	responseObservers []func(ResponseInfo)     // Callbacks receiving per-call HTTP metadata
+	lineup            atomic.Pointer[Lineup]   // Index of the cached channel list
+	state             StateStore               // Persistent client state, nil when not persisted
+	probeTTL          time.Duration            // Reuse window of stored probe results
+	probeFailures     int32                    // Consecutive failed calls, accessed atomically
+	attemptTimeout    time.Duration            // Limit of each endpoint-variant attempt
+	configMu          sync.RWMutex             // Guards Config against concurrent probing, e.g. during Warmup; PostActions is replaced, never modified
+	device            DeviceIdentity           // Emulated set-top box identity
+	signer            RequestSigner            // Adds signature parameters to each action
+	protocol          ProtocolParams           // JsHttpRequest and version parameter overrides
+	diagnostics       func(ShapeDiagnostic)    // Receives descriptions of empty or unrecognized lists
+	epgStore          EPGStore                 // Persistent EPG storage for time-range queries
+	translator        *CategoryTranslator      // Category name translation applied to exports
+	maxResponseSizes  map[string]int64         // Per-action body limits, "" for the API default
+	limiter           hostLimiter              // Per-host request slots and 429 Retry-After pauses
+	rateLimitRetries  *int                     // Retries of 429 responses, nil for the default
+	slots             streamSlots              // Active playback sessions and the stream limit
+	screenshotURL     string                   // Screenshot location template, "" for the default
+	override          *ProbeOverride           // Pinned capabilities, nil when everything is probed
+	decoders          map[string]ContentDecoder // Content-Encodings added with WithContentDecoder
+	pagedChannels     bool                     // Fetch the lineup with get_ordered_list pages
+	channelPages      pageCheckpoint[Channel]  // Pages of an interrupted paged lineup fetch
+	listProgress      func(ListProgress)       // Paged listing progress callback, nil when unused
+	logoSize          int                      // Preferred logo size variant, 0 to keep the portal's
+	auth              tokenState               // Serializes handshakes and counts issued tokens
+	enricher          metadataEnrichment       // External metadata lookups for VOD and EPG movies
+	availability      atomic.Pointer[AvailabilityMonitor] // Checker whose dead channels exports skip, nil when not pruning
+	template          RequestTemplate          // Parameter and header mapping for rebranded middlewares
+	actions           actionCache              // Do responses cached per WithActionCache TTLs
+	dnsCache          *DNSCache                // Cached and pinned host lookups, nil to resolve on every dial
+	failover          *portalFailover          // Dial tuning and portal address health, nil without WithDialOptions
 }
 
 /* ServerConfig holds server-specific capabilities determined by probing. */
 type ServerConfig struct {
 	SupportsGzip      bool // Whether the server supports gzip-compressed responses
 	RequiresCreateLink bool // Whether the server requires create_link for playback URLs
+	APIPath           string // Detected API endpoint path, empty for /stalker_portal/server/load.php
+	PostActions       map[string]bool // Actions sent as form-encoded POST instead of GET
+	PortalVersion     string // Portal version from c/version.js, empty when unknown
+	TokenTransport    TokenTransport // How the token is sent, TokenInHeader unless overridden
 }
 
 /* HandshakeResponse represents the JSON response from the handshake action. */
@@ -38,10 +101,49 @@ type HandshakeResponse struct {
 
 /* Channel represents a single channel from the Stalker API. */
 type Channel struct {
-	ID   string `json:"id"`
-	Name string `json:"name"`
-	Cmd  string `json:"cmd"`
-	Logo string `json:"logo"`
+	ID           string `json:"id"`
+	Name         string `json:"name"`
+	Number       string `json:"number"`
+	GenreID      string `json:"tv_genre_id"`
+	XMLTVID      string `json:"xmltv_id"`
+	Cmd          string `json:"cmd"`
+	Logo         string `json:"logo"`
+	Archive      bool   `json:"tv_archive"`          // Whether the portal records the channel for catch-up
+	ArchiveHours int    `json:"tv_archive_duration"` // Catch-up depth in hours, 0 when not reported
+	HD           bool   `json:"hd"`                  // Whether the portal flags the channel as high definition
+	Region       string `json:"region,omitempty"`    // Region code from a name prefix like "UK|" or "US:", empty when untagged
+
+	Extras map[string]json.RawMessage `json:"-"` // Portal fields not modeled above, e.g. "censored" or "use_http_tmp_link"
+}
+
+/* UnmarshalJSON decodes a channel, accepting numeric or string IDs, numbers, archive settings, and HD flags, tags it with the region of its name prefix, and keeps unmodeled fields in Extras. */
+func (ch *Channel) UnmarshalJSON(data []byte) error {
+	type plain Channel
+	aux := struct {
+		ID      flexString `json:"id"`
+		Number  flexString `json:"number"`
+		GenreID flexString `json:"tv_genre_id"`
+		Archive flexString `json:"tv_archive"`
+		Hours   flexString `json:"tv_archive_duration"`
+		HD      flexString `json:"hd"`
+		*plain
+	}{plain: (*plain)(ch)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	ch.ID, ch.Number, ch.GenreID = string(aux.ID), string(aux.Number), string(aux.GenreID)
+	ch.Archive = aux.Archive == "1" || aux.Archive == "true"
+	ch.ArchiveHours, _ = strconv.Atoi(string(aux.Hours))
+	ch.HD = aux.HD == "1" || aux.HD == "true"
+	if ch.Region == "" {
+		ch.Region = matching.Region(ch.Name)
+	}
+	extras, err := channelExtras(data)
+	if err != nil {
+		return err
+	}
+	ch.Extras = extras
+	return nil
 }
 
 /* ChannelListResponse represents the JSON response from get_all_channels action. */
@@ -60,12 +162,19 @@ type CreateLinkResponse struct {
 
 /* EPGProgram represents a single EPG program entry. */
 type EPGProgram struct {
-	ChannelID string `json:"ch_id"`
-	Name      string `json:"name"`
-	Start     int64  `json:"start_timestamp"`
-	Stop      int64  `json:"stop_timestamp"`
-	Desc      string `json:"descr"`
-	Category  string `json:"category"`
+	ChannelID string    `json:"ch_id"`
+	Name      string    `json:"name"`
+	Start     int64     `json:"start_timestamp"`
+	Stop      int64     `json:"stop_timestamp"`
+	Desc      string    `json:"descr"`
+	Category  string    `json:"category"`
+	Icon      string    `json:"icon,omitempty"`      // Program image URL, when the portal has one
+	Season    int       `json:"season,omitempty"`    // Season number, 0 when unknown
+	Episode   int       `json:"episode,omitempty"`   // Episode number, 0 when unknown
+	Year      string    `json:"year,omitempty"`      // Production year, when the portal has it
+	Directors []string  `json:"directors,omitempty"` // Director names
+	Actors    []string  `json:"actors,omitempty"`    // Actor names
+	Metadata  *Metadata `json:"metadata,omitempty"`  // Enricher metadata, nil when not enriched
 }
 
 /* EPGResponse represents the JSON response from get_epg action. */
@@ -90,244 +199,271 @@ type XMLTVChannel struct {
 
 /* XMLTVProgram represents a program in XMLTV format. */
 type XMLTVProgram struct {
-	Start    string `xml:"start,attr"`
-	Stop     string `xml:"stop,attr"`
-	Channel  string `xml:"channel,attr"`
-	Title    string `xml:"title"`
-	Desc     string `xml:"desc"`
-	Category string `xml:"category"`
+	Start       string            `xml:"start,attr"`
+	Stop        string            `xml:"stop,attr"`
+	Channel     string            `xml:"channel,attr"`
+	Title       string            `xml:"title"`
+	Desc        string            `xml:"desc"`
+	Credits     *XMLTVCredits     `xml:"credits,omitempty"`
+	Date        string            `xml:"date,omitempty"`
+	Category    string            `xml:"category"`
+	Icon        *XMLTVIcon        `xml:"icon,omitempty"`
+	EpisodeNums []XMLTVEpisodeNum `xml:"episode-num,omitempty"`
+}
+
+/* XMLTVCredits lists the people of an XMLTV program. */
+type XMLTVCredits struct {
+	Directors []string `xml:"director"`
+	Actors    []string `xml:"actor"`
 }
 
-/* NewStalkerClient creates a new StalkerClient with the given portal URL, MAC address, and timezone. */
-func NewStalkerClient(portalURL, mac, timezone string) *StalkerClient {
-	return &StalkerClient{
+/* XMLTVIcon is the image of an XMLTV program. */
+type XMLTVIcon struct {
+	Src string `xml:"src,attr"`
+}
+
+/* XMLTVEpisodeNum is an XMLTV episode number in one numbering system. */
+type XMLTVEpisodeNum struct {
+	System string `xml:"system,attr"`
+	Value  string `xml:",chardata"`
+}
+
+/* NewStalkerClient creates a new StalkerClient with the given portal URL, MAC address, timezone, and options. */
+func NewStalkerClient(portalURL, mac, timezone string, opts ...Option) *StalkerClient {
+	c := &StalkerClient{
 		PortalURL: portalURL,
 		MAC:       mac,
 		Timezone:  timezone,
 	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	c.httpClient = c.newHTTPClient()
+	return c
 }
 
 /* Authenticate performs the handshake action to obtain a Bearer token. */
 func (c *StalkerClient) Authenticate() error {
-	// Build API URL for handshake
-	apiURL := fmt.Sprintf("%s/stalker_portal/server/load.php", c.PortalURL)
-	params := url.Values{
-		"type":          {"stb"},
-		"action":        {"handshake"},
-		"JsHttpRequest": {"1-xml"},
-	}
-	req, err := http.NewRequest("GET", apiURL+"?"+params.Encode(), nil)
-	if err != nil {
-		return fmt.Errorf("failed to create handshake request: %w", err)
-	}
+	return c.authenticate(context.Background())
+}
 
-	// Set headers to mimic STB
-	req.Header.Set("Cookie", fmt.Sprintf("mac=%s; stb_lang=en; timezone=%s", c.MAC, c.Timezone))
-	req.Header.Set("User-Agent", "Mozilla/5.0 (QtEmbedded; U; Linux; C)")
+/* AuthenticateContext is Authenticate under ctx, which may carry a Priority or request ID. */
+func (c *StalkerClient) AuthenticateContext(ctx context.Context) error {
+	return c.authenticate(ctx)
+}
 
-	// Send request
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return fmt.Errorf("handshake request failed: %w", err)
-	}
-	defer resp.Body.Close()
+/* authenticate implements Authenticate under the given context. */
+func (c *StalkerClient) authenticate(ctx context.Context) error {
+	c.auth.mu.Lock()
+	defer c.auth.mu.Unlock()
+	return c.handshakeLocked(ctx)
+}
 
-	// Parse response
+/* handshakeLocked obtains a new token; the caller holds c.auth.mu. */
+func (c *StalkerClient) handshakeLocked(ctx context.Context) error {
+	params := url.Values{}
+	if metrics, ok := c.metrics(); ok {
+		params.Set("metrics", metrics)
+	}
 	var response HandshakeResponse
-	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-		return fmt.Errorf("failed to parse handshake response: %w", err)
+	if err := c.callAction(ctx, "stb", "handshake", params, &response); err != nil {
+		return err
+	}
+	event := EventAuthenticated
+	if c.CurrentToken() != "" {
+		event = EventTokenRefreshed
 	}
-	c.Token = response.Js.Token
+	c.setToken(response.Js.Token)
+	c.saveSession()
+	c.emit(Event{Type: event})
 	return nil
 }
 
 /* ProbeServer tests server capabilities (gzip support, create_link requirement). */
 func (c *StalkerClient) ProbeServer() error {
-	// Test gzip support
-	apiURL := fmt.Sprintf("%s/stalker_portal/server/load.php", c.PortalURL)
-	params := url.Values{
-		"type":          {"itv"},
-		"action":        {"get_all_channels"},
-		"gzip":          {"true"},
-		"JsHttpRequest": {"1-xml"},
+	return c.probeServer(context.Background())
+}
+
+/* probeServer implements ProbeServer under the given context, reusing a fresh stored result when a probe TTL is set and applying any probe override. */
+func (c *StalkerClient) probeServer(ctx context.Context) error {
+	if c.override != nil && c.override.complete() {
+		c.applyOverride()
+		return nil
+	}
+	if c.loadProbe() {
+		c.applyOverride()
+		return nil
+	}
+	if err := c.runProbe(ctx); err != nil {
+		return err
 	}
-	req, _ := http.NewRequest("GET", apiURL+"?"+params.Encode(), nil)
-	req.Header.Set("Accept-Encoding", "gzip")
-	req.Header.Set("Cookie", fmt.Sprintf("mac=%s; stb_lang=en; timezone=%s", c.MAC, c.Timezone))
+	c.applyOverride()
+	c.saveProbe()
+	return nil
+}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err == nil && resp.Header.Get("Content-Encoding") == "gzip" {
-		c.Config.SupportsGzip = true
+/* runProbe measures the server capabilities, first discovering the endpoint path and portal version when not yet known. */
+func (c *StalkerClient) runProbe(ctx context.Context) error {
+	// Keep the default path if no variant answers
+	if c.config().APIPath == "" {
+		c.discoverEndpoint(ctx)
 	}
-	if resp != nil {
-		resp.Body.Close()
+	if c.config().PortalVersion == "" {
+		c.detectPortalVersion(ctx)
 	}
 
-	// Test create_link requirement
-	params.Set("action", "create_link")
-	params.Set("cmd", "test_channel")
-	req, _ = http.NewRequest("GET", apiURL+"?"+params.Encode(), nil)
-	req.Header.Set("Cookie", fmt.Sprintf("mac=%s; stb_lang=en; timezone=%s", c.MAC, c.Timezone))
-	resp, err = client.Do(req)
-	if err == nil && resp.StatusCode == 200 {
-		var response CreateLinkResponse
-		if err := json.NewDecoder(resp.Body).Decode(&response); err == nil && response.Js.Cmd != "" {
-			c.Config.RequiresCreateLink = true
-		}
+	// Test gzip support; portals that ignore the negotiation answer uncompressed
+	resp, err := c.sendAction(ctx, "itv", "get_all_channels", url.Values{"gzip": {"true"}})
+	if isBuildError(err) {
+		return fmt.Errorf("failed to create probe request: %w", err)
 	}
-	if resp != nil {
+	if err == nil {
+		if resp.Uncompressed {
+			c.updateConfig(func(cfg *ServerConfig) { cfg.SupportsGzip = true })
+		}
 		resp.Body.Close()
 	}
+
+	// Test create_link requirement; the link needs a token, shared with a concurrent handshake
+	var response CreateLinkResponse
+	err = c.doAction(ctx, "itv", "create_link", url.Values{"cmd": {"test_channel"}}, &response)
+	if isBuildError(err) {
+		return fmt.Errorf("failed to create probe request: %w", err)
+	}
+	if err == nil && response.Js.Cmd != "" {
+		c.updateConfig(func(cfg *ServerConfig) { cfg.RequiresCreateLink = true })
+	}
 	return nil
 }
 
 /* GetChannels fetches all channels, optionally using gzip compression. */
 func (c *StalkerClient) GetChannels() ([]Channel, error) {
-	// Authenticate if no token
-	if c.Token == "" {
-		if err := c.Authenticate(); err != nil {
-			return nil, err
-		}
+	return c.getChannels(context.Background())
+}
+
+/* GetChannelsContext is GetChannels under ctx, which may carry a Priority or request ID. */
+func (c *StalkerClient) GetChannelsContext(ctx context.Context) ([]Channel, error) {
+	return c.getChannels(ctx)
+}
+
+/* getChannels implements GetChannels under the given context, serving from the offline cache when enabled. */
+func (c *StalkerClient) getChannels(ctx context.Context) ([]Channel, error) {
+	if c.offlineTTL > 0 {
+		return c.channels.readThrough(ctx, c.baseContext(), c.offlineTTL, c.fetchChannels)
 	}
+	return c.fetchChannels(ctx)
+}
 
-	// Build API URL for channels
-	apiURL := fmt.Sprintf("%s/stalker_portal/server/load.php", c.PortalURL)
-	params := url.Values{
-		"type":          {"itv"},
-		"action":        {"get_all_channels"},
-		"JsHttpRequest": {"1-xml"},
+/* fetchChannels requests the channel list from the portal and caches it. */
+func (c *StalkerClient) fetchChannels(ctx context.Context) ([]Channel, error) {
+	if c.pagedChannels {
+		channels, err := c.fetchChannelPages(ctx)
+		if err != nil {
+			return nil, err
+		}
+		c.channels.store(channels)
+		c.emit(Event{Type: EventChannelsUpdated})
+		return channels, nil
 	}
-	if c.Config.SupportsGzip {
+	params := url.Values{}
+	if c.config().SupportsGzip {
 		params.Set("gzip", "true")
 	}
-	req, err := http.NewRequest("GET", apiURL+"?"+params.Encode(), nil)
+	var response portalEnvelope
+	if err := c.doAction(ctx, "itv", "get_all_channels", params, &response); err != nil {
+		return nil, err
+	}
+	channels, err := decodeChannelList(response)
+	c.diagnose("get_all_channels", response, len(channels), err)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create channels request: %w", err)
+		return nil, fmt.Errorf("failed to parse channels response: %w", err)
 	}
+	c.channels.store(channels)
+	c.emit(Event{Type: EventChannelsUpdated})
+	return channels, nil
+}
 
-	// Set headers
-	req.Header.Set("Authorization", "Bearer "+c.Token)
-	req.Header.Set("Cookie", fmt.Sprintf("mac=%s; stb_lang=en; timezone=%s", c.MAC, c.Timezone))
-	if c.Config.SupportsGzip {
-		req.Header.Set("Accept-Encoding", "gzip")
-	}
+/* GetPlaybackURL fetches the playback URL for a channel, using create_link if required; under WithStreamLimit it is refused or queued while every slot is taken, but the returned URL holds no slot, so use StartPlayback for streams that must count against the limit. */
+func (c *StalkerClient) GetPlaybackURL(channelCmd string) (string, error) {
+	return c.admittedPlaybackURL(context.Background(), channelCmd)
+}
+
+/* GetPlaybackURLContext is GetPlaybackURL under ctx, which may carry a Priority or request ID and bounds a queued admission. */
+func (c *StalkerClient) GetPlaybackURLContext(ctx context.Context, channelCmd string) (string, error) {
+	return c.admittedPlaybackURL(ctx, channelCmd)
+}
 
-	// Send request
-	client := &http.Client{}
-	resp, err := client.Do(req)
+/* getPlaybackURL implements GetPlaybackURL under the given context, recording and reporting successful resolutions. */
+func (c *StalkerClient) getPlaybackURL(ctx context.Context, channelCmd string) (string, error) {
+	playURL, err := c.resolvePlaybackURL(ctx, channelCmd)
 	if err != nil {
-		return nil, fmt.Errorf("channels request failed: %w", err)
+		return "", err
 	}
-	defer resp.Body.Close()
-
-	// Handle gzip compression
-	var reader io.Reader = resp.Body
-	if c.Config.SupportsGzip && resp.Header.Get("Content-Encoding") == "gzip" {
-		gz, err := gzip.NewReader(resp.Body)
-		if err != nil {
-			return nil, fmt.Errorf("failed to create gzip reader: %w", err)
+	c.recordPlayback(channelCmd)
+	if c.reportPlayback {
+		// Reporting is best effort and must not block playback
+		if ch, ok := c.channelByCmd(channelCmd); ok {
+			go c.reportPlaybackEvent(c.baseContext(), ch)
 		}
-		defer gz.Close()
-		reader = gz
-	}
-
-	// Parse response
-	var response ChannelListResponse
-	if err := json.NewDecoder(reader).Decode(&response); err != nil {
-		return nil, fmt.Errorf("failed to parse channels response: %w", err)
 	}
-	return response.Js.Channels, nil
+	return playURL, nil
 }
 
-/* GetPlaybackURL fetches the playback URL for a channel, using create_link if required. */
-func (c *StalkerClient) GetPlaybackURL(channelCmd string) (string, error) {
+/* resolvePlaybackURL returns the direct URL or requests a temporary one with create_link. */
+func (c *StalkerClient) resolvePlaybackURL(ctx context.Context, channelCmd string) (string, error) {
 	// Return direct URL if create_link is not required
-	if !c.Config.RequiresCreateLink {
+	if !c.config().RequiresCreateLink {
 		return channelCmd, nil
 	}
 
-	// Authenticate if no token
-	if c.Token == "" {
-		if err := c.Authenticate(); err != nil {
-			return "", err
-		}
+	disableAd := "0"
+	if c.disableAds {
+		disableAd = "1"
 	}
-
-	// Build API URL for create_link
-	apiURL := fmt.Sprintf("%s/stalker_portal/server/load.php", c.PortalURL)
 	params := url.Values{
-		"type":           {"itv"},
-		"action":         {"create_link"},
 		"cmd":            {channelCmd},
 		"forced_storage": {"undefined"},
-		"disable_ad":     {"0"},
-		"JsHttpRequest":  {"1-xml"},
+		"disable_ad":     {disableAd},
 	}
-	req, err := http.NewRequest("GET", apiURL+"?"+params.Encode(), nil)
-	if err != nil {
-		return "", fmt.Errorf("failed to create playback URL request: %w", err)
-	}
-
-	// Set headers
-	req.Header.Set("Authorization", "Bearer "+c.Token)
-	req.Header.Set("Cookie", fmt.Sprintf("mac=%s; stb_lang=en; timezone=%s", c.MAC, c.Timezone))
-	req.Header.Set("User-Agent", "Mozilla/5.0 (QtEmbedded; U; Linux; C)")
-
-	// Send request
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", fmt.Errorf("playback URL request failed: %w", err)
-	}
-	defer resp.Body.Close()
-
-	// Parse response
 	var response CreateLinkResponse
-	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-		return "", fmt.Errorf("failed to parse playback URL response: %w", err)
+	if err := c.doAction(ctx, "itv", "create_link", params, &response); err != nil {
+		return "", err
+	}
+	if c.redirects != nil && c.redirects.PinPlaybackURL {
+		return c.pinPlaybackURL(ctx, response.Js.Cmd)
 	}
 	return response.Js.Cmd, nil
 }
 
 /* GetEPG fetches EPG data for a channel with timezone adjustment. */
 func (c *StalkerClient) GetEPG(channelID string) ([]EPGProgram, error) {
-	// Authenticate if no token
-	if c.Token == "" {
-		if err := c.Authenticate(); err != nil {
-			return nil, err
-		}
-	}
-
-	// Build API URL for EPG
-	apiURL := fmt.Sprintf("%s/stalker_portal/server/load.php", c.PortalURL)
-	params := url.Values{
-		"type":          {"itv"},
-		"action":        {"get_epg"},
-		"ch_id":         {channelID},
-		"JsHttpRequest": {"1-xml"},
-	}
-	req, err := http.NewRequest("GET", apiURL+"?"+params.Encode(), nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create EPG request: %w", err)
-	}
+	return c.getEPG(context.Background(), channelID)
+}
 
-	// Set headers
-	req.Header.Set("Authorization", "Bearer "+c.Token)
-	req.Header.Set("Cookie", fmt.Sprintf("mac=%s; stb_lang=en; timezone=%s", c.MAC, c.Timezone))
+/* GetEPGContext is GetEPG under ctx, which may carry a Priority or request ID. */
+func (c *StalkerClient) GetEPGContext(ctx context.Context, channelID string) ([]EPGProgram, error) {
+	return c.getEPG(ctx, channelID)
+}
 
-	// Send request
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("EPG request failed: %w", err)
+/* getEPG implements GetEPG under the given context, serving from the offline cache when enabled. */
+func (c *StalkerClient) getEPG(ctx context.Context, channelID string) ([]EPGProgram, error) {
+	if c.offlineTTL > 0 {
+		return c.epg.slot(channelID).readThrough(ctx, c.baseContext(), c.offlineTTL, func(ctx context.Context) ([]EPGProgram, error) {
+			return c.fetchEPG(ctx, channelID)
+		})
 	}
-	defer resp.Body.Close()
+	return c.fetchEPG(ctx, channelID)
+}
 
-	// Parse response
-	var epgResp EPGResponse
-	if err := json.NewDecoder(resp.Body).Decode(&epgResp); err != nil {
+/* fetchEPG requests a channel's EPG from the portal and caches it. */
+func (c *StalkerClient) fetchEPG(ctx context.Context, channelID string) ([]EPGProgram, error) {
+	var epgResp portalEnvelope
+	if err := c.doAction(ctx, "itv", "get_epg", url.Values{"ch_id": {channelID}}, &epgResp); err != nil {
+		return nil, err
+	}
+	programs, err := decodeProgramList(epgResp)
+	c.diagnose("get_epg", epgResp, len(programs), err)
+	if err != nil {
 		return nil, fmt.Errorf("failed to parse EPG response: %w", err)
 	}
 
@@ -336,34 +472,43 @@ func (c *StalkerClient) GetEPG(channelID string) ([]EPGProgram, error) {
 	if err != nil {
 		return nil, fmt.Errorf("invalid timezone %s: %w", c.Timezone, err)
 	}
-	for i, p := range epgResp.Js.Programs {
-		epgResp.Js.Programs[i].Start = time.Unix(p.Start, 0).In(loc).Unix()
-		epgResp.Js.Programs[i].Stop = time.Unix(p.Stop, 0).In(loc).Unix()
+	for i, p := range programs {
+		programs[i].Start = time.Unix(p.Start, 0).In(loc).Unix()
+		programs[i].Stop = time.Unix(p.Stop, 0).In(loc).Unix()
+		if c.sanitizer != nil {
+			programs[i].Name = c.sanitizer.Clean(p.Name)
+			programs[i].Desc = c.sanitizer.Clean(p.Desc)
+		}
 	}
-	return epgResp.Js.Programs, nil
+	c.enrichPrograms(ctx, programs)
+	c.epg.store(channelID, programs)
+	if c.epgStore != nil {
+		// A failing store must not cost the caller the programs it already has
+		if err := c.epgStore.PutPrograms(channelID, programs); err != nil {
+			c.emit(Event{Type: EventEPGStoreFailed, ChannelID: channelID, Err: fmt.Errorf("failed to store EPG: %w", err)})
+		}
+	}
+	c.emit(Event{Type: EventEPGUpdated, ChannelID: channelID})
+	return programs, nil
 }
 
 /* ConvertEPGToXMLTV converts EPG data to XMLTV format. */
 func (c *StalkerClient) ConvertEPGToXMLTV(channelID string, programs []EPGProgram) (string, error) {
+	return c.ConvertEPGToXMLTVWithOptions(channelID, programs, XMLTVOptions{})
+}
+
+/* ConvertEPGToXMLTVWithOptions converts EPG data to XMLTV format, with times in the zone of opts.Location as ExportXMLTV writes them. */
+func (c *StalkerClient) ConvertEPGToXMLTVWithOptions(channelID string, programs []EPGProgram, opts XMLTVOptions) (string, error) {
 	// Create XMLTV structure
-	loc, err := time.LoadLocation(c.Timezone)
+	loc, err := c.xmltvLocation(opts)
 	if err != nil {
-		return "", fmt.Errorf("invalid timezone %s: %w", c.Timezone, err)
+		return "", err
 	}
 	xmltv := XMLTV{
 		Channels: []XMLTVChannel{{ID: channelID, DisplayName: channelID}},
 	}
 	for _, p := range programs {
-		startTime := time.Unix(p.Start, 0).In(loc).Format("20060102150405 -0700")
-		stopTime := time.Unix(p.Stop, 0).In(loc).Format("20060102150405 -0700")
-		xmltv.Programs = append(xmltv.Programs, XMLTVProgram{
-			Start:    startTime,
-			Stop:     stopTime,
-			Channel:  p.ChannelID,
-			Title:    p.Name,
-			Desc:     p.Desc,
-			Category: p.Category,
-		})
+		xmltv.Programs = append(xmltv.Programs, c.xmltvProgram(p, loc))
 	}
 
 	// Marshal to XML
@@ -374,52 +519,67 @@ func (c *StalkerClient) ConvertEPGToXMLTV(channelID string, programs []EPGProgra
 	return string(output), nil
 }
 
-/* DownloadChannelLogo downloads a channel logo to the specified directory with a custom filename format. */
+/* DownloadChannelLogo downloads a channel logo to the specified directory with a custom filename format, falling back to the configured LogoResolvers. */
 func (c *StalkerClient) DownloadChannelLogo(logoURL, outputDir, filenameFormat string, channel Channel) error {
-	// Validate logo URL
-	if logoURL == "" {
-		return fmt.Errorf("no logo URL provided for channel %s", channel.Name)
-	}
+	_, _, err := c.downloadChannelLogo(logoURL, outputDir, filenameFormat, channel)
+	return err
+}
 
-	// Build full logo URL
-	u, err := url.Parse(logoURL)
-	if err != nil {
-		return fmt.Errorf("invalid logo URL %s: %w", logoURL, err)
-	}
-	if !u.IsAbs() {
-		u, err = url.Parse(fmt.Sprintf("%s/stalker_portal%s", c.PortalURL, logoURL))
+/* logoSources collects the candidate URLs of a channel logo: the portal logo first, then the configured fallbacks. */
+func (c *StalkerClient) logoSources(logoURL string, channel Channel) ([]string, error) {
+	var candidates []string
+	var errs []error
+	if logoURL != "" {
+		portalCandidates, err := c.logoCandidates(logoURL)
 		if err != nil {
-			return fmt.Errorf("failed to construct logo URL: %w", err)
+			errs = append(errs, err)
+		}
+		candidates = append(candidates, portalCandidates...)
+	}
+	for _, r := range c.logoResolvers {
+		if u, ok := r.ResolveLogo(channel); ok {
+			candidates = append(candidates, u)
+		}
+	}
+	if len(candidates) == 0 {
+		if len(errs) > 0 {
+			return nil, errs[0]
 		}
+		return nil, fmt.Errorf("no logo URL provided for channel %s", channel.Name)
 	}
+	return candidates, nil
+}
 
-	// Download logo
-	resp, err := http.Get(u.String())
+/* logoFilename formats the file a channel logo is saved to. */
+func logoFilename(outputDir, filenameFormat string, channel Channel) string {
+	return filepath.Join(outputDir, fmt.Sprintf(filenameFormat, channel.ID, channel.Name))
+}
+
+/* downloadChannelLogo implements DownloadChannelLogo, returning the saved file and the URL it came from. */
+func (c *StalkerClient) downloadChannelLogo(logoURL, outputDir, filenameFormat string, channel Channel) (string, string, error) {
+	candidates, err := c.logoSources(logoURL, channel)
 	if err != nil {
-		return fmt.Errorf("failed to download logo %s: %w", u.String(), err)
+		return "", "", err
 	}
-	defer resp.Body.Close()
 
 	// Create output directory
 	if err := os.MkdirAll(outputDir, 0755); err != nil {
-		return fmt.Errorf("failed to create output directory %s: %w", outputDir, err)
-	}
-
-	// Format filename
-	filename := filenameFormat
-	filename = fmt.Sprintf(filename, channel.ID, channel.Name)
-	filename = filepath.Join(outputDir, filename)
-
-	// Save file
-	out, err := os.Create(filename)
-	if err != nil {
-		return fmt.Errorf("failed to create file %s: %w", filename, err)
+		return "", "", fmt.Errorf("failed to create output directory %s: %w", outputDir, err)
 	}
-	defer out.Close()
+	filename := logoFilename(outputDir, filenameFormat, channel)
 
-	_, err = io.Copy(out, resp.Body)
-	if err != nil {
-		return fmt.Errorf("failed to save logo %s: %w", filename, err)
+	// Download logo, resuming any partial file from an earlier attempt
+	var errs []error
+	for i, u := range candidates {
+		err := c.DownloadFile(u, filename)
+		if err == nil {
+			return filename, u, nil
+		}
+		errs = append(errs, err)
+		if i < len(candidates)-1 {
+			// A partial file from one source must not be resumed from another
+			os.Remove(filename + ".part")
+		}
 	}
-	return nil
+	return "", "", errors.Join(errs...)
 }
@@ -0,0 +1,180 @@
+package stalkerlib
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+/* StateStore persists small pieces of client state, such as probe results, across restarts. */
+type StateStore interface {
+	Load(key string) (data []byte, ok bool, err error) // ok is false when nothing is stored under key
+	Save(key string, data []byte) error
+	Delete(key string) error
+}
+
+/* WithStateStore persists client state in store: the session token, so restarts resume without a handshake, and probe results when WithProbeTTL is set. */
+func WithStateStore(store StateStore) Option {
+	return func(c *StalkerClient) {
+		c.state = store
+	}
+}
+
+/* stateKey namespaces name by portal and MAC so several clients can share one store. */
+func (c *StalkerClient) stateKey(name string) string {
+	sum := sha256.Sum256([]byte(c.PortalURL + "|" + c.MAC))
+	return name + "-" + hex.EncodeToString(sum[:8])
+}
+
+/* sessionState is the stored session of one portal account. */
+type sessionState struct {
+	Token   string    `json:"token"`
+	SavedAt time.Time `json:"saved_at"`
+}
+
+/* loadSession adopts the stored session token, reporting whether one was found; a stale token is replaced by the handshake its first rejection triggers. The caller holds c.auth.mu. */
+func (c *StalkerClient) loadSession() bool {
+	if c.state == nil {
+		return false
+	}
+	data, ok, err := c.state.Load(c.stateKey("session"))
+	if err != nil || !ok {
+		return false
+	}
+	var session sessionState
+	if err := json.Unmarshal(data, &session); err != nil || session.Token == "" {
+		return false
+	}
+	c.setToken(session.Token)
+	return true
+}
+
+/* saveSession stores the current session token. */
+func (c *StalkerClient) saveSession() {
+	if c.state == nil {
+		return
+	}
+	data, err := json.Marshal(sessionState{Token: c.CurrentToken(), SavedAt: time.Now()})
+	if err != nil {
+		return
+	}
+	c.state.Save(c.stateKey("session"), data)
+}
+
+/* MemoryStateStore is an in-process StateStore, useful for tests and short-lived tools. */
+type MemoryStateStore struct {
+	mu   sync.Mutex
+	data map[string][]byte
+}
+
+/* NewMemoryStateStore creates an empty in-memory store. */
+func NewMemoryStateStore() *MemoryStateStore {
+	return &MemoryStateStore{data: make(map[string][]byte)}
+}
+
+/* Load returns a copy of the data stored under key. */
+func (s *MemoryStateStore) Load(key string) ([]byte, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, ok := s.data[key]
+	return append([]byte(nil), data...), ok, nil
+}
+
+/* Save stores a copy of data under key. */
+func (s *MemoryStateStore) Save(key string, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data[key] = append([]byte(nil), data...)
+	return nil
+}
+
+/* Delete removes key. */
+func (s *MemoryStateStore) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.data, key)
+	return nil
+}
+
+/* FileStateStore keeps each key in its own file under a directory. */
+type FileStateStore struct {
+	dir string
+}
+
+/* NewFileStateStore creates a store in dir, creating the directory if needed. */
+func NewFileStateStore(dir string) (*FileStateStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &FileStateStore{dir: dir}, nil
+}
+
+/* path maps key to a file name inside the store directory. */
+func (s *FileStateStore) path(key string) string {
+	return filepath.Join(s.dir, strings.NewReplacer("/", "_", "\\", "_", "..", "_").Replace(key)+".state")
+}
+
+/* Load reads the file stored under key. */
+func (s *FileStateStore) Load(key string) ([]byte, bool, error) {
+	data, err := os.ReadFile(s.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return data, true, nil
+}
+
+/* Save atomically replaces the file stored under key. */
+func (s *FileStateStore) Save(key string, data []byte) error {
+	return writeFileAtomic(s.path(key), 0600, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+}
+
+/* Delete removes the file stored under key; deleting a missing key is not an error. */
+func (s *FileStateStore) Delete(key string) error {
+	if err := os.Remove(s.path(key)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+/* writeFileAtomic writes filename with permissions perm through a uniquely named temporary file in the same directory, renamed into place, so readers never see a partial file and concurrent writers never share one. */
+func writeFileAtomic(filename string, perm os.FileMode, write func(w io.Writer) error) error {
+	f, err := os.CreateTemp(filepath.Dir(filename), "."+filepath.Base(filename)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file for %s: %w", filename, err)
+	}
+	tmp := f.Name()
+	if err := write(f); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write %s: %w", filename, err)
+	}
+	if err := f.Chmod(perm); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write %s: %w", filename, err)
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write %s: %w", filename, err)
+	}
+	if err := os.Rename(tmp, filename); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
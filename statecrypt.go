@@ -0,0 +1,147 @@
+package stalkerlib
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+/* ErrStateDecrypt is returned by an encrypted state store when a record fails authentication, typically because of a wrong key or passphrase. */
+var ErrStateDecrypt = errors.New("stalkerlib: cannot decrypt state record")
+
+/* Encrypted record layout: magic, then the passphrase salt (empty for raw keys), the GCM nonce and the sealed data. */
+const (
+	stateMagic      = "SLE1"
+	stateSaltSize   = 16
+	stateKDFRounds  = 600000
+	stateKeySize    = 32
+	stateNonceSize  = 12
+	stateHeaderSize = len(stateMagic) + stateSaltSize + stateNonceSize
+)
+
+/* EncryptedStateStore seals every record of an underlying StateStore with AES-GCM, so shared cache directories do not leak session tokens or portal capabilities. */
+type EncryptedStateStore struct {
+	store      StateStore
+	passphrase []byte // Nil when a raw key is used
+
+	mu   sync.Mutex
+	salt []byte      // Salt of the key used for new records
+	aead cipher.AEAD // Cipher of the current salt
+}
+
+/* NewEncryptedStateStore wraps store with a raw AES key of 16, 24, or 32 bytes. */
+func NewEncryptedStateStore(store StateStore, key []byte) (*EncryptedStateStore, error) {
+	aead, err := newStateAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	return &EncryptedStateStore{store: store, salt: make([]byte, stateSaltSize), aead: aead}, nil
+}
+
+/* NewPassphraseStateStore wraps store with a key derived from passphrase by PBKDF2-SHA256, salted per store instance. */
+func NewPassphraseStateStore(store StateStore, passphrase string) (*EncryptedStateStore, error) {
+	if passphrase == "" {
+		return nil, errors.New("stalkerlib: empty state passphrase")
+	}
+	salt := make([]byte, stateSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	s := &EncryptedStateStore{store: store, passphrase: []byte(passphrase)}
+	aead, err := s.deriveAEAD(salt)
+	if err != nil {
+		return nil, err
+	}
+	s.salt, s.aead = salt, aead
+	return s, nil
+}
+
+/* Load opens the record stored under key. */
+func (s *EncryptedStateStore) Load(key string) ([]byte, bool, error) {
+	sealed, ok, err := s.store.Load(key)
+	if err != nil || !ok {
+		return nil, ok, err
+	}
+	if len(sealed) < stateHeaderSize || string(sealed[:len(stateMagic)]) != stateMagic {
+		return nil, false, ErrStateDecrypt
+	}
+	salt := sealed[len(stateMagic) : len(stateMagic)+stateSaltSize]
+	nonce := sealed[len(stateMagic)+stateSaltSize : stateHeaderSize]
+	aead, err := s.cipherFor(salt)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// Bind the record to its key so files cannot be swapped between keys
+	data, err := aead.Open(nil, nonce, sealed[stateHeaderSize:], []byte(key))
+	if err != nil {
+		return nil, false, ErrStateDecrypt
+	}
+	return data, true, nil
+}
+
+/* Save seals data and stores it under key. */
+func (s *EncryptedStateStore) Save(key string, data []byte) error {
+	s.mu.Lock()
+	salt, aead := s.salt, s.aead
+	s.mu.Unlock()
+	nonce := make([]byte, stateNonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	sealed := make([]byte, 0, stateHeaderSize+len(data)+aead.Overhead())
+	sealed = append(sealed, stateMagic...)
+	sealed = append(sealed, salt...)
+	sealed = append(sealed, nonce...)
+	sealed = aead.Seal(sealed, nonce, data, []byte(key))
+	return s.store.Save(key, sealed)
+}
+
+/* Delete removes key from the underlying store. */
+func (s *EncryptedStateStore) Delete(key string) error {
+	return s.store.Delete(key)
+}
+
+/* cipherFor returns the cipher of a record salt, deriving it from the passphrase when it differs from the current one. */
+func (s *EncryptedStateStore) cipherFor(salt []byte) (cipher.AEAD, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if bytes.Equal(salt, s.salt) {
+		return s.aead, nil
+	}
+	if s.passphrase == nil {
+		return nil, ErrStateDecrypt
+	}
+
+	// Adopt the stored salt so later loads and saves skip the derivation
+	aead, err := s.deriveAEAD(salt)
+	if err != nil {
+		return nil, err
+	}
+	s.salt, s.aead = append([]byte(nil), salt...), aead
+	return aead, nil
+}
+
+/* deriveAEAD derives the passphrase key for salt. */
+func (s *EncryptedStateStore) deriveAEAD(salt []byte) (cipher.AEAD, error) {
+	key, err := pbkdf2.Key(sha256.New, string(s.passphrase), salt, stateKDFRounds, stateKeySize)
+	if err != nil {
+		return nil, err
+	}
+	return newStateAEAD(key)
+}
+
+/* newStateAEAD creates the AES-GCM cipher for key. */
+func newStateAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid state encryption key: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,167 @@
+package stalkerlib
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestEncryptedStateStoreRoundTrip(t *testing.T) {
+	key := bytes.Repeat([]byte{7}, 32)
+	raw := NewMemoryStateStore()
+	rawStore, err := NewEncryptedStateStore(raw, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	phrase := NewMemoryStateStore()
+	phraseStore, err := NewPassphraseStateStore(phrase, "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A new instance derives its own salt and must still open older records
+	reopened, err := NewPassphraseStateStore(phrase, "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		save, load StateStore
+		under      *MemoryStateStore
+	}{
+		{"raw key", rawStore, rawStore, raw},
+		{"passphrase", phraseStore, phraseStore, phrase},
+		{"passphrase after restart", phraseStore, reopened, phrase},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := []byte(`{"token":"secret-token"}`)
+			if err := tt.save.Save("session", want); err != nil {
+				t.Fatal(err)
+			}
+			if sealed, _, _ := tt.under.Load("session"); bytes.Contains(sealed, []byte("secret-token")) {
+				t.Errorf("underlying store holds the plaintext: %q", sealed)
+			}
+			got, ok, err := tt.load.Load("session")
+			if err != nil || !ok || !bytes.Equal(got, want) {
+				t.Errorf("Load = %q, %v, %v, want %q, true, nil", got, ok, err, want)
+			}
+			if _, ok, err := tt.load.Load("missing"); ok || err != nil {
+				t.Errorf("Load(missing) = %v, %v, want false, nil", ok, err)
+			}
+		})
+	}
+}
+
+func TestEncryptedStateStoreWrongKey(t *testing.T) {
+	under := NewMemoryStateStore()
+	store, err := NewPassphraseStateStore(under, "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Save("session", []byte("data")); err != nil {
+		t.Fatal(err)
+	}
+	rawUnder := NewMemoryStateStore()
+	rawStore, err := NewEncryptedStateStore(rawUnder, bytes.Repeat([]byte{1}, 16))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rawStore.Save("session", []byte("data")); err != nil {
+		t.Fatal(err)
+	}
+
+	wrongPhrase, err := NewPassphraseStateStore(under, "battery staple")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrongKey, err := NewEncryptedStateStore(rawUnder, bytes.Repeat([]byte{2}, 16))
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyOnPhrase, err := NewEncryptedStateStore(under, bytes.Repeat([]byte{1}, 16))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		store StateStore
+	}{
+		{"wrong passphrase", wrongPhrase},
+		{"wrong raw key", wrongKey},
+		{"raw key on passphrase records", keyOnPhrase},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if data, ok, err := tt.store.Load("session"); !errors.Is(err, ErrStateDecrypt) || ok || data != nil {
+				t.Errorf("Load = %q, %v, %v, want nil, false, ErrStateDecrypt", data, ok, err)
+			}
+		})
+	}
+
+	if _, err := NewEncryptedStateStore(under, []byte("short")); err == nil {
+		t.Error("NewEncryptedStateStore accepted a 5-byte key")
+	}
+	if _, err := NewPassphraseStateStore(under, ""); err == nil {
+		t.Error("NewPassphraseStateStore accepted an empty passphrase")
+	}
+}
+
+func TestEncryptedStateStoreTamper(t *testing.T) {
+	tests := []struct {
+		name   string
+		tamper func(under *MemoryStateStore, sealed []byte)
+	}{
+		{"ciphertext", func(under *MemoryStateStore, sealed []byte) {
+			sealed[len(sealed)-20] ^= 1
+			under.Save("session", sealed)
+		}},
+		{"tag", func(under *MemoryStateStore, sealed []byte) {
+			sealed[len(sealed)-1] ^= 1
+			under.Save("session", sealed)
+		}},
+		{"nonce", func(under *MemoryStateStore, sealed []byte) {
+			sealed[len(stateMagic)+stateSaltSize] ^= 1
+			under.Save("session", sealed)
+		}},
+		{"salt", func(under *MemoryStateStore, sealed []byte) {
+			sealed[len(stateMagic)] ^= 1
+			under.Save("session", sealed)
+		}},
+		{"magic", func(under *MemoryStateStore, sealed []byte) {
+			sealed[0] = 'X'
+			under.Save("session", sealed)
+		}},
+		{"truncated", func(under *MemoryStateStore, sealed []byte) {
+			under.Save("session", sealed[:stateHeaderSize-1])
+		}},
+		{"plaintext", func(under *MemoryStateStore, sealed []byte) {
+			under.Save("session", []byte(`{"token":"injected"}`))
+		}},
+		{"record moved from another key", func(under *MemoryStateStore, sealed []byte) {
+			other, _, _ := under.Load("probe")
+			under.Save("session", other)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			under := NewMemoryStateStore()
+			store, err := NewEncryptedStateStore(under, bytes.Repeat([]byte{7}, 32))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := store.Save("session", []byte(`{"token":"secret-token-of-some-length"}`)); err != nil {
+				t.Fatal(err)
+			}
+			if err := store.Save("probe", []byte(`{"gzip":true}`)); err != nil {
+				t.Fatal(err)
+			}
+			sealed, _, _ := under.Load("session")
+			tt.tamper(under, sealed)
+			if data, ok, err := store.Load("session"); !errors.Is(err, ErrStateDecrypt) || ok || data != nil {
+				t.Errorf("Load = %q, %v, %v, want nil, false, ErrStateDecrypt", data, ok, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,164 @@
+package stalkerlib
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+/* STRMOptions configures ExportSTRMLibrary. */
+type STRMOptions struct {
+	URL func(item VODItem, episode int) (string, error) // Playback URL written to each .strm, e.g. a relay endpoint; nil resolves create_link now, whose links may expire
+	NFO bool                                            // Also write Kodi movie.nfo, tvshow.nfo, and episode NFO files
+}
+
+/* ExportSTRMLibrary writes a media-center library of .strm files below dir, films under "Movies/<Title (Year)>/" and series under "TV Shows/<Title>/" with one "<Title> SxxEyy" file per episode, the seasons portals list as separate items, such as "Title Season 2", sharing their show's folder; unnamed items are filed under their ID, which is also appended to names another item already took, and results are keyed by VOD item ID. */
+func (c *StalkerClient) ExportSTRMLibrary(dir string, items []VODItem, opts STRMOptions) *BatchResult {
+	result := &BatchResult{}
+	resolve := opts.URL
+	if resolve == nil {
+		resolve = c.GetVODPlaybackURL
+	}
+	movies, shows := make(strmNames), strmShows{names: make(strmNames)}
+	for _, item := range items {
+		var err error
+		if item.Series {
+			show, season := seriesSeason(item.Name)
+			folder, first := shows.folder(safeFilename(show), season, item)
+			err = writeSeriesSTRM(dir, folder, season, item, resolve, opts.NFO, first)
+		} else {
+			name := safeFilename(item.Name)
+			if year := vodYear(item); year != "" && name != "" {
+				name += " (" + year + ")"
+			}
+			err = writeMovieSTRM(dir, movies.name(name, item), item, resolve, opts.NFO)
+		}
+		result.add(item.ID, err)
+	}
+	return result
+}
+
+/* strmNames hands out the folder names of one library section, keyed case-insensitively as on Windows and macOS filesystems. */
+type strmNames map[string]bool
+
+/* name returns base for item, the item ID when base is empty, and base with the ID appended when another item already took it. */
+func (n strmNames) name(base string, item VODItem) string {
+	id := safeFilename(item.ID)
+	if base == "" {
+		base = id
+	}
+	if base == "" || n[strings.ToLower(base)] {
+		base = strings.TrimSpace(base + " [" + id + "]")
+	}
+	n[strings.ToLower(base)] = true
+	return base
+}
+
+/* strmShows hands out the folders of the TV section, so the seasons of one show share a folder while a second item for the same season gets its own. */
+type strmShows struct {
+	names   strmNames
+	folders map[string]string       // Folder of each show title, keyed case-insensitively
+	seasons map[string]map[int]bool // Seasons already filed in each folder
+}
+
+/* folder returns the folder of season of show and whether the folder is new. */
+func (s *strmShows) folder(show string, season int, item VODItem) (string, bool) {
+	key := strings.ToLower(show)
+	if folder, ok := s.folders[key]; ok && show != "" && !s.seasons[folder][season] {
+		s.seasons[folder][season] = true
+		return folder, false
+	}
+	folder := s.names.name(show, item)
+	if s.folders == nil {
+		s.folders, s.seasons = make(map[string]string), make(map[string]map[int]bool)
+	}
+	if _, ok := s.folders[key]; !ok && show != "" {
+		s.folders[key] = folder
+	}
+	s.seasons[folder] = map[int]bool{season: true}
+	return folder, true
+}
+
+/* seasonMarkerPattern matches a trailing season marker of a series title, e.g. "Season 2", "(Staffel 2)", "S02", or "2 сезон". */
+var seasonMarkerPattern = regexp.MustCompile(`(?i)[\s\-–:,.(\[]*(?:\b(?:season|saison|staffel|temporada|stagione|seizoen|sezon)\s*(\d{1,3})|сезон\s*(\d{1,3})|\bS(\d{1,3})|\b(\d{1,3})\s*(?:сезон|season))[)\]]?\s*$`)
+
+/* seriesSeason splits a series title into the show title and the season its marker names, season 1 when it has none. */
+func seriesSeason(title string) (string, int) {
+	m := seasonMarkerPattern.FindStringSubmatchIndex(title)
+	if m == nil || m[0] == 0 {
+		return title, 1
+	}
+	for i := 2; i < len(m); i += 2 {
+		if m[i] >= 0 {
+			season, _ := strconv.Atoi(title[m[i]:m[i+1]])
+			return strings.TrimSpace(title[:m[0]]), season
+		}
+	}
+	return title, 1
+}
+
+/* writeMovieSTRM writes the .strm file and optional movie.nfo of a film into the folder name. */
+func writeMovieSTRM(dir, name string, item VODItem, resolve func(VODItem, int) (string, error), nfo bool) error {
+	folder := filepath.Join(dir, "Movies", name)
+	if err := os.MkdirAll(folder, 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", folder, err)
+	}
+	if err := writeSTRM(filepath.Join(folder, name+".strm"), item, 0, resolve); err != nil {
+		return err
+	}
+	if nfo {
+		return writeNFO(filepath.Join(folder, "movie.nfo"), movieNFO(item))
+	}
+	return nil
+}
+
+/* writeSeriesSTRM writes one .strm file per episode of a season of a series into the folder title, with optional episode NFO files and, for the first season filed there, tvshow.nfo. */
+func writeSeriesSTRM(dir, title string, season int, item VODItem, resolve func(VODItem, int) (string, error), nfo, newShow bool) error {
+	folder := filepath.Join(dir, "TV Shows", title)
+	if err := os.MkdirAll(folder, 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", folder, err)
+	}
+	if nfo && newShow {
+		if err := writeNFO(filepath.Join(folder, "tvshow.nfo"), tvShowNFO(item)); err != nil {
+			return err
+		}
+	}
+	for _, episode := range item.Episodes {
+		base := filepath.Join(folder, fmt.Sprintf("%s S%02dE%02d", title, season, episode))
+		if err := writeSTRM(base+".strm", item, episode, resolve); err != nil {
+			return err
+		}
+		if nfo {
+			if err := writeNFO(base+".nfo", episodeNFO(item, season, episode)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+/* writeSTRM writes the playback URL of an item or episode into a .strm file. */
+func writeSTRM(filename string, item VODItem, episode int, resolve func(VODItem, int) (string, error)) error {
+	playURL, err := resolve(item, episode)
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s: %w", item.Name, err)
+	}
+	if err := os.WriteFile(filename, []byte(playURL+"\n"), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filename, err)
+	}
+	return nil
+}
+
+/* safeFilename replaces characters that are invalid in file names on common filesystems. */
+func safeFilename(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`/\:*?"<>|`, r) || r < 0x20 {
+			return '_'
+		}
+		return r
+	}, name)
+	return strings.Trim(strings.TrimSpace(name), ".")
+}
@@ -0,0 +1,81 @@
+package stalkerlib
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestSeriesSeason(t *testing.T) {
+	tests := []struct {
+		title  string
+		show   string
+		season int
+	}{
+		{"Breaking Bad", "Breaking Bad", 1},
+		{"Breaking Bad Season 2", "Breaking Bad", 2},
+		{"Breaking Bad - Season 03", "Breaking Bad", 3},
+		{"Dark (Staffel 2)", "Dark", 2},
+		{"La Casa de Papel: Temporada 4", "La Casa de Papel", 4},
+		{"Fargo S04", "Fargo", 4},
+		{"Метод 2 сезон", "Метод", 2},
+		{"Метод (Сезон 2)", "Метод", 2},
+		{"Season 2", "Season 2", 1},
+		{"Apollo 13", "Apollo 13", 1},
+		{"Battlestar Galactica", "Battlestar Galactica", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			show, season := seriesSeason(tt.title)
+			if show != tt.show || season != tt.season {
+				t.Errorf("seriesSeason(%q) = %q, %d, want %q, %d", tt.title, show, season, tt.show, tt.season)
+			}
+		})
+	}
+}
+
+func TestExportSTRMLibrarySeasons(t *testing.T) {
+	dir := t.TempDir()
+	items := []VODItem{
+		{ID: "1", Name: "Dark Season 1", Series: true, Episodes: []int{1, 2}},
+		{ID: "2", Name: "Dark Season 2", Series: true, Episodes: []int{1}},
+		{ID: "3", Name: "Fargo", Series: true, Episodes: []int{1}},
+		{ID: "4", Name: "dark season 2", Series: true, Episodes: []int{1}},
+	}
+	c := NewStalkerClient("http://portal.invalid", "00:1A:79:00:00:01", "UTC")
+	result := c.ExportSTRMLibrary(dir, items, STRMOptions{
+		URL: func(VODItem, int) (string, error) { return "http://relay.invalid/vod", nil },
+		NFO: true,
+	})
+	if err := result.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	var files []string
+	filepath.WalkDir(filepath.Join(dir, "TV Shows"), func(path string, d os.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			rel, _ := filepath.Rel(dir, path)
+			files = append(files, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	want := []string{
+		"TV Shows/Dark/Dark S01E01.nfo",
+		"TV Shows/Dark/Dark S01E01.strm",
+		"TV Shows/Dark/Dark S01E02.nfo",
+		"TV Shows/Dark/Dark S01E02.strm",
+		"TV Shows/Dark/Dark S02E01.nfo",
+		"TV Shows/Dark/Dark S02E01.strm",
+		"TV Shows/Dark/tvshow.nfo",
+		"TV Shows/Fargo/Fargo S01E01.nfo",
+		"TV Shows/Fargo/Fargo S01E01.strm",
+		"TV Shows/Fargo/tvshow.nfo",
+		"TV Shows/dark [4]/dark [4] S02E01.nfo",
+		"TV Shows/dark [4]/dark [4] S02E01.strm",
+		"TV Shows/dark [4]/tvshow.nfo",
+	}
+	if !slices.Equal(files, want) {
+		t.Errorf("library = %q, want %q", files, want)
+	}
+}
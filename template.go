@@ -0,0 +1,92 @@
+package stalkerlib
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+/* RequestTemplate adapts load.php requests to rebranded middlewares that rename or relocate parameters, e.g. taking the MAC as a query parameter instead of a cookie. Params and Headers values may contain the placeholders {mac}, {token}, {timezone}, {serial}, {model}, and {device_id}. */
+type RequestTemplate struct {
+	Rename      map[string]string `json:"rename,omitempty"`       // Query parameter renames, e.g. {"type": "t"}
+	Params      map[string]string `json:"params,omitempty"`       // Query parameters added to every action, e.g. {"mac": "{mac}"}
+	Headers     map[string]string `json:"headers,omitempty"`      // Headers added or replaced on every action, e.g. {"X-Device": "{serial}"}
+	DropHeaders []string          `json:"drop_headers,omitempty"` // STB headers left out, e.g. "Cookie"
+}
+
+/* LoadRequestTemplate reads a RequestTemplate from a JSON file such as {"params": {"mac": "{mac}"}, "drop_headers": ["Cookie"]}. */
+func LoadRequestTemplate(path string) (RequestTemplate, error) {
+	var t RequestTemplate
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return t, fmt.Errorf("failed to read request template: %w", err)
+	}
+	if err := json.Unmarshal(data, &t); err != nil {
+		return t, fmt.Errorf("failed to parse request template %s: %w", path, err)
+	}
+	return t, nil
+}
+
+/* WithRequestTemplate applies t to every load.php action; signers see the templated parameters. */
+func WithRequestTemplate(t RequestTemplate) Option {
+	return func(c *StalkerClient) {
+		c.template = t
+	}
+}
+
+/* param returns the name a standard parameter is sent under. */
+func (t RequestTemplate) param(name string) string {
+	if renamed, ok := t.Rename[name]; ok && renamed != "" {
+		return renamed
+	}
+	return name
+}
+
+/* applyParams renames and adds query parameters; applying it again to its own output changes nothing. */
+func (c *StalkerClient) applyParams(params url.Values) {
+	t := c.template
+	for from, to := range t.Rename {
+		if values, ok := params[from]; ok && to != "" && to != from {
+			params[to] = values
+			delete(params, from)
+		}
+	}
+	if len(t.Params) == 0 {
+		return
+	}
+	expand := c.templateReplacer()
+	for name, value := range t.Params {
+		params.Set(name, expand.Replace(value))
+	}
+}
+
+/* applyHeaders drops and sets the template's headers on an action request. */
+func (c *StalkerClient) applyHeaders(header http.Header) {
+	t := c.template
+	for _, name := range t.DropHeaders {
+		header.Del(name)
+	}
+	if len(t.Headers) == 0 {
+		return
+	}
+	expand := c.templateReplacer()
+	for name, value := range t.Headers {
+		header.Set(name, expand.Replace(value))
+	}
+}
+
+/* templateReplacer expands the template placeholders with the client's current values. */
+func (c *StalkerClient) templateReplacer() *strings.Replacer {
+	id := c.identity()
+	return strings.NewReplacer(
+		"{mac}", id.MAC,
+		"{token}", c.CurrentToken(),
+		"{timezone}", c.Timezone,
+		"{serial}", id.SerialNumber,
+		"{model}", id.Model,
+		"{device_id}", id.DeviceID,
+	)
+}
@@ -0,0 +1,56 @@
+package stalkerlib
+
+import (
+	"context"
+	"time"
+)
+
+/* Timeouts configures network time limits; zero values keep the defaults (30s dial, 10s TLS handshake, no header or request limit). */
+type Timeouts struct {
+	Dial           time.Duration // Establishing the TCP connection
+	TLSHandshake   time.Duration // Completing the TLS handshake
+	ResponseHeader time.Duration // Waiting for response headers after the request is sent
+	Request        time.Duration // Whole request, including reading the response body
+}
+
+/* WithTimeouts sets the connection and request time limits used for all portal calls. */
+func WithTimeouts(t Timeouts) Option {
+	return func(c *StalkerClient) {
+		c.timeouts = t
+	}
+}
+
+/* WithOperationTimeout overrides the total request limit for one portal action (e.g. "get_epg", or "download" for DownloadFile), 0 meaning unlimited. */
+func WithOperationTimeout(action string, d time.Duration) Option {
+	return func(c *StalkerClient) {
+		if c.operationTimeouts == nil {
+			c.operationTimeouts = make(map[string]time.Duration)
+		}
+		c.operationTimeouts[action] = d
+	}
+}
+
+/* requestContext derives a context from parent that is canceled when the client closes and bounded by action's request limit. */
+func (c *StalkerClient) requestContext(parent context.Context, action string) (context.Context, context.CancelFunc) {
+	// Keep a caller-supplied priority, otherwise queue by the action's default
+	if _, ok := parent.Value(priorityKey{}).(Priority); !ok {
+		parent = ContextWithPriority(parent, actionPriority(action))
+	}
+	parent = withCallID(context.WithValue(parent, actionKey{}, action))
+	limit := c.timeouts.Request
+	if d, ok := c.operationTimeouts[action]; ok {
+		limit = d
+	}
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if limit > 0 {
+		ctx, cancel = context.WithTimeout(parent, limit)
+	} else {
+		ctx, cancel = context.WithCancel(parent)
+	}
+	stop := context.AfterFunc(c.baseContext(), cancel)
+	return ctx, func() {
+		stop()
+		cancel()
+	}
+}
@@ -0,0 +1,64 @@
+package stalkerlib
+
+import (
+	"slices"
+	"strconv"
+	"time"
+)
+
+/* TimeshiftOptions makes exporters add "+N" duplicates of archive-capable channels, e.g. "BBC One +1", that play the channel's recording from N hours ago through the relay's catch-up endpoint. */
+type TimeshiftOptions struct {
+	Hours  []int                 // Offsets in hours to synthesize, e.g. {1, 2}
+	Filter func(ch Channel) bool // Selects the channels to duplicate; nil selects every archive channel
+}
+
+/* shiftedChannel is an exported channel, either a portal channel or a time-shifted duplicate of one. */
+type shiftedChannel struct {
+	Channel
+	source string // Portal channel ID the entry plays and takes its programs from
+	hours  int    // Offset in hours, 0 for the portal channel itself
+}
+
+/* expand returns the channels in order, each followed by its time-shifted duplicates when shifts is true. */
+func (o TimeshiftOptions) expand(channels []Channel, shifts bool) []shiftedChannel {
+	entries := make([]shiftedChannel, 0, len(channels))
+	for _, ch := range channels {
+		entries = append(entries, shiftedChannel{Channel: ch, source: ch.ID})
+		if !shifts || !ch.Archive || (o.Filter != nil && !o.Filter(ch)) {
+			continue
+		}
+		for _, hours := range o.Hours {
+			// The recording must still exist at the shifted position
+			if hours <= 0 || (ch.ArchiveHours > 0 && hours > ch.ArchiveHours) {
+				continue
+			}
+			suffix := "+" + strconv.Itoa(hours)
+			shifted := ch
+			shifted.ID += suffix
+			shifted.Name += " " + suffix
+			if shifted.XMLTVID != "" {
+				shifted.XMLTVID += suffix
+			}
+			shifted.Number = ""
+			shifted.Archive, shifted.ArchiveHours = false, 0
+			entries = append(entries, shiftedChannel{Channel: shifted, source: ch.ID, hours: hours})
+		}
+	}
+	return entries
+}
+
+/* programs returns the programs of the entry, moved later by its offset. */
+func (e shiftedChannel) programs(programs map[string][]EPGProgram) []EPGProgram {
+	progs := programs[e.source]
+	if e.hours == 0 {
+		return progs
+	}
+	shift := int64(time.Duration(e.hours) * time.Hour / time.Second)
+	progs = slices.Clone(progs)
+	for i := range progs {
+		progs[i].ChannelID = e.ID
+		progs[i].Start += shift
+		progs[i].Stop += shift
+	}
+	return progs
+}
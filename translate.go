@@ -0,0 +1,108 @@
+package stalkerlib
+
+import (
+	"strings"
+	"sync"
+)
+
+/* defaultCategoryTranslations maps common Russian and Ukrainian portal genre and category names, lowercased, to English. */
+var defaultCategoryTranslations = map[string]string{
+	"фильмы":          "Movies",
+	"кино":            "Movies",
+	"фільми":          "Movies",
+	"сериалы":         "Series",
+	"серіали":         "Series",
+	"новости":         "News",
+	"новини":          "News",
+	"информационные":  "News",
+	"спорт":           "Sports",
+	"спортивные":      "Sports",
+	"детские":         "Kids",
+	"дитячі":          "Kids",
+	"мультфильмы":     "Cartoons",
+	"мультфільми":     "Cartoons",
+	"музыка":          "Music",
+	"музыкальные":     "Music",
+	"музика":          "Music",
+	"познавательные":  "Educational",
+	"пізнавальні":     "Educational",
+	"документальные":  "Documentary",
+	"документальні":   "Documentary",
+	"развлекательные": "Entertainment",
+	"розважальні":     "Entertainment",
+	"юмор":            "Comedy",
+	"природа":         "Nature",
+	"путешествия":     "Travel",
+	"кулинария":       "Cooking",
+	"бизнес":          "Business",
+	"религиозные":     "Religious",
+	"региональные":    "Regional",
+	"регіональні":     "Regional",
+	"федеральные":     "Federal",
+	"эфирные":         "Broadcast",
+	"взрослые":        "Adult",
+	"для взрослых":    "Adult",
+	"hd каналы":       "HD Channels",
+	"другие":          "Other",
+	"інші":            "Other",
+	"все":             "All",
+	"всі":             "All",
+}
+
+/* CategoryTranslator translates whole genre and category names during export, with user overrides taking precedence over the built-in table. */
+type CategoryTranslator struct {
+	mu     sync.RWMutex
+	custom map[string]string
+}
+
+/* NewCategoryTranslator creates a translator backed by the built-in table. */
+func NewCategoryTranslator() *CategoryTranslator {
+	return &CategoryTranslator{custom: make(map[string]string)}
+}
+
+/* Set translates the category name from (case-insensitive) to to, overriding any built-in translation. */
+func (t *CategoryTranslator) Set(from, to string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.custom[categoryKey(from)] = to
+}
+
+/* Translate returns the translation of name, or name unchanged when it has none. */
+func (t *CategoryTranslator) Translate(name string) string {
+	key := categoryKey(name)
+	if key == "" {
+		return name
+	}
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if to, ok := t.custom[key]; ok {
+		return to
+	}
+	if to, ok := defaultCategoryTranslations[key]; ok {
+		return to
+	}
+	return name
+}
+
+/* categoryKey lowercases name and collapses its whitespace for table lookups. */
+func categoryKey(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
+
+/* WithCategoryTranslator translates program categories through t in XMLTV and iCalendar exports and genre group titles in M3U exports, before any genre normalization. */
+func WithCategoryTranslator(t *CategoryTranslator) Option {
+	return func(c *StalkerClient) {
+		c.translator = t
+	}
+}
+
+/* exportCategory applies the category translator and then the genre map to a category name for export. */
+func (c *StalkerClient) exportCategory(category string) string {
+	if c.translator != nil {
+		category = c.translator.Translate(category)
+	}
+	if c.genres != nil {
+		category = c.genres.Normalize(category)
+	}
+	return category
+}
@@ -0,0 +1,123 @@
+package stalkerlib
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+/* client returns the shared HTTP client, falling back to the default client for StalkerClients built without NewStalkerClient. */
+func (c *StalkerClient) client() *http.Client {
+	if c.httpClient == nil {
+		return http.DefaultClient
+	}
+	return c.httpClient
+}
+
+/* newHTTPClient builds the shared HTTP client from the client's network options. */
+func (c *StalkerClient) newHTTPClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   orDefault(c.timeouts.Dial, 30*time.Second),
+		KeepAlive: 30 * time.Second,
+		Resolver:  c.resolver,
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSHandshakeTimeout = orDefault(c.timeouts.TLSHandshake, 10*time.Second)
+	transport.ResponseHeaderTimeout = c.timeouts.ResponseHeader
+	if c.failover != nil {
+		dialer.FallbackDelay = c.failover.opts.FallbackDelay
+	}
+	transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return c.dial(ctx, dialer, network, addr)
+	}
+
+	var rt http.RoundTripper = transport
+	if c.hostOverride != "" {
+		portalHost := ""
+		if u, err := url.Parse(c.PortalURL); err == nil {
+			portalHost = u.Host
+		}
+		transport.DialTLSContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return c.dialTLS(ctx, transport, network, addr, portalHost)
+		}
+		rt = &hostOverrideTransport{base: transport, portalHost: portalHost, host: c.hostOverride}
+	}
+	if c.maxPerHost > 0 {
+		transport.MaxConnsPerHost = c.maxPerHost
+	}
+	c.limiter.limit = c.maxPerHost
+	rt = &limitTransport{base: rt, limiter: &c.limiter}
+	httpClient := &http.Client{Transport: &trackingTransport{base: rt, client: c}}
+	if c.redirects != nil {
+		httpClient.CheckRedirect = c.redirects.checkRedirect
+	}
+	return httpClient
+}
+
+/* dialTLS dials a TLS connection, presenting the overridden server name when connecting to the portal address. */
+func (c *StalkerClient) dialTLS(ctx context.Context, transport *http.Transport, network, addr, portalHost string) (net.Conn, error) {
+	conn, err := transport.DialContext(ctx, network, addr)
+	if err != nil {
+		return nil, err
+	}
+	serverName, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		serverName = addr
+	}
+	if addr == portalHost || serverName == portalHost {
+		serverName, _, err = net.SplitHostPort(c.hostOverride)
+		if err != nil {
+			serverName = c.hostOverride
+		}
+	}
+	config := transport.TLSClientConfig.Clone()
+	if config == nil {
+		config = &tls.Config{}
+	}
+	config.ServerName = serverName
+	tlsConn := tls.Client(conn, config)
+	hsCtx, cancel := context.WithTimeout(ctx, transport.TLSHandshakeTimeout)
+	defer cancel()
+	if err := tlsConn.HandshakeContext(hsCtx); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return tlsConn, nil
+}
+
+/* hostOverrideTransport rewrites the Host header of requests addressed to the portal. */
+type hostOverrideTransport struct {
+	base       http.RoundTripper
+	portalHost string
+	host       string
+}
+
+func (t *hostOverrideTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Host == t.portalHost {
+		req = req.Clone(req.Context())
+		req.Host = t.host
+	}
+	return t.base.RoundTrip(req)
+}
+
+/* network narrows a dial network such as "tcp" to the address family-specific variant. */
+func (f AddressFamily) network(network string) string {
+	switch f {
+	case AddressFamilyIPv4:
+		return network + "4"
+	case AddressFamilyIPv6:
+		return network + "6"
+	}
+	return network
+}
+
+/* orDefault returns d, or def when d is not set. */
+func orDefault(d, def time.Duration) time.Duration {
+	if d > 0 {
+		return d
+	}
+	return def
+}
@@ -0,0 +1,156 @@
+package stalkerlib
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+/* VODItem is a film or series of the portal's video library. */
+type VODItem struct {
+	ID           string    // VOD item ID
+	Name         string    // Display title
+	OriginalName string    // Title in the original language, when the portal has it
+	Description  string    // Plot summary
+	Year         string    // Release year as reported by the portal
+	Rating       float64   // IMDb rating, or the portal's other rating when missing; 0 when unrated
+	Poster       string    // Cover image URL
+	Cmd          string    // Command passed to create_link for playback
+	CategoryID   string    // VOD category ID
+	GenreIDs     []string  // VOD genre IDs
+	Director     string    // Director names as listed by the portal
+	Actors       string    // Actor names as listed by the portal
+	Duration     int       // Running time in minutes, 0 when unknown
+	Added        time.Time // When the item was added to the portal, zero when unknown
+	Series       bool      // Whether the item is a series with episodes
+	Episodes     []int     // Episode numbers of a series, passed as series to create_link
+	Metadata     *Metadata // Enricher metadata, nil when not enriched
+}
+
+/* vodAddedLayout is the format of the portal's added timestamps. */
+const vodAddedLayout = "2006-01-02 15:04:05"
+
+/* UnmarshalJSON decodes a portal VOD entry, accepting numeric or string fields. */
+func (v *VODItem) UnmarshalJSON(data []byte) error {
+	var aux struct {
+		ID         flexString      `json:"id"`
+		Name       string          `json:"name"`
+		OName      string          `json:"o_name"`
+		Desc       string          `json:"description"`
+		Year       flexString      `json:"year"`
+		IMDb       flexString      `json:"rating_imdb"`
+		Kinopoisk  flexString      `json:"rating_kinopoisk"`
+		Screenshot string          `json:"screenshot_uri"`
+		Cmd        string          `json:"cmd"`
+		CategoryID flexString      `json:"category_id"`
+		GenreID    flexString      `json:"genre_id"`
+		GenreIDs   []flexString    `json:"genres_ids"`
+		Director   string          `json:"director"`
+		Actors     string          `json:"actors"`
+		Time       flexString      `json:"time"`
+		Added      string          `json:"added"`
+		IsSeries   flexString      `json:"is_series"`
+		Series     json.RawMessage `json:"series"`
+	}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	*v = VODItem{
+		ID:           string(aux.ID),
+		Name:         aux.Name,
+		OriginalName: aux.OName,
+		Description:  aux.Desc,
+		Year:         string(aux.Year),
+		Poster:       aux.Screenshot,
+		Cmd:          aux.Cmd,
+		CategoryID:   string(aux.CategoryID),
+		Director:     aux.Director,
+		Actors:       aux.Actors,
+		Series:       aux.IsSeries == "1",
+	}
+	for _, r := range []flexString{aux.IMDb, aux.Kinopoisk} {
+		if rating, err := strconv.ParseFloat(string(r), 64); err == nil && rating > 0 {
+			v.Rating = rating
+			break
+		}
+	}
+	for _, id := range aux.GenreIDs {
+		v.GenreIDs = append(v.GenreIDs, string(id))
+	}
+	if len(v.GenreIDs) == 0 && aux.GenreID != "" {
+		v.GenreIDs = []string{string(aux.GenreID)}
+	}
+	// Films report series as 0 or "", series as an array of episode numbers
+	var episodes []flexString
+	json.Unmarshal(aux.Series, &episodes)
+	for _, e := range episodes {
+		if n, err := strconv.Atoi(string(e)); err == nil {
+			v.Episodes = append(v.Episodes, n)
+		}
+	}
+	v.Duration, _ = strconv.Atoi(string(aux.Time))
+	if added, err := time.ParseInLocation(vodAddedLayout, aux.Added, time.UTC); err == nil {
+		v.Added = added
+	}
+	return nil
+}
+
+/* VODSort is a sortby value of the portal's VOD listings. */
+type VODSort string
+
+const (
+	VODSortAdded  VODSort = "added"  // Newest first
+	VODSortRating VODSort = "rating" // Best rated first
+	VODSortName   VODSort = "name"   // Alphabetical
+)
+
+/* VODQuery selects and orders a VOD listing. */
+type VODQuery struct {
+	Category string  // Category ID, "" for every category
+	Genre    string  // Genre ID within the category, "" for every genre
+	SortBy   VODSort // Portal sort order, "" for the portal default
+	Limit    int     // Maximum items, 0 to fetch every page
+}
+
+/* GetVODItems lists VOD items with get_ordered_list, fetching pages until q.Limit items are collected, and attaches enricher metadata. */
+func (c *StalkerClient) GetVODItems(q VODQuery) ([]VODItem, error) {
+	params := url.Values{
+		"category": {firstNonEmpty(q.Category, "*")},
+		"genre":    {firstNonEmpty(q.Genre, "*")},
+	}
+	if q.SortBy != "" {
+		params.Set("sortby", string(q.SortBy))
+	}
+	ctx := context.Background()
+	items, err := fetchOrderedList[VODItem](ctx, c, "vod", params, q.Limit)
+	if err != nil {
+		return nil, err
+	}
+	c.enrichVOD(ctx, items)
+	return items, nil
+}
+
+/* GetRecentVOD returns the limit most recently added VOD items, for "recently added" rows. */
+func (c *StalkerClient) GetRecentVOD(limit int) ([]VODItem, error) {
+	return c.GetVODItems(VODQuery{SortBy: VODSortAdded, Limit: limit})
+}
+
+/* GetTopRatedVOD returns the limit best rated VOD items, for "popular" rows. */
+func (c *StalkerClient) GetTopRatedVOD(limit int) ([]VODItem, error) {
+	return c.GetVODItems(VODQuery{SortBy: VODSortRating, Limit: limit})
+}
+
+/* GetVODPlaybackURL requests a temporary playback URL for a film, or for episode of a series (0 for films), with the vod create_link action. */
+func (c *StalkerClient) GetVODPlaybackURL(item VODItem, episode int) (string, error) {
+	params := url.Values{"cmd": {item.Cmd}}
+	if episode > 0 {
+		params.Set("series", strconv.Itoa(episode))
+	}
+	var response CreateLinkResponse
+	if err := c.doAction(context.Background(), "vod", "create_link", params, &response); err != nil {
+		return "", err
+	}
+	return streamLocation(response.Js.Cmd), nil
+}
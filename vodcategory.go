@@ -0,0 +1,177 @@
+package stalkerlib
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/url"
+)
+
+/* VODCategory is a VOD category, or a genre nested in one, as listed by the portal. */
+type VODCategory struct {
+	ID       string // Category or genre ID
+	Title    string // Display title
+	Alias    string // Alias used to list the category's genres and items
+	ParentID string // Parent category ID, "" for top-level categories
+	Censored bool   // Whether the portal hides the category behind the parental PIN
+	Genre    bool   // Whether this is a genre nested in its parent category
+}
+
+/* UnmarshalJSON decodes a portal category or genre entry. */
+func (cat *VODCategory) UnmarshalJSON(data []byte) error {
+	var aux struct {
+		ID       flexString `json:"id"`
+		Title    string     `json:"title"`
+		Alias    string     `json:"alias"`
+		ParentID flexString `json:"parent_id"`
+		Censored flexString `json:"censored"`
+	}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	cat.ID, cat.Title, cat.Alias = string(aux.ID), aux.Title, aux.Alias
+	cat.ParentID = string(aux.ParentID)
+	if cat.ParentID == "0" {
+		cat.ParentID = ""
+	}
+	cat.Censored = aux.Censored == "1" || aux.Censored == "true"
+	return nil
+}
+
+/* CategoryNode is one folder of a CategoryTree. */
+type CategoryNode struct {
+	Category VODCategory     // The category or genre itself
+	Children []*CategoryNode // Subcategories and genres in portal order
+	Parent   *CategoryNode   // Enclosing folder, nil for roots
+}
+
+/* CategoryTree is the portal's VOD folder hierarchy, for exporters that reproduce it instead of a flat list. */
+type CategoryTree struct {
+	Roots []*CategoryNode // Top-level categories in portal order
+}
+
+/* NewCategoryTree links categories into a tree by ParentID; categories whose parent is unknown, or that form a cycle, become roots. */
+func NewCategoryTree(categories []VODCategory) *CategoryTree {
+	nodes := make(map[string]*CategoryNode, len(categories))
+	ordered := make([]*CategoryNode, 0, len(categories))
+	for _, cat := range categories {
+		n := &CategoryNode{Category: cat}
+		if _, dup := nodes[cat.ID]; !dup {
+			nodes[cat.ID] = n
+		}
+		ordered = append(ordered, n)
+	}
+	t := &CategoryTree{}
+	for _, n := range ordered {
+		parent := nodes[n.Category.ParentID]
+		if parent == nil || parent == n || parent.descendsFrom(n) {
+			t.Roots = append(t.Roots, n)
+			continue
+		}
+		n.Parent = parent
+		parent.Children = append(parent.Children, n)
+	}
+	return t
+}
+
+/* descendsFrom reports whether n is linked below ancestor. */
+func (n *CategoryNode) descendsFrom(ancestor *CategoryNode) bool {
+	for p := n.Parent; p != nil; p = p.Parent {
+		if p == ancestor {
+			return true
+		}
+	}
+	return false
+}
+
+/* Path returns the node's categories from its root down to the node itself. */
+func (n *CategoryNode) Path() []VODCategory {
+	var path []VODCategory
+	for p := n; p != nil; p = p.Parent {
+		path = append([]VODCategory{p.Category}, path...)
+	}
+	return path
+}
+
+/* Walk visits the tree depth-first in portal order; returning false from fn skips the node's children. */
+func (t *CategoryTree) Walk(fn func(n *CategoryNode) bool) {
+	var walk func(nodes []*CategoryNode)
+	walk = func(nodes []*CategoryNode) {
+		for _, n := range nodes {
+			if fn(n) {
+				walk(n.Children)
+			}
+		}
+	}
+	walk(t.Roots)
+}
+
+/* Find returns the first category with the given ID in walk order; genres are matched only when genre is true, since genre IDs may repeat category IDs. */
+func (t *CategoryTree) Find(id string, genre bool) (*CategoryNode, bool) {
+	var found *CategoryNode
+	t.Walk(func(n *CategoryNode) bool {
+		if found == nil && n.Category.ID == id && n.Category.Genre == genre {
+			found = n
+		}
+		return found == nil
+	})
+	return found, found != nil
+}
+
+/* GetVODCategories returns the portal's VOD categories as a flat list. */
+func (c *StalkerClient) GetVODCategories() ([]VODCategory, error) {
+	var response portalEnvelope
+	if err := c.doAction(context.Background(), "vod", "get_categories", nil, &response); err != nil {
+		return nil, err
+	}
+	return decodeEnvelopeList[VODCategory](response, []string{"data", "categories"})
+}
+
+/* GetVODCategoryTree returns the VOD categories as a tree, with each category's genres nested below it. */
+func (c *StalkerClient) GetVODCategoryTree() (*CategoryTree, error) {
+	categories, err := c.GetVODCategories()
+	if err != nil {
+		return nil, err
+	}
+	tree := NewCategoryTree(categories)
+	var nodes []*CategoryNode
+	tree.Walk(func(n *CategoryNode) bool {
+		nodes = append(nodes, n)
+		return true
+	})
+	for _, n := range nodes {
+		// The "*" alias is the portal's all-items pseudo category
+		if n.Category.Alias == "" || n.Category.Alias == "*" {
+			continue
+		}
+		genres, err := c.getVODGenres(n.Category)
+		if err != nil {
+			return nil, err
+		}
+		for _, g := range genres {
+			n.Children = append(n.Children, &CategoryNode{Category: g, Parent: n})
+		}
+	}
+	return tree, nil
+}
+
+/* getVODGenres lists the genres of a category, treating a portal refusal as no genres since many portals lack the action. */
+func (c *StalkerClient) getVODGenres(cat VODCategory) ([]VODCategory, error) {
+	var response portalEnvelope
+	params := url.Values{"cat_alias": {cat.Alias}}
+	if err := c.doAction(context.Background(), "vod", "get_genres_by_category_alias", params, &response); err != nil {
+		return nil, err
+	}
+	genres, err := decodeEnvelopeList[VODCategory](response, []string{"data", "genres"})
+	var portalErr *PortalError
+	if errors.As(err, &portalErr) || errors.Is(err, ErrUnrecognizedResponse) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for i := range genres {
+		genres[i].ParentID, genres[i].Genre = cat.ID, true
+	}
+	return genres, nil
+}
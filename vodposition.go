@@ -0,0 +1,75 @@
+package stalkerlib
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+/* VODPosition is a stored playback position of a VOD item, for resuming where the user stopped. */
+type VODPosition struct {
+	VideoID  string        // VOD item ID
+	Series   int           // Episode number for series, 0 for films
+	Position time.Duration // Offset the user stopped at
+}
+
+/* UnmarshalJSON decodes a portal not_ended entry. */
+func (p *VODPosition) UnmarshalJSON(data []byte) error {
+	var aux struct {
+		VideoID flexString `json:"video_id"`
+		Series  flexString `json:"series"`
+		EndTime flexString `json:"end_time"`
+	}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	p.VideoID = string(aux.VideoID)
+	p.Series, _ = strconv.Atoi(string(aux.Series))
+	seconds, _ := strconv.ParseInt(string(aux.EndTime), 10, 64)
+	p.Position = time.Duration(seconds) * time.Second
+	return nil
+}
+
+/* SetVODPosition stores the playback position of a VOD item (series 0 for films) with the portal's set_not_ended action. */
+func (c *StalkerClient) SetVODPosition(videoID string, series int, position time.Duration) error {
+	params := url.Values{
+		"video_id": {videoID},
+		"series":   {strconv.Itoa(series)},
+		"end_time": {strconv.FormatInt(int64(position/time.Second), 10)},
+	}
+	return c.doAction(context.Background(), "vod", "set_not_ended", params, nil)
+}
+
+/* MarkVODEnded clears the stored position of a VOD item once it has been watched to the end. */
+func (c *StalkerClient) MarkVODEnded(videoID string, series int) error {
+	params := url.Values{
+		"video_id": {videoID},
+		"series":   {strconv.Itoa(series)},
+	}
+	return c.doAction(context.Background(), "vod", "set_ended", params, nil)
+}
+
+/* GetVODPositions returns every unfinished VOD item with its stored position. */
+func (c *StalkerClient) GetVODPositions() ([]VODPosition, error) {
+	var response portalEnvelope
+	if err := c.doAction(context.Background(), "vod", "get_not_ended", nil, &response); err != nil {
+		return nil, err
+	}
+	return decodeEnvelopeList[VODPosition](response, []string{"data", "items"})
+}
+
+/* GetVODPosition returns the stored position of one VOD item, reporting false when it has none. */
+func (c *StalkerClient) GetVODPosition(videoID string, series int) (VODPosition, bool, error) {
+	positions, err := c.GetVODPositions()
+	if err != nil {
+		return VODPosition{}, false, err
+	}
+	for _, p := range positions {
+		if p.VideoID == videoID && p.Series == series {
+			return p, true, nil
+		}
+	}
+	return VODPosition{}, false, nil
+}
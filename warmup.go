@@ -0,0 +1,35 @@
+package stalkerlib
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+/* Warmup runs the capability probe concurrently with the handshake, skipped when a token already exists, and prefetches the channel list as soon as there is a token, joining all failures into one error; only an unknown API path is discovered first, as the handshake needs it. */
+func (c *StalkerClient) Warmup(ctx context.Context) ([]Channel, error) {
+	if c.config().APIPath == "" && (c.override == nil || c.override.APIPath == nil) && !c.loadProbe() {
+		c.discoverEndpoint(ctx)
+	}
+
+	gen := c.auth.gen.Load()
+	var probeErr error
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		probeErr = c.probeServer(ctx)
+	}()
+
+	// A handshake the probe needed as well is shared through the token generation
+	var err error
+	if c.CurrentToken() == "" {
+		err = c.renewToken(ctx, gen)
+	}
+	var channels []Channel
+	if err == nil {
+		channels, err = c.getChannels(ctx)
+	}
+	wg.Wait()
+	return channels, errors.Join(probeErr, err)
+}
@@ -0,0 +1,182 @@
+package stalkerlib
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ericcmi/stalkerlib/matching"
+)
+
+/* ExternalGuide is an XMLTV guide parsed from an outside source. */
+type ExternalGuide struct {
+	Channels map[string][]string     // XMLTV channel ID to its display names
+	Programs map[string][]EPGProgram // XMLTV channel ID to its programs
+}
+
+/* xmltvChannelIn is a <channel> element as read by ParseXMLTV. */
+type xmltvChannelIn struct {
+	ID           string   `xml:"id,attr"`
+	DisplayNames []string `xml:"display-name"`
+}
+
+/* xmltvProgramIn is a <programme> element as read by ParseXMLTV. */
+type xmltvProgramIn struct {
+	Start      string   `xml:"start,attr"`
+	Stop       string   `xml:"stop,attr"`
+	Channel    string   `xml:"channel,attr"`
+	Titles     []string `xml:"title"`
+	Descs      []string `xml:"desc"`
+	Categories []string `xml:"category"`
+}
+
+/* ParseXMLTV reads an XMLTV document element by element, so multi-hundred-megabyte guides are not held in memory as XML. */
+func ParseXMLTV(r io.Reader) (*ExternalGuide, error) {
+	guide := &ExternalGuide{
+		Channels: make(map[string][]string),
+		Programs: make(map[string][]EPGProgram),
+	}
+	dec := xml.NewDecoder(r)
+	dec.Strict = false
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return guide, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse XMLTV: %w", err)
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		switch start.Name.Local {
+		case "channel":
+			var ch xmltvChannelIn
+			if err := dec.DecodeElement(&ch, &start); err != nil {
+				return nil, fmt.Errorf("failed to parse XMLTV channel: %w", err)
+			}
+			guide.Channels[ch.ID] = append(guide.Channels[ch.ID], ch.DisplayNames...)
+		case "programme":
+			var p xmltvProgramIn
+			if err := dec.DecodeElement(&p, &start); err != nil {
+				return nil, fmt.Errorf("failed to parse XMLTV programme: %w", err)
+			}
+			startTime, err1 := parseXMLTVTime(p.Start)
+			stopTime, err2 := parseXMLTVTime(p.Stop)
+			if err1 != nil || err2 != nil {
+				continue
+			}
+			guide.Programs[p.Channel] = append(guide.Programs[p.Channel], EPGProgram{
+				ChannelID: p.Channel,
+				Name:      firstOf(p.Titles),
+				Start:     startTime.Unix(),
+				Stop:      stopTime.Unix(),
+				Desc:      firstOf(p.Descs),
+				Category:  firstOf(p.Categories),
+			})
+		}
+	}
+}
+
+/* parseXMLTVTime parses an XMLTV timestamp such as "20240101120000 +0100", treating a missing offset as UTC. */
+func parseXMLTVTime(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if t, err := time.Parse("20060102150405 -0700", s); err == nil {
+		return t, nil
+	}
+	if len(s) > 14 {
+		s = s[:14]
+	}
+	return time.Parse("20060102150405", s)
+}
+
+/* firstOf returns the first element of values, or "" when empty. */
+func firstOf(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+/* guideMatchThreshold is the minimum name similarity accepted when matching portal channels to guide channels. */
+const guideMatchThreshold = 0.8
+
+/* MatchGuideChannel finds the XMLTV channel ID for a portal channel, by its xmltv_id field first and then by fuzzy display name. */
+func (g *ExternalGuide) MatchGuideChannel(ch Channel) (string, bool) {
+	if ch.XMLTVID != "" {
+		if _, ok := g.Programs[ch.XMLTVID]; ok {
+			return ch.XMLTVID, true
+		}
+	}
+	if _, ok := g.Programs[ch.ID]; ok {
+		return ch.ID, true
+	}
+
+	// Prefer an exact normalized name, then the most similar one
+	guideIDs := make([]string, 0, len(g.Channels))
+	for id := range g.Channels {
+		guideIDs = append(guideIDs, id)
+	}
+	sort.Strings(guideIDs)
+	var ids, names []string
+	for _, id := range guideIDs {
+		for _, name := range g.Channels[id] {
+			if matching.Same(ch.Name, name) {
+				return id, true
+			}
+			ids = append(ids, id)
+			names = append(names, name)
+		}
+	}
+	if best, ok := matching.BestMatch(ch.Name, names, guideMatchThreshold); ok {
+		return ids[best.Index], true
+	}
+	return "", false
+}
+
+/* MergeEPG overlays guide onto portal EPG keyed by channel ID, filling channels that have no portal programs. */
+func MergeEPG(channels []Channel, portal map[string][]EPGProgram, guide *ExternalGuide) map[string][]EPGProgram {
+	merged := make(map[string][]EPGProgram, len(channels))
+	for id, programs := range portal {
+		merged[id] = programs
+	}
+	for _, ch := range channels {
+		if len(merged[ch.ID]) > 0 {
+			continue
+		}
+		guideID, ok := guide.MatchGuideChannel(ch)
+		if !ok {
+			continue
+		}
+		programs := make([]EPGProgram, len(guide.Programs[guideID]))
+		for i, p := range guide.Programs[guideID] {
+			p.ChannelID = ch.ID
+			programs[i] = p
+		}
+		merged[ch.ID] = programs
+	}
+	return merged
+}
+
+/* OverlayGuide stores guide programs in the EPG cache for channels without cached portal programs and returns how many were filled. */
+func (c *StalkerClient) OverlayGuide(channels []Channel, guide *ExternalGuide) int {
+	portal := make(map[string][]EPGProgram)
+	for _, ch := range channels {
+		if programs, ok := c.epg.load(ch.ID); ok {
+			portal[ch.ID] = programs
+		}
+	}
+	filled := 0
+	for id, programs := range MergeEPG(channels, portal, guide) {
+		if len(portal[id]) == 0 && len(programs) > 0 {
+			c.epg.store(id, programs)
+			filled++
+		}
+	}
+	return filled
+}
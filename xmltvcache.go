@@ -0,0 +1,171 @@
+package stalkerlib
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"sync"
+	"time"
+)
+
+/* XMLTVCache keeps the encoded XMLTV fragments of each channel with a hash of their inputs, so ExportXMLTVIncremental only re-encodes channels whose EPG changed. */
+type XMLTVCache struct {
+	mu        sync.Mutex
+	fragments map[string]xmltvFragment
+}
+
+/* xmltvFragment is the encoded channel element and programmes of one channel. */
+type xmltvFragment struct {
+	Hash       string `json:"hash"`
+	Channel    []byte `json:"channel"`
+	Programmes []byte `json:"programmes"`
+}
+
+/* NewXMLTVCache creates an empty fragment cache. */
+func NewXMLTVCache() *XMLTVCache {
+	return &XMLTVCache{fragments: make(map[string]xmltvFragment)}
+}
+
+/* LoadXMLTVCache reads a cache written by Save, returning an empty cache when the file does not exist. */
+func LoadXMLTVCache(path string) (*XMLTVCache, error) {
+	cache := NewXMLTVCache()
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return cache, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read XMLTV cache: %w", err)
+	}
+	if err := json.Unmarshal(data, &cache.fragments); err != nil {
+		return nil, fmt.Errorf("failed to parse XMLTV cache: %w", err)
+	}
+	if cache.fragments == nil {
+		cache.fragments = make(map[string]xmltvFragment)
+	}
+	return cache, nil
+}
+
+/* Save writes the cache to path for the next run to load. */
+func (xc *XMLTVCache) Save(path string) error {
+	xc.mu.Lock()
+	data, err := json.Marshal(xc.fragments)
+	xc.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, 0644, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+}
+
+/* ExportXMLTVIncremental writes the same guide as ExportXMLTV, re-encoding only channels whose programs or export settings changed since the cache last saw them and reusing the cached fragments of the rest; it returns the IDs of the re-encoded channels. */
+func (c *StalkerClient) ExportXMLTVIncremental(w io.Writer, channels []Channel, programs map[string][]EPGProgram, opts XMLTVOptions, cache *XMLTVCache) ([]string, error) {
+	loc, err := c.xmltvLocation(opts)
+	if err != nil {
+		return nil, err
+	}
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	var changed []string
+	entries := opts.Timeshift.expand(c.exportChannels(channels, opts.Regions, opts.Collapse, opts.Sort), opts.URLs == URLProxied)
+	frags := make([]xmltvFragment, len(entries))
+	seen := make(map[string]bool, len(entries))
+	for i, entry := range entries {
+		ch, progs := entry.Channel, entry.programs(programs)
+		seen[ch.ID] = true
+		hash := c.xmltvInputHash(ch, progs, loc, opts)
+		if frag, ok := cache.fragments[ch.ID]; ok && frag.Hash == hash {
+			frags[i] = frag
+			continue
+		}
+		channel, xprogs := c.xmltvChannel(ch, progs, loc, opts)
+		frag, err := encodeXMLTVFragment(hash, channel, xprogs)
+		if err != nil {
+			return nil, err
+		}
+		cache.fragments[ch.ID] = frag
+		frags[i] = frag
+		changed = append(changed, ch.ID)
+	}
+
+	// Forget channels that left the lineup
+	for id := range cache.fragments {
+		if !seen[id] {
+			delete(cache.fragments, id)
+		}
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header + "<tv>\n")
+	for _, frag := range frags {
+		buf.Write(frag.Channel)
+	}
+	for _, frag := range frags {
+		buf.Write(frag.Programmes)
+	}
+	buf.WriteString("</tv>\n")
+	if _, err := buf.WriteTo(w); err != nil {
+		return nil, fmt.Errorf("failed to write XMLTV output: %w", err)
+	}
+	return changed, nil
+}
+
+/* xmltvInputHash digests everything a channel's fragments are encoded from, including the client settings behind each programme's category, image and year, so changing the translator, genre map or enricher re-encodes the channel. */
+func (c *StalkerClient) xmltvInputHash(ch Channel, programs []EPGProgram, loc *time.Location, opts XMLTVOptions) string {
+	h := sha256.New()
+	enc := json.NewEncoder(h)
+	enc.Encode(ch)
+	enc.Encode(programs)
+	enc.Encode([]string{loc.String(), opts.ChannelIDs.ID(ch)})
+	for _, p := range programs {
+		prog := c.xmltvProgram(p, loc)
+		icon := ""
+		if prog.Icon != nil {
+			icon = prog.Icon.Src
+		}
+		enc.Encode([]string{prog.Category, icon, prog.Date})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+/* encodeXMLTVFragment encodes a channel element and its programmes, indented as in a full ExportXMLTV document. */
+func encodeXMLTVFragment(hash string, channel XMLTVChannel, programs []XMLTVProgram) (xmltvFragment, error) {
+	encode := func(v interface{}) ([]byte, error) {
+		var buf bytes.Buffer
+		enc := xml.NewEncoder(&buf)
+		enc.Indent("  ", "  ")
+		if err := enc.Encode(v); err != nil {
+			return nil, fmt.Errorf("failed to encode XMLTV fragment: %w", err)
+		}
+		buf.WriteString("\n")
+		return buf.Bytes(), nil
+	}
+	frag := xmltvFragment{Hash: hash}
+	var err error
+	if frag.Channel, err = encode(struct {
+		XMLTVChannel
+		XMLName xml.Name `xml:"channel"`
+	}{XMLTVChannel: channel}); err != nil {
+		return frag, err
+	}
+	for _, p := range programs {
+		b, err := encode(struct {
+			XMLTVProgram
+			XMLName xml.Name `xml:"programme"`
+		}{XMLTVProgram: p})
+		if err != nil {
+			return frag, err
+		}
+		frag.Programmes = append(frag.Programmes, b...)
+	}
+	return frag, nil
+}
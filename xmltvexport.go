@@ -0,0 +1,112 @@
+package stalkerlib
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+/* XMLTVOptions configures ExportXMLTV. */
+type XMLTVOptions struct {
+	Location   *time.Location   // Zone whose offset the emitted times carry, e.g. time.UTC for "+0000"; nil for the client timezone
+	ChannelIDs ChannelIDScheme  // Derivation of channel ids, matching the M3U export's tvg-id
+	Timeshift  TimeshiftOptions // Time-shifted channel duplicates with correspondingly shifted programs, listed only when URLs is URLProxied
+	URLs       PlaylistURLStyle // URL style of the playlist the guide accompanies, so both list the same channels
+	Collapse   QualityCollapse  // Reduction of SD/HD/FHD duplicates to the preferred variant, matching the M3U export's
+	Regions    []string         // Region tags to keep, "" for untagged channels; nil keeps every channel
+	Sort       ChannelSort      // Channel order of the guide, matching the M3U export's
+}
+
+/* ExportXMLTV writes an XMLTV guide of channels with their programs, keyed by channel ID as returned by GetAllEPG. */
+func (c *StalkerClient) ExportXMLTV(w io.Writer, channels []Channel, programs map[string][]EPGProgram, opts XMLTVOptions) error {
+	loc, err := c.xmltvLocation(opts)
+	if err != nil {
+		return err
+	}
+	var xmltv XMLTV
+	for _, entry := range opts.Timeshift.expand(c.exportChannels(channels, opts.Regions, opts.Collapse, opts.Sort), opts.URLs == URLProxied) {
+		channel, progs := c.xmltvChannel(entry.Channel, entry.programs(programs), loc, opts)
+		xmltv.Channels = append(xmltv.Channels, channel)
+		xmltv.Programs = append(xmltv.Programs, progs...)
+	}
+
+	io.WriteString(w, xml.Header)
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(xmltv); err != nil {
+		return fmt.Errorf("failed to write XMLTV output: %w", err)
+	}
+	_, err = io.WriteString(w, "\n")
+	return err
+}
+
+/* xmltvChannel converts one channel and its programs to their XMLTV form under the exported channel ID. */
+func (c *StalkerClient) xmltvChannel(ch Channel, programs []EPGProgram, loc *time.Location, opts XMLTVOptions) (XMLTVChannel, []XMLTVProgram) {
+	id := opts.ChannelIDs.ID(ch)
+	progs := make([]XMLTVProgram, 0, len(programs))
+	for _, p := range programs {
+		prog := c.xmltvProgram(p, loc)
+		prog.Channel = id
+		progs = append(progs, prog)
+	}
+	return XMLTVChannel{ID: id, DisplayName: ch.Name}, progs
+}
+
+/* xmltvLocation returns the zone of exported times: the configured one, or the client timezone. */
+func (c *StalkerClient) xmltvLocation(opts XMLTVOptions) (*time.Location, error) {
+	if opts.Location != nil {
+		return opts.Location, nil
+	}
+	loc, err := time.LoadLocation(c.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone %s: %w", c.Timezone, err)
+	}
+	return loc, nil
+}
+
+/* xmltvProgram converts a portal program to its XMLTV form, with times in loc and credits; the image and year fall back to enricher metadata. */
+func (c *StalkerClient) xmltvProgram(p EPGProgram, loc *time.Location) XMLTVProgram {
+	prog := XMLTVProgram{
+		Start:       time.Unix(p.Start, 0).In(loc).Format("20060102150405 -0700"),
+		Stop:        time.Unix(p.Stop, 0).In(loc).Format("20060102150405 -0700"),
+		Channel:     p.ChannelID,
+		Title:       p.Name,
+		Desc:        p.Desc,
+		Date:        p.Year,
+		Category:    c.exportCategory(p.Category),
+		EpisodeNums: xmltvEpisodeNums(p.Season, p.Episode),
+	}
+	if len(p.Directors) > 0 || len(p.Actors) > 0 {
+		prog.Credits = &XMLTVCredits{Directors: p.Directors, Actors: p.Actors}
+	}
+	icon := p.Icon
+	if p.Metadata != nil {
+		icon = firstNonEmpty(icon, p.Metadata.Poster)
+		prog.Date = firstNonEmpty(prog.Date, p.Metadata.Year)
+	}
+	if icon != "" {
+		prog.Icon = &XMLTVIcon{Src: icon}
+	}
+	return prog
+}
+
+/* xmltvEpisodeNums returns the xmltv_ns (zero-based) and onscreen forms of a season and episode, omitting unknown parts. */
+func xmltvEpisodeNums(season, episode int) []XMLTVEpisodeNum {
+	if season <= 0 && episode <= 0 {
+		return nil
+	}
+	var ns, onscreen string
+	if season > 0 {
+		ns = strconv.Itoa(season - 1)
+		onscreen = fmt.Sprintf("S%02d", season)
+	}
+	ns += "."
+	if episode > 0 {
+		ns += strconv.Itoa(episode - 1)
+		onscreen += fmt.Sprintf("E%02d", episode)
+	}
+	ns += "."
+	return []XMLTVEpisodeNum{{System: "xmltv_ns", Value: ns}, {System: "onscreen", Value: onscreen}}
+}
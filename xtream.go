@@ -0,0 +1,163 @@
+package stalkerlib
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/ericcmi/stalkerlib/matching"
+)
+
+/* XtreamClient is a Provider for Xtream Codes compatible panels (player_api.php). */
+type XtreamClient struct {
+	ServerURL  string       // Panel base URL (e.g., http://example.com:8080)
+	Username   string       // Account username
+	Password   string       // Account password
+	Format     string       // Live stream container extension, "ts" or "m3u8"
+	HTTPClient *http.Client // HTTP client used for API calls; http.DefaultClient when nil
+}
+
+/* xtreamStream represents a live stream from the get_live_streams action. */
+type xtreamStream struct {
+	Num          flexString `json:"num"`
+	Name         string     `json:"name"`
+	StreamID     flexString `json:"stream_id"`
+	StreamIcon   string     `json:"stream_icon"`
+	EPGChannelID string     `json:"epg_channel_id"`
+	CategoryID   flexString `json:"category_id"`
+	TVArchive    flexString `json:"tv_archive"`
+	ArchiveDays  flexString `json:"tv_archive_duration"`
+}
+
+/* xtreamEPGListing represents an entry of the get_simple_data_table action; title and description are base64-encoded. */
+type xtreamEPGListing struct {
+	Title          string     `json:"title"`
+	Description    string     `json:"description"`
+	ChannelID      string     `json:"channel_id"`
+	StartTimestamp flexString `json:"start_timestamp"`
+	StopTimestamp  flexString `json:"stop_timestamp"`
+}
+
+/* xtreamEPGResponse represents the JSON response from the get_simple_data_table action. */
+type xtreamEPGResponse struct {
+	Listings []xtreamEPGListing `json:"epg_listings"`
+}
+
+/* NewXtreamClient creates a new XtreamClient with the given panel URL and credentials. */
+func NewXtreamClient(serverURL, username, password string) *XtreamClient {
+	return &XtreamClient{
+		ServerURL: strings.TrimRight(serverURL, "/"),
+		Username:  username,
+		Password:  password,
+		Format:    "ts",
+	}
+}
+
+/* GetChannels fetches all live streams as channels whose Cmd is the direct stream URL. */
+func (x *XtreamClient) GetChannels() ([]Channel, error) {
+	var streams []xtreamStream
+	if err := x.call("get_live_streams", nil, &streams); err != nil {
+		return nil, fmt.Errorf("live streams request failed: %w", err)
+	}
+	channels := make([]Channel, 0, len(streams))
+	for _, s := range streams {
+		days, _ := strconv.Atoi(string(s.ArchiveDays))
+		channels = append(channels, Channel{
+			ID:           string(s.StreamID),
+			Name:         s.Name,
+			Number:       string(s.Num),
+			GenreID:      string(s.CategoryID),
+			Cmd:          x.streamURL(string(s.StreamID)),
+			Logo:         s.StreamIcon,
+			Archive:      s.TVArchive == "1",
+			ArchiveHours: days * 24,
+			Region:       matching.Region(s.Name),
+		})
+	}
+	return channels, nil
+}
+
+/* GetEPG fetches the full EPG of a stream, decoding the base64 title and description fields. */
+func (x *XtreamClient) GetEPG(channelID string) ([]EPGProgram, error) {
+	var response xtreamEPGResponse
+	if err := x.call("get_simple_data_table", url.Values{"stream_id": {channelID}}, &response); err != nil {
+		return nil, fmt.Errorf("EPG request failed: %w", err)
+	}
+	programs := make([]EPGProgram, 0, len(response.Listings))
+	for _, l := range response.Listings {
+		start, _ := strconv.ParseInt(string(l.StartTimestamp), 10, 64)
+		stop, _ := strconv.ParseInt(string(l.StopTimestamp), 10, 64)
+		programs = append(programs, EPGProgram{
+			ChannelID: channelID,
+			Name:      decodeXtreamText(l.Title),
+			Start:     start,
+			Stop:      stop,
+			Desc:      decodeXtreamText(l.Description),
+		})
+	}
+	return programs, nil
+}
+
+/* GetPlaybackURL returns the stream URL for a channel Cmd, which may be a full URL or a bare stream ID. */
+func (x *XtreamClient) GetPlaybackURL(channelCmd string) (string, error) {
+	if strings.Contains(channelCmd, "://") {
+		return channelCmd, nil
+	}
+	if channelCmd == "" {
+		return "", fmt.Errorf("no stream ID provided")
+	}
+	return x.streamURL(channelCmd), nil
+}
+
+/* streamURL builds the direct live stream URL for a stream ID. */
+func (x *XtreamClient) streamURL(streamID string) string {
+	format := x.Format
+	if format == "" {
+		format = "ts"
+	}
+	return fmt.Sprintf("%s/live/%s/%s/%s.%s", x.ServerURL, url.PathEscape(x.Username), url.PathEscape(x.Password), streamID, format)
+}
+
+/* call performs a player_api.php action and decodes the JSON response into out. */
+func (x *XtreamClient) call(action string, params url.Values, out interface{}) error {
+	if params == nil {
+		params = url.Values{}
+	}
+	params.Set("username", x.Username)
+	params.Set("password", x.Password)
+	params.Set("action", action)
+	req, err := http.NewRequest("GET", x.ServerURL+"/player_api.php?"+params.Encode(), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create %s request: %w", action, err)
+	}
+
+	client := x.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to parse %s response: %w", action, err)
+	}
+	return nil
+}
+
+/* decodeXtreamText decodes a base64 EPG field, returning it unchanged if it is not base64. */
+func decodeXtreamText(s string) string {
+	decoded, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return s
+	}
+	return string(decoded)
+}